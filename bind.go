@@ -0,0 +1,288 @@
+package rahjoo
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindError is a request value Bind failed to convert into a struct field.
+type BindError struct {
+	// Source is where the value came from: "path", "query", "header" or "form".
+	Source string
+	// Name is the name of the value in its source, e.g. the query parameter.
+	Name string
+	// Value is the raw value.
+	Value string
+	// Err is the conversion error.
+	Err error
+}
+
+// Error describes the failed conversion.
+func (e *BindError) Error() string {
+	return fmt.Sprintf("invalid %s parameter %s %q: %v", e.Source, e.Name, e.Value, e.Err)
+}
+
+// Unwrap returns the conversion error.
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns 400 Bad Request.
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// BindErrors is the list of values Bind failed to convert. It is answered with 400 Bad Request
+// by middleware.ErrorHandler.
+type BindErrors []*BindError
+
+// Error joins the messages of the errors.
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// StatusCode returns 400 Bad Request.
+func (e BindErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// bindSource looks up the values of name in a part of the request.
+type bindSource struct {
+	tag    string
+	lookup func(r *http.Request, name string) ([]string, bool)
+}
+
+var bindSources = []bindSource{
+	{"path", func(r *http.Request, name string) ([]string, bool) {
+		v := r.PathValue(name)
+		return []string{v}, v != ""
+	}},
+	{"query", func(r *http.Request, name string) ([]string, bool) {
+		v, ok := r.URL.Query()[name]
+		return v, ok
+	}},
+	{"header", func(r *http.Request, name string) ([]string, bool) {
+		v := r.Header.Values(name)
+		return v, len(v) > 0
+	}},
+	{"form", func(r *http.Request, name string) ([]string, bool) {
+		v, ok := r.PostForm[name]
+		return v, ok
+	}},
+}
+
+// maxFormMemory is the size of multipart file parts Bind keeps in memory, the rest being stored
+// in temporary files, as with http.Request.FormValue.
+const maxFormMemory = 32 << 20
+
+var (
+	fileHeaderType  = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeadersType = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+// Bind populates the fields of the struct dst points to from the path parameters, the query
+// parameters, the headers and the URL-encoded or multipart form body of r, named by the path,
+// query, header and form tags:
+//
+//	type listBooksParams struct {
+//		ShelfID int      `path:"shelf_id"`
+//		Page    int      `query:"page"`
+//		Tags    []string `query:"tag"`
+//		Tenant  string   `header:"X-Tenant"`
+//	}
+//
+// Fields can be strings, booleans, integers, floats, time.Duration, time.Time in RFC 3339
+// format, types implementing encoding.TextUnmarshaler, pointers to these and slices of these
+// for repeated values. Form fields can also be uploaded files, as *multipart.FileHeader or
+// []*multipart.FileHeader. Fields whose value is absent are left untouched, and embedded
+// structs are bound as well.
+//
+// The body is only parsed if dst has form fields, which works along with the
+// middleware.Multipart middleware. Conversion failures are returned together as BindErrors,
+// and malformed form bodies as an *HTTPError with status 400 Bad Request, or 413 Content Too
+// Large when over a limit set with http.MaxBytesReader. Bind panics if dst is not a non-nil
+// pointer to a struct.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic("rahjoo: Bind destination must be a non-nil pointer to a struct")
+	}
+	if hasTag(v.Elem().Type(), "form") {
+		if err := parseForm(r); err != nil {
+			return err
+		}
+	}
+	var errs BindErrors
+	bindStruct(r, v.Elem(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// hasTag reports whether a field of t, or of its embedded structs, has tag.
+func hasTag(t reflect.Type, tag string) bool {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return true
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && hasTag(field.Type, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseForm parses the form body of r, multipart or URL-encoded.
+func parseForm(r *http.Request) error {
+	var err error
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		err = r.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err == nil {
+		return nil
+	}
+	if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+		return &HTTPError{Code: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+	}
+	return &HTTPError{Code: http.StatusBadRequest, Message: "malformed form body"}
+}
+
+func bindStruct(r *http.Request, v reflect.Value, errs *BindErrors) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindStruct(r, v.Field(i), errs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name := field.Tag.Get("form"); name != "" && name != "-" && r.MultipartForm != nil {
+			switch files := r.MultipartForm.File[name]; {
+			case field.Type == fileHeaderType && len(files) > 0:
+				v.Field(i).Set(reflect.ValueOf(files[0]))
+				continue
+			case field.Type == fileHeadersType && len(files) > 0:
+				v.Field(i).Set(reflect.ValueOf(files))
+				continue
+			}
+		}
+		if field.Type == fileHeaderType || field.Type == fileHeadersType {
+			continue
+		}
+		for _, src := range bindSources {
+			name, ok := field.Tag.Lookup(src.tag)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+			values, ok := src.lookup(r, name)
+			if !ok {
+				continue
+			}
+			if err := setField(v.Field(i), values); err != nil {
+				*errs = append(*errs, &BindError{Source: src.tag, Name: name, Value: strings.Join(values, ","), Err: err})
+			}
+		}
+	}
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setField converts values into f, a slice taking them all and any other type the first one.
+func setField(f reflect.Value, values []string) error {
+	if f.Kind() == reflect.Slice && !f.Type().Implements(textUnmarshalerType) && f.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(f.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	return setValue(f, values[0])
+}
+
+func setValue(f reflect.Value, value string) error {
+	if f.Kind() == reflect.Pointer {
+		p := reflect.New(f.Type().Elem())
+		if err := setValue(p.Elem(), value); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+	if f.CanAddr() && f.Addr().Type().Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch f.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+		f.SetInt(int64(d))
+		return nil
+	case timeType:
+		tm, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("invalid RFC 3339 time")
+		}
+		f.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("invalid boolean")
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
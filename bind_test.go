@@ -0,0 +1,194 @@
+package rahjoo_test
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+type pagination struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type bookParams struct {
+	pagination
+	ShelfID  int64         `path:"shelf_id"`
+	Tags     []string      `query:"tag"`
+	Draft    bool          `query:"draft"`
+	Since    time.Time     `query:"since"`
+	Timeout  time.Duration `header:"X-Timeout"`
+	Tenant   string        `header:"X-Tenant"`
+	Client   netip.Addr    `header:"X-Client-Ip"`
+	Ratio    float32       `query:"ratio"`
+	Priority uint8         `query:"priority"`
+	Ignored  string
+}
+
+func TestBind(t *testing.T) {
+	limit := 20
+	testCases := []struct {
+		name   string
+		target string
+		header http.Header
+		want   bookParams
+		errs   []string
+	}{
+		{
+			name:   "all_sources",
+			target: "/shelves/7/books?page=2&limit=20&tag=go&tag=web&draft=true&since=2024-01-02T03:04:05Z&ratio=0.5&priority=3",
+			header: http.Header{"X-Timeout": {"3s"}, "X-Tenant": {"acme"}, "X-Client-Ip": {"10.0.0.1"}},
+			want: bookParams{
+				pagination: pagination{Page: 2, Limit: &limit},
+				ShelfID:    7,
+				Tags:       []string{"go", "web"},
+				Draft:      true,
+				Since:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Timeout:    3 * time.Second,
+				Tenant:     "acme",
+				Client:     netip.MustParseAddr("10.0.0.1"),
+				Ratio:      0.5,
+				Priority:   3,
+			},
+		},
+		{
+			name:   "absent_values_untouched",
+			target: "/shelves/7/books",
+			want:   bookParams{ShelfID: 7},
+		},
+		{
+			name:   "aggregated_errors",
+			target: "/shelves/x/books?page=two&draft=maybe&priority=300",
+			header: http.Header{"X-Timeout": {"soon"}},
+			errs:   []string{"page", "shelf_id", "draft", "X-Timeout", "priority"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bookParams
+			var err error
+			mux := http.NewServeMux()
+			mux.HandleFunc("/shelves/{shelf_id}/books", func(w http.ResponseWriter, r *http.Request) {
+				err = rahjoo.Bind(r, &got)
+			})
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			mux.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tc.errs == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got %+v, want %+v", got, tc.want)
+				}
+				return
+			}
+
+			var bindErrs rahjoo.BindErrors
+			if !errors.As(err, &bindErrs) {
+				t.Fatalf("got error %v, want BindErrors", err)
+			}
+			if bindErrs.StatusCode() != http.StatusBadRequest {
+				t.Errorf("got status code %d, want %d", bindErrs.StatusCode(), http.StatusBadRequest)
+			}
+			names := make([]string, len(bindErrs))
+			for i, e := range bindErrs {
+				names[i] = e.Name
+			}
+			if !reflect.DeepEqual(names, tc.errs) {
+				t.Errorf("got errors for %v, want %v", names, tc.errs)
+			}
+		})
+	}
+}
+
+func TestBindInvalidDestination(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for non-pointer destination")
+		}
+	}()
+	rahjoo.Bind(httptest.NewRequest(http.MethodGet, "/", http.NoBody), bookParams{})
+}
+
+type signupForm struct {
+	Name      string                  `form:"name"`
+	Age       int                     `form:"age"`
+	Interests []string                `form:"interest"`
+	Ref       string                  `query:"ref"`
+	Avatar    *multipart.FileHeader   `form:"avatar"`
+	Documents []*multipart.FileHeader `form:"document"`
+}
+
+func TestBindForm(t *testing.T) {
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	mw.WriteField("name", "sara")
+	mw.WriteField("age", "31")
+	for _, f := range []struct{ field, name string }{{"avatar", "me.png"}, {"document", "a.pdf"}, {"document", "b.pdf"}} {
+		fw, _ := mw.CreateFormFile(f.field, f.name)
+		fw.Write([]byte("content of " + f.name))
+	}
+	mw.Close()
+
+	testCases := []struct {
+		name        string
+		body        string
+		contentType string
+		wantName    string
+		wantAge     int
+		wantFiles   []string
+		wantStatus  int
+	}{
+		{"urlencoded", "name=sara&age=31&interest=go&interest=web", "application/x-www-form-urlencoded", "sara", 31, nil, 0},
+		{"multipart", multipartBody.String(), mw.FormDataContentType(), "sara", 31, []string{"me.png", "a.pdf", "b.pdf"}, 0},
+		{"invalid_value", "age=old", "application/x-www-form-urlencoded", "", 0, nil, http.StatusBadRequest},
+		{"malformed_multipart", "garbage", "multipart/form-data; boundary=x", "", 0, nil, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signup?ref=ad&name=query", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			var got signupForm
+			err := rahjoo.Bind(req, &got)
+			if tc.wantStatus != 0 {
+				var coder interface{ StatusCode() int }
+				if !errors.As(err, &coder) || coder.StatusCode() != tc.wantStatus {
+					t.Fatalf("got error %v, want status %d", err, tc.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Name != tc.wantName || got.Age != tc.wantAge || got.Ref != "ad" {
+				t.Errorf("got %+v", got)
+			}
+			var files []string
+			if got.Avatar != nil {
+				files = append(files, got.Avatar.Filename)
+			}
+			for _, fh := range got.Documents {
+				files = append(files, fh.Filename)
+			}
+			if !reflect.DeepEqual(files, tc.wantFiles) {
+				t.Errorf("got files %v, want %v", files, tc.wantFiles)
+			}
+		})
+	}
+}
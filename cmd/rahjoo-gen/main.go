@@ -0,0 +1,48 @@
+// Command rahjoo-gen generates a typed server interface and its rahjoo route wiring from an
+// OpenAPI 3 document in JSON, see package openapi/codegen:
+//
+//	rahjoo-gen -spec openapi.json -package api -o api/routes.gen.go
+//
+// It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/amirzayi/rahjoo/cmd/rahjoo-gen -spec openapi.json -package api -o routes.gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amirzayi/rahjoo/openapi/codegen"
+)
+
+func main() {
+	spec := flag.String("spec", "", "path of the OpenAPI document (JSON)")
+	pkg := flag.String("package", "api", "package name of the generated code")
+	out := flag.String("o", "", "output file (default standard output)")
+	flag.Parse()
+
+	if err := run(*spec, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "rahjoo-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, out string) error {
+	if spec == "" {
+		return fmt.Errorf("missing -spec")
+	}
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+	src, err := codegen.Generate(data, pkg)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,55 @@
+package rahjoo
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/middleware/cors"
+)
+
+// SetCORS applies a CORS policy configured by opts to every route, so distinct groups can have
+// distinct policies instead of one wrapping the whole mux:
+//
+//	public := rahjoo.NewGroupRoute("/api", apiRoutes).SetCORS()
+//	admin := rahjoo.NewGroupRoute("/admin", adminRoutes).SetCORS(cors.WithOrigins([]string{"https://admin.example.com"}))
+//
+// The policy runs before the route middlewares, so their error responses carry the CORS headers
+// browsers need to expose them to scripts. An OPTIONS route answering preflight requests is
+// registered for every path without one, running the CORS policy alone so that route middlewares
+// such as authentication do not reject preflights. Non-preflight OPTIONS requests get 204 No
+// Content with the Allow header listing the methods of the path.
+func (r Route) SetCORS(opts ...cors.Option) Route {
+	policy := cors.CORSHandler(opts...)
+	for _, path := range r {
+		for method, action := range path {
+			action.middlewares = slices.Concat([]middleware.Middleware{policy}, action.middlewares)
+			path[method] = action
+		}
+		if _, ok := path[http.MethodOptions]; ok {
+			continue
+		}
+		if _, ok := path[""]; ok {
+			continue
+		}
+		path[http.MethodOptions] = NewHandler(allowHandler(path), policy)
+	}
+	return r
+}
+
+// allowHandler answers OPTIONS requests with the methods of path.
+func allowHandler(path map[Method]actionHandler) http.HandlerFunc {
+	methods := sortedMethods(path)
+	allow := make([]string, 0, len(methods)+1)
+	for _, m := range methods {
+		allow = append(allow, string(m))
+	}
+	allow = append(allow, http.MethodOptions)
+	value := strings.Join(allow, ", ")
+
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Allow", value)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
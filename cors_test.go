@@ -0,0 +1,86 @@
+package rahjoo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/middleware/cors"
+)
+
+func TestSetCORS(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	deny := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+	}
+	custom := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }
+
+	public := rahjoo.NewGroupRoute("/api", rahjoo.Route{
+		"/items": {
+			http.MethodGet:  rahjoo.NewHandler(ok),
+			http.MethodPost: rahjoo.NewHandler(ok),
+		},
+		"/custom": {
+			http.MethodGet:     rahjoo.NewHandler(ok),
+			http.MethodOptions: rahjoo.NewHandler(custom),
+		},
+	}).SetCORS()
+	admin := rahjoo.NewGroupRoute("/admin", rahjoo.Route{
+		"/users": {
+			http.MethodDelete: rahjoo.NewHandler(ok, middleware.Middleware(deny)),
+		},
+	}).SetCORS(cors.WithOrigins([]string{"https://admin.example.com"}))
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, public, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		origin        string
+		preflight     string
+		status        int
+		allowedOrigin string
+		allow         string
+	}{
+		{"public_preflight", http.MethodOptions, "/api/items", "https://any.example.org", http.MethodPost, http.StatusNoContent, "https://any.example.org", ""},
+		{"public_request", http.MethodGet, "/api/items", "https://any.example.org", "", http.StatusOK, "https://any.example.org", ""},
+		{"plain_options", http.MethodOptions, "/api/items", "", "", http.StatusNoContent, "", "GET, POST, OPTIONS"},
+		{"own_options_kept", http.MethodOptions, "/api/custom", "", "", http.StatusTeapot, "", ""},
+		{"admin_preflight_skips_auth", http.MethodOptions, "/admin/users", "https://admin.example.com", http.MethodDelete, http.StatusNoContent, "https://admin.example.com", ""},
+		{"admin_preflight_denied", http.MethodOptions, "/admin/users", "https://any.example.org", http.MethodDelete, http.StatusOK, "", ""},
+		{"admin_request", http.MethodDelete, "/admin/users", "https://admin.example.com", "", http.StatusUnauthorized, "https://admin.example.com", ""},
+		{"admin_request_other_origin", http.MethodDelete, "/admin/users", "https://any.example.org", "", http.StatusUnauthorized, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, http.NoBody)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight != "" {
+				req.Header.Set("Access-Control-Request-Method", tc.preflight)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.allowedOrigin {
+				t.Errorf("got allowed origin %q, want %q", got, tc.allowedOrigin)
+			}
+			if got := rec.Header().Get("Allow"); got != tc.allow {
+				t.Errorf("got Allow %q, want %q", got, tc.allow)
+			}
+		})
+	}
+}
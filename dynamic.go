@@ -0,0 +1,93 @@
+package rahjoo
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// DynamicRouter is an http.Handler whose route table can be changed after the server has started.
+// Every change builds a fresh http.ServeMux from the whole route table and atomically swaps it in,
+// so requests already being served keep using the mux they were dispatched by and new requests
+// observe the updated routes. It is safe for concurrent use, which makes it suitable for plugin
+// systems and admin consoles that register endpoints at runtime.
+type DynamicRouter struct {
+	// mu serializes writers; readers only load mux.
+	mu     sync.Mutex
+	routes Route
+	mux    atomic.Pointer[http.ServeMux]
+}
+
+// NewDynamicRouter creates a DynamicRouter serving the given routes.
+// It returns an error if the routes can not be registered.
+func NewDynamicRouter(routes ...Route) (*DynamicRouter, error) {
+	d := &DynamicRouter{routes: Route{}}
+	if err := d.AddRoute(routes...); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// AddRoute adds the given routes to the route table. Handlers registered for an existing
+// path and method are replaced, other methods of an existing path are kept.
+// If the resulting routes can not be registered, the route table is left untouched and
+// the error is returned.
+func (d *DynamicRouter) AddRoute(routes ...Route) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := d.routes.Clone()
+	for _, route := range routes {
+		for path, methods := range route {
+			if next[path] == nil {
+				next[path] = map[Method]actionHandler{}
+			}
+			for method, action := range methods {
+				next[path][method] = action
+			}
+		}
+	}
+	return d.swap(next)
+}
+
+// RemoveRoute removes the given methods of path from the route table.
+// If no method is given, the path is removed entirely.
+func (d *DynamicRouter) RemoveRoute(path Path, methods ...Method) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.routes[path]; !ok {
+		return nil
+	}
+	next := d.routes.Clone()
+	for _, method := range methods {
+		delete(next[path], method)
+	}
+	if len(methods) == 0 || len(next[path]) == 0 {
+		delete(next, path)
+	}
+	return d.swap(next)
+}
+
+// Routes returns a copy of the current route table.
+func (d *DynamicRouter) Routes() Route {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.routes.Clone()
+}
+
+// ServeHTTP dispatches the request to the currently active mux.
+func (d *DynamicRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.Load().ServeHTTP(w, r)
+}
+
+// swap binds routes to a new mux and makes it the active one. The caller must hold d.mu.
+func (d *DynamicRouter) swap(routes Route) error {
+	mux := http.NewServeMux()
+	if err := BindRoutesToMux(mux, routes); err != nil {
+		return err
+	}
+	d.routes = routes
+	d.mux.Store(mux)
+	return nil
+}
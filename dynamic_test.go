@@ -0,0 +1,95 @@
+package rahjoo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestDynamicRouter(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	d, err := rahjoo.NewDynamicRouter(rahjoo.Route{
+		"/static": {http.MethodGet: rahjoo.NewHandler(ok)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(method, path string) int {
+		req := httptest.NewRequest(method, path, http.NoBody)
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := status(http.MethodGet, "/plugin"); got != http.StatusNotFound {
+		t.Fatalf("got status code %d before adding route, want %d", got, http.StatusNotFound)
+	}
+
+	if err := d.AddRoute(rahjoo.Route{"/plugin": {http.MethodGet: rahjoo.NewHandler(ok)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddRoute(rahjoo.Route{"/plugin": {http.MethodPost: rahjoo.NewHandler(ok)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddRoute(rahjoo.Route{"plugin": {http.MethodPut: rahjoo.NewHandler(ok)}}); err == nil {
+		t.Fatal("expected error for malformed route")
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if got := status(method, "/plugin"); got != http.StatusOK {
+			t.Errorf("%s /plugin: got status code %d, want %d", method, got, http.StatusOK)
+		}
+	}
+
+	if err := d.RemoveRoute("/plugin", http.MethodPost); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(http.MethodPost, "/plugin"); got != http.StatusMethodNotAllowed {
+		t.Errorf("got status code %d after removing method, want %d", got, http.StatusMethodNotAllowed)
+	}
+
+	if err := d.RemoveRoute("/plugin"); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(http.MethodGet, "/plugin"); got != http.StatusNotFound {
+		t.Errorf("got status code %d after removing path, want %d", got, http.StatusNotFound)
+	}
+	if got := status(http.MethodGet, "/static"); got != http.StatusOK {
+		t.Errorf("got status code %d for untouched route, want %d", got, http.StatusOK)
+	}
+	if routes := d.Routes(); len(routes) != 1 {
+		t.Errorf("got %d routes, want 1", len(routes))
+	}
+}
+
+func TestDynamicRouterConcurrent(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	d, err := rahjoo.NewDynamicRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := d.AddRoute(rahjoo.Route{"/a": {http.MethodGet: rahjoo.NewHandler(ok)}}); err != nil {
+				t.Error(err)
+			}
+			if err := d.RemoveRoute("/a"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			d.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", http.NoBody))
+		}()
+	}
+	wg.Wait()
+}
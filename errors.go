@@ -0,0 +1,87 @@
+package rahjoo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/internal/errctx"
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+// HTTPError is an error answered with a specific status code and message, e.g.
+//
+//	return &rahjoo.HTTPError{Code: http.StatusNotFound, Message: "user not found"}
+type HTTPError struct {
+	// Code is the HTTP status code of the response.
+	Code int
+	// Message is the client-facing description of the error. It defaults to the status text.
+	Message string
+	// Cause is the underlying error, for logging and errors.Is. It is not exposed to clients.
+	Cause error
+}
+
+// ErrorOption configures an HTTPError created with Error.
+type ErrorOption func(*HTTPError)
+
+// WithCause sets the underlying error of the HTTPError.
+func WithCause(err error) ErrorOption {
+	return func(e *HTTPError) {
+		e.Cause = err
+	}
+}
+
+// Error returns an *HTTPError with status code and client-facing message msg, e.g.
+//
+//	return rahjoo.Error(http.StatusConflict, "email already registered", rahjoo.WithCause(err))
+func Error(code int, msg string, opts ...ErrorOption) error {
+	e := &HTTPError{Code: code, Message: msg}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Error returns the message of the error.
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.Code)
+	}
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e *HTTPError) StatusCode() int {
+	return e.Code
+}
+
+// Unwrap returns the cause of the error.
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// HandlerE adapts a handler returning an error to an http.HandlerFunc, so handlers can return
+// errors instead of formatting error responses themselves:
+//
+//	rahjoo.NewHandler(rahjoo.HandlerE(getUser), middleware.ErrorHandler())
+//
+// The returned error is rendered by the middleware.ErrorHandler wrapping the handler. Without
+// one, the error is answered as problem details with the status code and message of an
+// HTTPError, or 500 Internal Server Error without detail for any other error and HTTPErrors
+// whose code is outside 100-599.
+func HandlerE(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := handler(w, r)
+		if err == nil {
+			return
+		}
+		if sink := errctx.From(r.Context()); sink != nil {
+			sink.Err = err
+			return
+		}
+		if httpErr := (*HTTPError)(nil); errors.As(err, &httpErr) && httpErr.Code >= 100 && httpErr.Code <= 599 {
+			problem.Write(w, httpErr.Code, problem.WithDetail(httpErr.Error()), problem.WithInstance(r.URL.Path))
+			return
+		}
+		problem.Write(w, http.StatusInternalServerError, problem.WithInstance(r.URL.Path))
+	}
+}
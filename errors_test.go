@@ -0,0 +1,101 @@
+package rahjoo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+func TestHandlerE(t *testing.T) {
+	handler := rahjoo.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.URL.Path {
+		case "/missing":
+			return fmt.Errorf("lookup: %w", &rahjoo.HTTPError{Code: http.StatusNotFound, Message: "user not found"})
+		case "/gone":
+			return &rahjoo.HTTPError{Code: http.StatusGone}
+		case "/broken":
+			return errors.New("db: connection refused")
+		case "/zero":
+			return rahjoo.Error(0, "no status")
+		}
+		w.Write([]byte("ok"))
+		return nil
+	})
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+		detail string
+	}{
+		{"http_error", "/missing", http.StatusNotFound, "user not found"},
+		{"default_message", "/gone", http.StatusGone, "Gone"},
+		{"other_error", "/broken", http.StatusInternalServerError, ""},
+		{"zero_status", "/zero", http.StatusInternalServerError, ""},
+	}
+
+	for _, tc := range testCases {
+		for name, h := range map[string]http.Handler{
+			"bare":          handler,
+			"error_handler": middleware.ErrorHandler()(handler),
+		} {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, http.NoBody))
+				if rec.Code != tc.status || rec.Header().Get("Content-Type") != problem.ContentType {
+					t.Fatalf("got %d %q, want %d %q", rec.Code, rec.Header().Get("Content-Type"), tc.status, problem.ContentType)
+				}
+				var details problem.Details
+				if err := json.Unmarshal(rec.Body.Bytes(), &details); err != nil {
+					t.Fatal(err)
+				}
+				if details.Detail != tc.detail || details.Instance != tc.path {
+					t.Errorf("got problem %+v", details)
+				}
+			})
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", http.NoBody))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("got %d %q, want 200 %q", rec.Code, rec.Body, "ok")
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("unique violation")
+	testCases := []struct {
+		name    string
+		err     error
+		code    int
+		message string
+		cause   error
+	}{
+		{"message", rahjoo.Error(http.StatusConflict, "email taken"), http.StatusConflict, "email taken", nil},
+		{"default_message", rahjoo.Error(http.StatusNotFound, ""), http.StatusNotFound, "Not Found", nil},
+		{"cause", rahjoo.Error(http.StatusConflict, "email taken", rahjoo.WithCause(cause)), http.StatusConflict, "email taken", cause},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var httpErr *rahjoo.HTTPError
+			if !errors.As(tc.err, &httpErr) {
+				t.Fatalf("got %T, want *rahjoo.HTTPError", tc.err)
+			}
+			if httpErr.StatusCode() != tc.code || tc.err.Error() != tc.message {
+				t.Errorf("got %d %q, want %d %q", httpErr.StatusCode(), tc.err.Error(), tc.code, tc.message)
+			}
+			if tc.cause != nil && !errors.Is(tc.err, tc.cause) {
+				t.Errorf("expected the error to wrap %v", tc.cause)
+			}
+		})
+	}
+}
@@ -47,5 +47,23 @@ func ExampleBindRoutesToMux() {
 
 	mux := http.NewServeMux()
 	mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("./images"))))
-	rahjoo.BindRoutesToMux(mux, userV1Gp, userV2Gp)
+	if err := rahjoo.BindRoutesToMux(mux, userV1Gp, userV2Gp); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleBindRoutesToMuxWithPrefix() {
+	listUsers := func(http.ResponseWriter, *http.Request) {}
+
+	routes := rahjoo.NewGroupRoute("/api/v1", rahjoo.Route{
+		"/users": {
+			http.MethodGet: rahjoo.NewHandler(listUsers),
+		},
+	})
+
+	// serves GET /internal/api/v1/users
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMuxWithPrefix(mux, "/internal", routes); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,228 @@
+// Package health serves liveness and readiness probes backed by named checks:
+//
+//	h := health.New()
+//	h.AddReadiness("db", health.CheckerFunc(db.PingContext), health.WithTimeout(time.Second))
+//	rahjoo.BindRoutesToMux(mux, h.Routes(), api)
+//
+// Liveness checks tell whether the process must be restarted, readiness checks whether it can
+// serve traffic; a failing dependency should usually only fail readiness.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+// DefaultTimeout bounds the duration of checks registered without WithTimeout.
+const DefaultTimeout = 5 * time.Second
+
+// Checker checks a dependency or a condition of the service.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption configures a check.
+type CheckOption func(*check)
+
+// WithTimeout bounds the duration of a check; a check running longer fails. It defaults to
+// DefaultTimeout.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// WithCacheTTL reuses the result of a check for d, so frequent probes do not hammer an
+// expensive dependency. Results are not cached by default.
+func WithCacheTTL(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.ttl = d
+	}
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	err     error
+	checked time.Time
+}
+
+func (c *check) run(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 && time.Since(c.checked) < c.ttl {
+		return c.err
+	}
+
+	// The result may be cached and shared by other probes, so it must not depend on the probe
+	// that ran it going away.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- c.checker.Check(ctx) }()
+	select {
+	case c.err = <-done:
+	case <-ctx.Done():
+		c.err = ctx.Err()
+	}
+	c.checked = time.Now()
+	return c.err
+}
+
+// Health is a registry of liveness and readiness checks. It is safe for concurrent use.
+type Health struct {
+	mu        sync.RWMutex
+	liveness  []*check
+	readiness []*check
+	details   bool
+}
+
+// Option configures a Health.
+type Option func(*Health)
+
+// WithDetails reports every check, with the errors of the failing ones, instead of the names of
+// the failing checks only. Errors may reveal internals such as host names, so it suits probes
+// only reachable from inside the infrastructure.
+func WithDetails() Option {
+	return func(h *Health) {
+		h.details = true
+	}
+}
+
+// New creates a Health without checks, reporting healthy.
+func New(opts ...Option) *Health {
+	h := &Health{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// AddLiveness registers a liveness check under name.
+func (h *Health) AddLiveness(name string, checker Checker, opts ...CheckOption) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, newCheck(name, checker, opts))
+}
+
+// AddReadiness registers a readiness check under name.
+func (h *Health) AddReadiness(name string, checker Checker, opts ...CheckOption) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, newCheck(name, checker, opts))
+}
+
+func newCheck(name string, checker Checker, opts []CheckOption) *check {
+	c := &check{name: name, checker: checker, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Routes returns the GET /healthz route running the liveness checks and the GET /readyz route
+// running the readiness checks, ready for BindRoutesToMux. Both answer 200 OK when all their
+// checks pass and 503 Service Unavailable otherwise, with a JSON report of the failing checks.
+func (h *Health) Routes() rahjoo.Route {
+	return rahjoo.Route{
+		"/healthz": {http.MethodGet: rahjoo.NewHandler(h.LivenessHandler())},
+		"/readyz":  {http.MethodGet: rahjoo.NewHandler(h.ReadinessHandler())},
+	}
+}
+
+// LivenessHandler returns the handler of the /healthz route, to mount it elsewhere.
+func (h *Health) LivenessHandler() http.HandlerFunc {
+	return h.handler(func() []*check { return h.liveness })
+}
+
+// ReadinessHandler returns the handler of the /readyz route, to mount it elsewhere.
+func (h *Health) ReadinessHandler() http.HandlerFunc {
+	return h.handler(func() []*check { return h.readiness })
+}
+
+// Report is the JSON body served by the health routes.
+type Report struct {
+	Status string `json:"status"`
+	// Checks holds the failing checks, or every check with WithDetails.
+	Checks map[string]CheckReport `json:"checks,omitempty"`
+}
+
+// CheckReport is the result of a check.
+type CheckReport struct {
+	Status string `json:"status"`
+	// Error is the error of a failing check, reported with WithDetails only.
+	Error string `json:"error,omitempty"`
+}
+
+// Statuses of reports.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+func (h *Health) handler(checks func() []*check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		list := slices.Clone(checks())
+		h.mu.RUnlock()
+
+		results := make([]error, len(list))
+		var wg sync.WaitGroup
+		for i, c := range list {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = c.run(r.Context())
+			}()
+		}
+		wg.Wait()
+
+		report := Report{Status: StatusOK}
+		for i, c := range list {
+			err := results[i]
+			if err == nil && !h.details {
+				continue
+			}
+			if report.Checks == nil {
+				report.Checks = make(map[string]CheckReport, len(list))
+			}
+			switch {
+			case err == nil:
+				report.Checks[c.name] = CheckReport{Status: StatusOK}
+			case h.details:
+				report.Status = StatusFail
+				report.Checks[c.name] = CheckReport{Status: StatusFail, Error: err.Error()}
+			default:
+				report.Status = StatusFail
+				report.Checks[c.name] = CheckReport{Status: StatusFail}
+			}
+		}
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
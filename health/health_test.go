@@ -0,0 +1,117 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/health"
+)
+
+func TestHealth(t *testing.T) {
+	var dbCalls atomic.Int32
+	var queueDown atomic.Bool
+
+	h := health.New(health.WithDetails())
+	h.AddLiveness("goroutines", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.AddReadiness("db", health.CheckerFunc(func(ctx context.Context) error {
+		dbCalls.Add(1)
+		return nil
+	}), health.WithCacheTTL(time.Hour))
+	h.AddReadiness("queue", health.CheckerFunc(func(ctx context.Context) error {
+		if queueDown.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+	h.AddReadiness("slow", health.CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}), health.WithTimeout(10*time.Millisecond), health.WithCacheTTL(time.Hour))
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, h.Routes()); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		var report health.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get("/healthz"); code != http.StatusOK || report.Status != health.StatusOK || len(report.Checks) != 1 {
+		t.Errorf("liveness: got %d %+v", code, report)
+	}
+
+	code, report := get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Checks["slow"].Error != context.DeadlineExceeded.Error() ||
+		report.Checks["db"].Status != health.StatusOK || report.Checks["queue"].Status != health.StatusOK {
+		t.Errorf("readiness: got %d %+v", code, report)
+	}
+
+	queueDown.Store(true)
+	code, report = get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Checks["queue"].Error != "connection refused" {
+		t.Errorf("readiness with queue down: got %d %+v", code, report)
+	}
+	if got := dbCalls.Load(); got != 1 {
+		t.Errorf("db checked %d times, want 1 thanks to the cache", got)
+	}
+}
+
+func TestHealthWithoutChecks(t *testing.T) {
+	rec := httptest.NewRecorder()
+	health.New().ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"status":"ok"}`+"\n" {
+		t.Errorf("got %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestHealthReport(t *testing.T) {
+	h := health.New()
+	h.AddReadiness("db", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.AddReadiness("queue", health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.7:5672: connection refused")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+	want := `{"status":"fail","checks":{"queue":{"status":"fail"}}}` + "\n"
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != want {
+		t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body, http.StatusServiceUnavailable, want)
+	}
+}
+
+func TestHealthCanceledProbe(t *testing.T) {
+	h := health.New()
+	h.AddReadiness("db", health.CheckerFunc(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return nil
+		}
+	}), health.WithCacheTTL(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody).WithContext(ctx))
+
+	rec = httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got %d %q, want the cached result not to depend on the canceled probe", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,29 @@
+// Package errctx carries the error returned by a rahjoo.HandlerE to the ErrorHandler middleware.
+//
+// The middleware attaches a Sink to the request context with With; the handler adapter stores
+// the returned error in it instead of writing a response, leaving the rendering to the middleware.
+package errctx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Sink receives the error returned by a handler.
+type Sink struct {
+	Err error
+}
+
+type ctxKey struct{}
+
+// With returns r carrying a new Sink, along with that Sink.
+func With(r *http.Request) (*http.Request, *Sink) {
+	sink := &Sink{}
+	return r.WithContext(context.WithValue(r.Context(), ctxKey{}, sink)), sink
+}
+
+// From returns the Sink carried by ctx, or nil.
+func From(ctx context.Context) *Sink {
+	sink, _ := ctx.Value(ctxKey{}).(*Sink)
+	return sink
+}
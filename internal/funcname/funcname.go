@@ -0,0 +1,31 @@
+// Package funcname names the functions implementing handlers and middlewares, for route
+// snapshots, traces and policy violations to refer to them the same way.
+package funcname
+
+import (
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// closureSuffix matches the suffixes the compiler gives to closures and method values.
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+|-fm)+$`)
+
+// Of returns the name of the function f without its import path and closure suffixes,
+// e.g. "middleware.Recovery".
+func Of(f any) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "<nil>"
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return "<unknown>"
+	}
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return closureSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,36 @@
+// Package routectx carries the route matched by the router to the middlewares wrapping it.
+//
+// The router fills an Info stored in the request context. A middleware wrapping the whole mux
+// attaches the Info with With before dispatching, so it can read the matched route afterwards.
+package routectx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Info describes the matched route.
+type Info struct {
+	// Path is the matched path pattern (e.g. "/users/{id}").
+	Path string
+	// Method is the HTTP method the route was registered for, empty if it handles all methods.
+	Method string
+}
+
+type ctxKey struct{}
+
+// With returns r carrying an Info, along with that Info. If r already carries one, r is
+// returned unchanged.
+func With(r *http.Request) (*http.Request, *Info) {
+	if info := From(r.Context()); info != nil {
+		return r, info
+	}
+	info := &Info{}
+	return r.WithContext(context.WithValue(r.Context(), ctxKey{}, info)), info
+}
+
+// From returns the Info carried by ctx, or nil.
+func From(ctx context.Context) *Info {
+	info, _ := ctx.Value(ctxKey{}).(*Info)
+	return info
+}
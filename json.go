@@ -0,0 +1,44 @@
+package rahjoo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/render"
+)
+
+// maxJSONBody is the size of request bodies JSON decodes at most.
+const maxJSONBody = 1 << 20
+
+// JSON adapts a typed function to an http.HandlerFunc for JSON APIs: the request body, up to
+// 1 MiB, is decoded into a Req, an empty body leaving it zero, and the Resp returned by fn is
+// written as a 200 OK response with render.Format: JSON unless another media type was
+// negotiated by middleware.Negotiate, indented on request with the render.PrettyParam flag.
+//
+//	rahjoo.NewHandler(rahjoo.JSON(createUser), middleware.ErrorHandler())
+//
+//	func createUser(ctx context.Context, req CreateUserRequest) (User, error)
+//
+// Errors are handled as with HandlerE, so they are rendered by middleware.ErrorHandler if it
+// wraps the handler. Malformed bodies are answered with 400 Bad Request and bodies too large
+// with 413 Content Too Large.
+func JSON[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) http.HandlerFunc {
+	return HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		var req Req
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBody)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+				return &HTTPError{Code: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+			}
+			return &HTTPError{Code: http.StatusBadRequest, Message: "malformed JSON body"}
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			return err
+		}
+		return render.Format(w, r, http.StatusOK, resp)
+	})
+}
@@ -0,0 +1,71 @@
+package rahjoo_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+type greetRequest struct {
+	Name string `json:"name"`
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(_ context.Context, req greetRequest) (greetResponse, error) {
+	switch req.Name {
+	case "":
+		return greetResponse{Greeting: "hello"}, nil
+	case "nobody":
+		return greetResponse{}, &rahjoo.HTTPError{Code: http.StatusNotFound, Message: "no such person"}
+	case "boom":
+		return greetResponse{}, errors.New("database down")
+	}
+	return greetResponse{Greeting: "hello " + req.Name}, nil
+}
+
+func TestJSON(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        string
+		status      int
+		contentType string
+		want        string
+	}{
+		{"decoded", `{"name":"sara"}`, http.StatusOK, "application/json; charset=utf-8", `{"greeting":"hello sara"}` + "\n"},
+		{"empty_body", "", http.StatusOK, "application/json; charset=utf-8", `{"greeting":"hello"}` + "\n"},
+		{"malformed", `{"name":`, http.StatusBadRequest, "application/problem+json", `"detail":"malformed JSON body"`},
+		{"too_large", `{"name":"` + strings.Repeat("a", 1<<20) + `"}`, http.StatusRequestEntityTooLarge, "application/problem+json", `"status":413`},
+		{"http_error", `{"name":"nobody"}`, http.StatusNotFound, "application/problem+json", `"detail":"no such person"`},
+		{"internal_error", `{"name":"boom"}`, http.StatusInternalServerError, "application/problem+json", `"status":500`},
+	}
+
+	handler := middleware.ErrorHandler()(rahjoo.JSON(greet))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(tc.body)))
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("got Content-Type %q, want %q", got, tc.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), tc.want) {
+				t.Errorf("got body %q, want it to contain %q", rec.Body.String(), tc.want)
+			}
+			if strings.Contains(rec.Body.String(), "database down") {
+				t.Error("internal error message leaked")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package links builds hypermedia links from the named routes of a rahjoo route table, so APIs
+// generate URLs from the routes they serve rather than from string templates:
+//
+//	routes := rahjoo.Route{
+//		"/users/{id}": {
+//			http.MethodGet: rahjoo.NewHandler(getUser).WithName("user"),
+//		},
+//	}
+//	builder, err := links.New(routes)
+//
+//	self, err := builder.Link("self", "user", "id", "42")
+//	links.SetHeader(w, self)          // Link: </users/42>; rel="self"
+//	resp.Links = links.Embedded(self) // "_links": {"self": {"href": "/users/42", "method": "GET"}}
+package links
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+// ErrUnknownRoute is returned when no route has the requested name.
+var ErrUnknownRoute = errors.New("links: unknown route")
+
+// Link is a link to a route.
+type Link struct {
+	// Rel is the relation of the link to the current resource, e.g. "self" or "next".
+	Rel string `json:"-"`
+	// Href is the URL of the link.
+	Href string `json:"href"`
+	// Method is the method of the route, empty for routes handling all methods.
+	Method string `json:"method,omitempty"`
+}
+
+type namedRoute struct {
+	path   rahjoo.Path
+	method rahjoo.Method
+}
+
+// Builder builds the URLs of named routes.
+type Builder struct {
+	routes map[string]namedRoute
+	prefix string
+}
+
+// Option configures a Builder.
+type Option func(*Builder)
+
+// WithPrefix prepends prefix to the built URLs, e.g. the prefix routes are bound with by
+// rahjoo.WithPrefix, or an absolute base such as "https://api.example.com".
+func WithPrefix(prefix string) Option {
+	return func(b *Builder) {
+		b.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// New returns a Builder for the routes named with WithName, e.g. the rahjoo.MergeRoutes of the
+// whole route table. It returns an error if two routes have the same name.
+func New(routes rahjoo.Route, opts ...Option) (*Builder, error) {
+	b := &Builder{routes: make(map[string]namedRoute)}
+	for _, opt := range opts {
+		opt(b)
+	}
+	for path, methods := range routes {
+		for method, action := range methods {
+			name := action.Name()
+			if name == "" {
+				continue
+			}
+			if prev, ok := b.routes[name]; ok {
+				return nil, fmt.Errorf("links: route name %q used by %s %s and %s %s", name, prev.method, prev.path, method, path)
+			}
+			b.routes[name] = namedRoute{path: path, method: method}
+		}
+	}
+	return b, nil
+}
+
+// URL returns the URL of the route name, its path wildcards replaced by params given as
+// name/value pairs:
+//
+//	builder.URL("book", "shelf_id", "3", "book_id", "7") // "/shelves/3/books/7"
+//
+// Values are escaped, except for the slashes of a "{name...}" wildcard. It returns an error
+// if the route is unknown or a wildcard has no value.
+func (b *Builder) URL(name string, params ...string) (string, error) {
+	route, ok := b.routes[name]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownRoute, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("links: odd number of params for route %q", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	path, err := expand(string(route.path), values)
+	if err != nil {
+		return "", fmt.Errorf("links: route %q: %w", name, err)
+	}
+	return b.prefix + path, nil
+}
+
+// Link returns the link with relation rel to the route name, built as URL does.
+func (b *Builder) Link(rel, name string, params ...string) (Link, error) {
+	href, err := b.URL(name, params...)
+	if err != nil {
+		return Link{}, err
+	}
+	return Link{Rel: rel, Href: href, Method: string(b.routes[name].method)}, nil
+}
+
+// expand replaces the wildcards of the ServeMux pattern path with values.
+func expand(path string, values map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			b.WriteString(path)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("malformed pattern %q", path)
+		}
+		b.WriteString(path[:start])
+		wildcard := path[start+1 : start+end]
+		path = path[start+end+1:]
+
+		if wildcard == "$" {
+			continue
+		}
+		name, rest := strings.CutSuffix(wildcard, "...")
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("missing value for %q", name)
+		}
+		if !rest {
+			b.WriteString(url.PathEscape(value))
+			continue
+		}
+		segments := strings.Split(value, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+	}
+}
+
+// Header returns the Link header value of links (RFC 8288).
+func Header(links ...Link) string {
+	values := make([]string, len(links))
+	for i, l := range links {
+		values[i] = "<" + l.Href + `>; rel="` + l.Rel + `"`
+	}
+	return strings.Join(values, ", ")
+}
+
+// SetHeader adds links to the Link header of w.
+func SetHeader(w http.ResponseWriter, links ...Link) {
+	if len(links) > 0 {
+		w.Header().Add("Link", Header(links...))
+	}
+}
+
+// Embedded returns links keyed by relation, to embed in a response body as "_links" in the
+// style of HAL.
+func Embedded(links ...Link) map[string]Link {
+	m := make(map[string]Link, len(links))
+	for _, l := range links {
+		m[l.Rel] = l
+	}
+	return m
+}
@@ -0,0 +1,112 @@
+package links_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/links"
+)
+
+func routes() rahjoo.Route {
+	h := func(http.ResponseWriter, *http.Request) {}
+	return rahjoo.NewGroupRoute("/api", rahjoo.Route{
+		"/shelves/{shelf_id}/books/{book_id}": {
+			http.MethodGet:    rahjoo.NewHandler(h).WithName("book"),
+			http.MethodDelete: rahjoo.NewHandler(h).WithName("delete-book"),
+		},
+		"/files/{path...}": {
+			"": rahjoo.NewHandler(h).WithName("file"),
+		},
+		"/{$}": {
+			http.MethodGet: rahjoo.NewHandler(h).WithName("root"),
+		},
+		"/unnamed": {
+			http.MethodGet: rahjoo.NewHandler(h),
+		},
+	})
+}
+
+func TestURL(t *testing.T) {
+	b, err := links.New(routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		route   string
+		params  []string
+		want    string
+		wantErr bool
+	}{
+		{"params", "book", []string{"shelf_id", "3", "book_id", "7"}, "/api/shelves/3/books/7", false},
+		{"escaped", "book", []string{"shelf_id", "a b", "book_id", "x/y"}, "/api/shelves/a%20b/books/x%2Fy", false},
+		{"rest_wildcard", "file", []string{"path", "docs/read me.md"}, "/api/files/docs/read%20me.md", false},
+		{"end_anchor", "root", nil, "/api/", false},
+		{"missing_param", "book", []string{"shelf_id", "3"}, "", true},
+		{"odd_params", "book", []string{"shelf_id"}, "", true},
+		{"unknown", "unnamed", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := b.URL(tc.route, tc.params...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := b.URL("unnamed"); !errors.Is(err, links.ErrUnknownRoute) {
+		t.Errorf("got error %v, want %v", err, links.ErrUnknownRoute)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	b, err := links.New(routes(), links.WithPrefix("https://example.com/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err := b.Link("self", "book", "shelf_id", "3", "book_id", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	del, err := b.Link("delete", "delete-book", "shelf_id", "3", "book_id", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	links.SetHeader(rec, self, del)
+	want := `<https://example.com/api/shelves/3/books/7>; rel="self", <https://example.com/api/shelves/3/books/7>; rel="delete"`
+	if got := rec.Header().Get("Link"); got != want {
+		t.Errorf("got Link %q, want %q", got, want)
+	}
+
+	body, err := json.Marshal(map[string]any{"_links": links.Embedded(self, del)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBody := `{"_links":{"delete":{"href":"https://example.com/api/shelves/3/books/7","method":"DELETE"},"self":{"href":"https://example.com/api/shelves/3/books/7","method":"GET"}}}`
+	if string(body) != wantBody {
+		t.Errorf("got body %s, want %s", body, wantBody)
+	}
+}
+
+func TestNewDuplicateName(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	_, err := links.New(rahjoo.Route{
+		"/a": {http.MethodGet: rahjoo.NewHandler(h).WithName("dup")},
+		"/b": {http.MethodGet: rahjoo.NewHandler(h).WithName("dup")},
+	})
+	if err == nil {
+		t.Error("expected an error for duplicate route names")
+	}
+}
@@ -0,0 +1,139 @@
+package rahjoo
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Filter operators of ListQuery filters.
+const (
+	FilterEq  = "eq"
+	FilterNe  = "ne"
+	FilterGt  = "gt"
+	FilterGte = "gte"
+	FilterLt  = "lt"
+	FilterLte = "lte"
+	FilterIn  = "in"
+)
+
+var filterOperators = []string{FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterIn}
+
+// ListQueryFields is the allowlist of fields a list endpoint can be sorted and filtered by.
+type ListQueryFields struct {
+	// Sortable are the fields allowed in the sort parameter.
+	Sortable []string
+	// Filterable are the fields allowed in filter parameters.
+	Filterable []string
+	// DefaultSort is the sort applied when the request has none, in the syntax of the sort
+	// parameter (e.g. "-created_at").
+	DefaultSort string
+}
+
+// SortField is a field to sort a list by.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a condition on a field of the items of a list.
+type Filter struct {
+	Field string
+	// Operator is one of the Filter constants, FilterEq if the request did not set one.
+	Operator string
+	// Values holds the compared value, or the values of a FilterIn filter.
+	Values []string
+}
+
+// ListQuery is the sorting and filtering requested for a list.
+type ListQuery struct {
+	Sort []SortField
+	// Filters are sorted by field then operator.
+	Filters []Filter
+}
+
+// ParseListQuery parses the sorting and filtering of a list requested by the query parameters
+// of r, with the same semantics across endpoints:
+//
+//	?sort=-created_at,name&filter[status]=active&filter[age][gte]=18&filter[role][in]=admin,owner
+//
+// sort is a comma-separated list of fields, descending if prefixed with "-". Filters are named
+// filter[field] for equality, or filter[field][operator] with an operator among eq, ne, gt, gte,
+// lt, lte and in, whose value is comma-separated. Fields outside of the allowlist, unknown
+// operators and malformed parameters are returned as BindErrors, answered with 400 Bad Request
+// by middleware.ErrorHandler.
+func ParseListQuery(r *http.Request, fields ListQueryFields) (ListQuery, error) {
+	query := r.URL.Query()
+	var lq ListQuery
+	var errs BindErrors
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = fields.DefaultSort
+	}
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, desc := strings.CutPrefix(field, "-")
+		if !slices.Contains(fields.Sortable, name) {
+			errs = append(errs, &BindError{Source: "query", Name: "sort", Value: field, Err: errors.New("field is not sortable")})
+			continue
+		}
+		lq.Sort = append(lq.Sort, SortField{Field: name, Desc: desc})
+	}
+
+	for param, values := range query {
+		rest, ok := strings.CutPrefix(param, "filter[")
+		if !ok {
+			continue
+		}
+		field, operator, err := parseFilterParam(rest)
+		switch {
+		case err != nil:
+		case !slices.Contains(fields.Filterable, field):
+			err = errors.New("field is not filterable")
+		case !slices.Contains(filterOperators, operator):
+			err = errors.New("unknown operator")
+		}
+		if err != nil {
+			errs = append(errs, &BindError{Source: "query", Name: param, Value: values[0], Err: err})
+			continue
+		}
+		f := Filter{Field: field, Operator: operator, Values: values[:1]}
+		if operator == FilterIn {
+			f.Values = strings.Split(values[0], ",")
+		}
+		lq.Filters = append(lq.Filters, f)
+	}
+	slices.SortFunc(lq.Filters, func(a, b Filter) int {
+		return cmp.Or(strings.Compare(a.Field, b.Field), strings.Compare(a.Operator, b.Operator))
+	})
+
+	if len(errs) > 0 {
+		slices.SortStableFunc(errs, func(a, b *BindError) int { return strings.Compare(a.Name, b.Name) })
+		return ListQuery{}, errs
+	}
+	return lq, nil
+}
+
+// parseFilterParam parses the "field]" or "field][operator]" rest of a filter parameter.
+func parseFilterParam(rest string) (field, operator string, err error) {
+	field, rest, ok := strings.Cut(rest, "]")
+	if !ok || field == "" {
+		return "", "", errors.New("malformed filter parameter")
+	}
+	if rest == "" {
+		return field, FilterEq, nil
+	}
+	if rest, ok = strings.CutPrefix(rest, "["); ok {
+		operator, ok = strings.CutSuffix(rest, "]")
+	}
+	if !ok || operator == "" {
+		return "", "", errors.New("malformed filter parameter")
+	}
+	return field, operator, nil
+}
@@ -0,0 +1,91 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestParseListQuery(t *testing.T) {
+	fields := rahjoo.ListQueryFields{
+		Sortable:    []string{"created_at", "name"},
+		Filterable:  []string{"status", "age", "role"},
+		DefaultSort: "-created_at",
+	}
+
+	testCases := []struct {
+		name    string
+		query   url.Values
+		want    rahjoo.ListQuery
+		wantErr []string
+	}{
+		{
+			name:  "default_sort",
+			query: nil,
+			want:  rahjoo.ListQuery{Sort: []rahjoo.SortField{{Field: "created_at", Desc: true}}},
+		},
+		{
+			name: "sort_and_filters",
+			query: url.Values{
+				"sort":              {"-created_at, name"},
+				"filter[status]":    {"active"},
+				"filter[age][gte]":  {"18"},
+				"filter[age][lt]":   {"65"},
+				"filter[role][in]":  {"admin,owner"},
+				"unrelated[filter]": {"x"},
+			},
+			want: rahjoo.ListQuery{
+				Sort: []rahjoo.SortField{{Field: "created_at", Desc: true}, {Field: "name"}},
+				Filters: []rahjoo.Filter{
+					{Field: "age", Operator: rahjoo.FilterGte, Values: []string{"18"}},
+					{Field: "age", Operator: rahjoo.FilterLt, Values: []string{"65"}},
+					{Field: "role", Operator: rahjoo.FilterIn, Values: []string{"admin", "owner"}},
+					{Field: "status", Operator: rahjoo.FilterEq, Values: []string{"active"}},
+				},
+			},
+		},
+		{
+			name: "not_allowed",
+			query: url.Values{
+				"sort":                 {"password"},
+				"filter[secret]":       {"x"},
+				"filter[age][between]": {"1,2"},
+				"filter[status":        {"active"},
+				"filter[status][eq":    {"active"},
+			},
+			wantErr: []string{"filter[age][between]", "filter[secret]", "filter[status", "filter[status][eq", "sort"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items?"+tc.query.Encode(), http.NoBody)
+			got, err := rahjoo.ParseListQuery(req, fields)
+			if tc.wantErr != nil {
+				var errs rahjoo.BindErrors
+				if !errors.As(err, &errs) {
+					t.Fatalf("got error %v, want BindErrors", err)
+				}
+				names := make([]string, len(errs))
+				for i, e := range errs {
+					names[i] = e.Name
+				}
+				if !reflect.DeepEqual(names, tc.wantErr) {
+					t.Errorf("got errors for %v, want %v", names, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
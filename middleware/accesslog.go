@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Access log formats understood by log processors such as GoAccess and awstats.
+const (
+	// CommonLogFormat is the Apache Common Log Format.
+	CommonLogFormat = `%h %l %u %t "%r" %>s %b`
+	// CombinedLogFormat is the Apache Combined Log Format.
+	CombinedLogFormat = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+)
+
+// accessLogEntry holds what an access log line is rendered from, and the buffer it is rendered to.
+type accessLogEntry struct {
+	r        *http.Request
+	rw       ResponseWriter
+	start    time.Time
+	duration time.Duration
+	buf      bytes.Buffer
+}
+
+// accessLogEntryPool recycles accessLogEntries along with their buffers.
+var accessLogEntryPool = sync.Pool{New: func() any { return new(accessLogEntry) }}
+
+type accessLogField func(b *bytes.Buffer, e *accessLogEntry)
+
+// AccessLog is a middleware that writes one line per request to w using an Apache mod_log_config
+// style format, such as CommonLogFormat or CombinedLogFormat. Supported directives are:
+//
+//	%h  remote IP            %l  remote logname, always "-"
+//	%u  basic auth user      %t  time the request was received
+//	%r  request line         %s, %>s  status code
+//	%b  bytes or "-"         %B  bytes
+//	%D  duration in µs       %T  duration in seconds
+//	%m  method               %U  URL path
+//	%q  query string         %H  protocol
+//	%{Name}i  request header %{Name}o  response header
+//	%%  literal percent sign
+//
+// Unknown directives are written as is. Writes to w are serialized.
+func AccessLog(w io.Writer, format string) Middleware {
+	fields := parseAccessLogFormat(format)
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			e := accessLogEntryPool.Get().(*accessLogEntry)
+			e.r, e.rw, e.start = r, AcquireResponseWriter(rw), time.Now()
+			defer func() {
+				ReleaseResponseWriter(e.rw, rw)
+				e.r, e.rw = nil, nil
+				e.buf.Reset()
+				accessLogEntryPool.Put(e)
+			}()
+			next.ServeHTTP(e.rw, r)
+			e.duration = time.Since(e.start)
+
+			for _, field := range fields {
+				field(&e.buf, e)
+			}
+			e.buf.WriteByte('\n')
+
+			mu.Lock()
+			defer mu.Unlock()
+			w.Write(e.buf.Bytes())
+		})
+	}
+}
+
+func parseAccessLogFormat(format string) []accessLogField {
+	var fields []accessLogField
+	literal := func(s string) accessLogField {
+		return func(b *bytes.Buffer, _ *accessLogEntry) { b.WriteString(s) }
+	}
+
+	for len(format) > 0 {
+		i := strings.IndexByte(format, '%')
+		if i < 0 {
+			fields = append(fields, literal(format))
+			break
+		}
+		if i > 0 {
+			fields = append(fields, literal(format[:i]))
+		}
+		format = format[i:]
+
+		directive := format[:min(2, len(format))]
+		var name string
+		if strings.HasPrefix(format, "%{") {
+			if end := strings.IndexByte(format, '}'); end > 0 && end+1 < len(format) {
+				name, directive = format[2:end], "%{}"+format[end+1:end+2]
+				format = format[end+2:]
+			} else {
+				fields = append(fields, literal(format))
+				break
+			}
+		} else if strings.HasPrefix(format, "%>s") {
+			directive = "%s"
+			format = format[3:]
+		} else {
+			format = format[len(directive):]
+		}
+
+		if field := accessLogDirective(directive, name); field != nil {
+			fields = append(fields, field)
+		} else {
+			fields = append(fields, literal(directive))
+		}
+	}
+	return fields
+}
+
+func accessLogDirective(directive, name string) accessLogField {
+	switch directive {
+	case "%%":
+		return func(b *bytes.Buffer, _ *accessLogEntry) { b.WriteByte('%') }
+	case "%h":
+		return func(b *bytes.Buffer, e *accessLogEntry) { b.WriteString(remoteIP(e.r)) }
+	case "%l":
+		return func(b *bytes.Buffer, _ *accessLogEntry) { b.WriteByte('-') }
+	case "%u":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			user, _, ok := e.r.BasicAuth()
+			if !ok || user == "" {
+				user = "-"
+			}
+			b.WriteString(user)
+		}
+	case "%t":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			b.Write(e.start.AppendFormat(b.AvailableBuffer(), "[02/Jan/2006:15:04:05 -0700]"))
+		}
+	case "%r":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			b.WriteString(e.r.Method)
+			b.WriteByte(' ')
+			b.WriteString(e.r.URL.RequestURI())
+			b.WriteByte(' ')
+			b.WriteString(e.r.Proto)
+		}
+	case "%s":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			b.Write(strconv.AppendInt(b.AvailableBuffer(), int64(e.rw.Status()), 10))
+		}
+	case "%b":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			if e.rw.BytesWritten() == 0 {
+				b.WriteByte('-')
+				return
+			}
+			b.Write(strconv.AppendInt(b.AvailableBuffer(), e.rw.BytesWritten(), 10))
+		}
+	case "%B":
+		return func(b *bytes.Buffer, e *accessLogEntry) { b.WriteString(strconv.FormatInt(e.rw.BytesWritten(), 10)) }
+	case "%D":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			b.WriteString(strconv.FormatInt(e.duration.Microseconds(), 10))
+		}
+	case "%T":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			b.WriteString(strconv.FormatInt(int64(e.duration.Seconds()), 10))
+		}
+	case "%m":
+		return func(b *bytes.Buffer, e *accessLogEntry) { b.WriteString(e.r.Method) }
+	case "%U":
+		return func(b *bytes.Buffer, e *accessLogEntry) { b.WriteString(e.r.URL.Path) }
+	case "%q":
+		return func(b *bytes.Buffer, e *accessLogEntry) {
+			if e.r.URL.RawQuery != "" {
+				b.WriteString("?" + e.r.URL.RawQuery)
+			}
+		}
+	case "%H":
+		return func(b *bytes.Buffer, e *accessLogEntry) { b.WriteString(e.r.Proto) }
+	case "%{}i":
+		return func(b *bytes.Buffer, e *accessLogEntry) { writeHeaderValue(b, e.r.Header.Get(name)) }
+	case "%{}o":
+		return func(b *bytes.Buffer, e *accessLogEntry) { writeHeaderValue(b, e.rw.Header().Get(name)) }
+	}
+	return nil
+}
+
+func writeHeaderValue(b *bytes.Buffer, v string) {
+	if v == "" {
+		v = "-"
+	}
+	b.WriteString(strings.ReplaceAll(v, `"`, `\"`))
+}
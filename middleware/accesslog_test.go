@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "rahjoo")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	testCases := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"common", middleware.CommonLogFormat,
+			`^192\.0\.2\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /users\?page=2 HTTP/1\.1" 201 5\n$`},
+		{"combined", middleware.CombinedLogFormat,
+			`^192\.0\.2\.1 - alice \[.+\] "POST /users\?page=2 HTTP/1\.1" 201 5 "https://example\.com/" "curl/8\.0"\n$`},
+		{"custom", `%m %U%q %s %{X-Served-By}o %{X-Missing}i %D 100%% %x`,
+			`^POST /users\?page=2 201 rahjoo - \d+ 100% %x\n$`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req := httptest.NewRequest(http.MethodPost, "/users?page=2", http.NoBody)
+			req.SetBasicAuth("alice", "secret")
+			req.Header.Set("Referer", "https://example.com/")
+			req.Header.Set("User-Agent", "curl/8.0")
+
+			middleware.AccessLog(&buf, tc.format)(h).ServeHTTP(httptest.NewRecorder(), req)
+
+			if !regexp.MustCompile(tc.want).Match(buf.Bytes()) {
+				t.Errorf("got log line %q, want match for %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
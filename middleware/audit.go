@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+// AuditEvent records who did what through a mutating request.
+type AuditEvent struct {
+	Time      time.Time         `json:"time"`
+	Principal string            `json:"principal,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Method    string            `json:"method"`
+	Route     string            `json:"route,omitempty"`
+	Path      string            `json:"path"`
+	Params    map[string]string `json:"params,omitempty"`
+	Status    int               `json:"status"`
+	RequestID string            `json:"request_id,omitempty"`
+	RemoteIP  string            `json:"remote_ip,omitempty"`
+	// Extra holds application-specific details set by the extract function.
+	Extra map[string]any `json:"extra,omitempty"`
+}
+
+// AuditSink stores audit events.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to an AuditSink.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent) error
+
+// Record implements AuditSink.
+func (f AuditSinkFunc) Record(ctx context.Context, event AuditEvent) error {
+	return f(ctx, event)
+}
+
+// Audit is a middleware recording an AuditEvent to sink for every POST, PUT, PATCH and DELETE
+// request once it is served. The event carries the principal (see GetPrincipal), the matched
+// route pattern with its path parameters, the status code, the request ID and the client IP;
+// extract, if not nil, is called first to fill the application-specific fields such as Action
+// or Extra, and may set any other field to override the default.
+//
+// Place Audit after the authentication middleware, so the principal is known. Sink errors do
+// not affect the response; sinks needing delivery guarantees must handle them.
+func Audit(sink AuditSink, extract func(*http.Request) AuditEvent) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, route := routectx.With(r)
+			rw := AcquireResponseWriter(w)
+			defer ReleaseResponseWriter(rw, w)
+			next.ServeHTTP(rw, r)
+
+			var event AuditEvent
+			if extract != nil {
+				event = extract(r)
+			}
+			if event.Time.IsZero() {
+				event.Time = time.Now()
+			}
+			if event.Principal == "" {
+				event.Principal, _ = GetPrincipal(r.Context())
+			}
+			if event.Method == "" {
+				event.Method = r.Method
+			}
+			if event.Route == "" {
+				event.Route = route.Path
+			}
+			if event.Path == "" {
+				event.Path = r.URL.Path
+			}
+			if event.Params == nil {
+				event.Params = pathParams(r, route.Path)
+			}
+			if event.Status == 0 {
+				event.Status = rw.Status()
+			}
+			if event.RequestID == "" {
+				event.RequestID = requestIDOf(r)
+			}
+			if event.RemoteIP == "" {
+				event.RemoteIP = remoteIP(r)
+			}
+			sink.Record(context.WithoutCancel(r.Context()), event)
+		})
+	}
+}
+
+// pathParams returns the values of the wildcards of the route pattern matched by r.
+func pathParams(r *http.Request, pattern string) map[string]string {
+	var params map[string]string
+	for _, segment := range strings.Split(pattern, "/") {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+		if name == "$" {
+			continue
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[name] = r.PathValue(name)
+	}
+	return params
+}
+
+// NewAuditWriterSink returns an AuditSink writing events as JSON lines to w, e.g. an
+// append-only file. It is safe for concurrent use.
+func NewAuditWriterSink(w io.Writer) AuditSink {
+	var mu sync.Mutex
+	return AuditSinkFunc(func(_ context.Context, event AuditEvent) error {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err = w.Write(append(line, '\n'))
+		return err
+	})
+}
+
+// NewAuditChannelSink returns an AuditSink sending events to ch, for processing in another
+// goroutine. It blocks while ch is full, until the request context is done.
+func NewAuditChannelSink(ch chan<- AuditEvent) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+		select {
+		case ch <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// NewAuditHTTPSink returns an AuditSink posting events as JSON to url with client
+// (http.DefaultClient if nil). Responses other than 2xx are reported as errors.
+func NewAuditHTTPSink(url string, client *http.Client) AuditSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("audit: %s responded %s", url, resp.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestAudit(t *testing.T) {
+	events := make(chan middleware.AuditEvent, 10)
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithPrincipal(r.Context(), "alice")))
+		})
+	}
+	audit := middleware.Audit(middleware.NewAuditChannelSink(events), func(r *http.Request) middleware.AuditEvent {
+		return middleware.AuditEvent{Action: "order." + r.Method, Extra: map[string]any{"tenant": r.Header.Get("X-Tenant")}}
+	})
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux, rahjoo.Route{
+		"/orders/{id}": {
+			"": rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}, authenticate, audit),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/orders/42", http.NoBody)
+		req.Header.Set("X-Tenant", "acme")
+		req.RemoteAddr = "1.2.3.4:5678"
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := <-events
+	if event.Principal != "alice" || event.Action != "order.DELETE" || event.Method != http.MethodDelete ||
+		event.Route != "/orders/{id}" || event.Path != "/orders/42" || event.Params["id"] != "42" ||
+		event.Status != http.StatusNoContent || event.RemoteIP != "1.2.3.4" || event.Extra["tenant"] != "acme" || event.Time.IsZero() {
+		t.Errorf("got event %+v", event)
+	}
+}
+
+func TestAuditSinks(t *testing.T) {
+	event := middleware.AuditEvent{Principal: "bob", Method: http.MethodPost, Path: "/users", Status: 201}
+
+	var buf bytes.Buffer
+	if err := middleware.NewAuditWriterSink(&buf).Record(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	var got middleware.AuditEvent
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil || got.Principal != "bob" || buf.Bytes()[buf.Len()-1] != '\n' {
+		t.Errorf("writer sink: got %q, %v", buf.String(), err)
+	}
+
+	var received middleware.AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	if err := middleware.NewAuditHTTPSink(srv.URL+"/events", nil).Record(context.Background(), event); err != nil || received.Path != "/users" {
+		t.Errorf("http sink: got %+v, %v", received, err)
+	}
+	if err := middleware.NewAuditHTTPSink(srv.URL+"/fail", srv.Client()).Record(context.Background(), event); err == nil {
+		t.Error("http sink: expected error for a 502 response")
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Authorizer decides whether the authenticated principal may perform a request, e.g. by
+// consulting a policy engine. A non-nil error means the decision could not be made.
+type Authorizer interface {
+	Authorize(r *http.Request, principal string) (bool, error)
+}
+
+// AuthorizerFunc adapts a function to an Authorizer.
+type AuthorizerFunc func(r *http.Request, principal string) (bool, error)
+
+// Authorize implements Authorizer.
+func (f AuthorizerFunc) Authorize(r *http.Request, principal string) (bool, error) {
+	return f(r, principal)
+}
+
+// Authorize is a middleware letting requests through only if authorizer allows them. It must
+// run after the authentication middleware storing the principal (see WithPrincipal).
+// Anonymous requests get 401 Unauthorized, denied ones 403 Forbidden and requests the
+// authorizer fails to decide on 500 Internal Server Error.
+func Authorize(authorizer Authorizer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return authorize(next, authorizer, nil)
+	}
+}
+
+// RequireScopes is a middleware letting requests through only if the principal was granted all
+// of scopes, as returned by GetScopes. Denied requests get 403 Forbidden with an
+// insufficient_scope challenge as specified by RFC 6750.
+func RequireScopes(scopes ...string) Middleware {
+	challenge := `Bearer error="insufficient_scope", scope=` + strconv.Quote(strings.Join(scopes, " "))
+	authorizer := AuthorizerFunc(func(r *http.Request, _ string) (bool, error) {
+		granted := GetScopes(r.Context())
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	onDeny := func(w http.ResponseWriter) {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	return func(next http.Handler) http.Handler {
+		return authorize(next, authorizer, onDeny)
+	}
+}
+
+// RequireRoles is a middleware letting requests through only if the principal has any of
+// roles, as returned by GetRoles. Denied requests get 403 Forbidden.
+func RequireRoles(roles ...string) Middleware {
+	authorizer := AuthorizerFunc(func(r *http.Request, _ string) (bool, error) {
+		granted := GetRoles(r.Context())
+		return slices.ContainsFunc(roles, func(role string) bool {
+			return slices.Contains(granted, role)
+		}), nil
+	})
+	return func(next http.Handler) http.Handler {
+		return authorize(next, authorizer, nil)
+	}
+}
+
+// authorize wraps next with the checks of the authorization middlewares. Each of them returns
+// its own closure calling it, so rahjoo.RequireMiddleware tells them apart.
+func authorize(next http.Handler, authorizer Authorizer, onDeny func(w http.ResponseWriter)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := GetPrincipal(r.Context())
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		allowed, err := authorizer.Authorize(r, principal)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			if onDeny != nil {
+				onDeny(w)
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type scopesKey struct{}
+
+type rolesKey struct{}
+
+// WithScopes returns a copy of ctx carrying the scopes granted to the principal. Authentication
+// middlewares not based on tokens, e.g. API keys, store the scopes with it for RequireScopes.
+func WithScopes(ctx context.Context, scopes ...string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// GetScopes returns the scopes stored in ctx by WithScopes or, failing that, those of the token
+// authenticated by the JWT, OIDC or Introspection middleware.
+func GetScopes(ctx context.Context) []string {
+	if scopes, ok := ctx.Value(scopesKey{}).([]string); ok {
+		return scopes
+	}
+	if claims, ok := GetJWTClaims(ctx); ok {
+		return claims.Scopes()
+	}
+	return nil
+}
+
+// WithRoles returns a copy of ctx carrying the roles of the principal. Authentication
+// middlewares not based on tokens, e.g. API keys, store the roles with it for RequireRoles.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// GetRoles returns the roles stored in ctx by WithRoles or, failing that, the "roles" claim of
+// the token authenticated by the JWT, OIDC or Introspection middleware.
+func GetRoles(ctx context.Context) []string {
+	if roles, ok := ctx.Value(rolesKey{}).([]string); ok {
+		return roles
+	}
+	if claims, ok := GetJWTClaims(ctx); ok {
+		return claims.Roles()
+	}
+	return nil
+}
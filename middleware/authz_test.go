@@ -0,0 +1,138 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRequireScopesAndRoles(t *testing.T) {
+	key := []byte("secret")
+	jwt := middleware.JWT(func(*middleware.Token) (any, error) { return key, nil })
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	token := func(claims map[string]any) string {
+		claims["sub"] = "alice"
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		return signJWT(t, "HS256", key, nil, claims)
+	}
+
+	testCases := []struct {
+		name      string
+		required  middleware.Middleware
+		claims    map[string]any
+		status    int
+		challenge string
+	}{
+		{"scopes_granted", middleware.RequireScopes("users:read", "users:write"), map[string]any{"scope": "users:read users:write admin"}, http.StatusOK, ""},
+		{"scp_list", middleware.RequireScopes("users:read"), map[string]any{"scp": []any{"users:read"}}, http.StatusOK, ""},
+		{"scope_missing", middleware.RequireScopes("users:read", "users:write"), map[string]any{"scope": "users:read"}, http.StatusForbidden, `Bearer error="insufficient_scope", scope="users:read users:write"`},
+		{"no_scopes", middleware.RequireScopes("users:read"), map[string]any{}, http.StatusForbidden, `Bearer error="insufficient_scope", scope="users:read"`},
+		{"any_role", middleware.RequireRoles("admin", "editor"), map[string]any{"roles": []any{"viewer", "editor"}}, http.StatusOK, ""},
+		{"single_role", middleware.RequireRoles("admin"), map[string]any{"roles": "admin"}, http.StatusOK, ""},
+		{"role_missing", middleware.RequireRoles("admin"), map[string]any{"roles": []any{"viewer"}}, http.StatusForbidden, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+token(tc.claims))
+			rec := httptest.NewRecorder()
+			middleware.Chain(ok, jwt, tc.required).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tc.challenge {
+				t.Errorf("got challenge %q, want %q", got, tc.challenge)
+			}
+		})
+	}
+}
+
+func TestRequireScopesFromContext(t *testing.T) {
+	// apiKey simulates an API key middleware granting scopes and roles without a token.
+	apiKey := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") == "key-1" {
+				ctx := middleware.WithPrincipal(r.Context(), "service-1")
+				ctx = middleware.WithScopes(ctx, "reports:read")
+				r = r.WithContext(middleware.WithRoles(ctx, "service"))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	testCases := []struct {
+		name     string
+		key      string
+		required middleware.Middleware
+		status   int
+	}{
+		{"scope", "key-1", middleware.RequireScopes("reports:read"), http.StatusOK},
+		{"role", "key-1", middleware.RequireRoles("service"), http.StatusOK},
+		{"denied", "key-1", middleware.RequireScopes("reports:write"), http.StatusForbidden},
+		{"anonymous", "", middleware.RequireScopes("reports:read"), http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("X-Api-Key", tc.key)
+			rec := httptest.NewRecorder()
+			middleware.Chain(ok, apiKey, tc.required).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	authorizer := middleware.AuthorizerFunc(func(r *http.Request, principal string) (bool, error) {
+		switch principal {
+		case "broken":
+			return false, errors.New("policy store unavailable")
+		case "alice":
+			return r.Method == http.MethodGet, nil
+		}
+		return false, nil
+	})
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	testCases := []struct {
+		name      string
+		principal string
+		method    string
+		status    int
+	}{
+		{"allowed", "alice", http.MethodGet, http.StatusOK},
+		{"denied_method", "alice", http.MethodDelete, http.StatusForbidden},
+		{"denied_principal", "bob", http.MethodGet, http.StatusForbidden},
+		{"error", "broken", http.MethodGet, http.StatusInternalServerError},
+		{"anonymous", "", http.MethodGet, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", http.NoBody)
+			req = req.WithContext(middleware.WithPrincipal(req.Context(), tc.principal))
+			rec := httptest.NewRecorder()
+			middleware.Authorize(authorizer)(ok).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestClaimsScopes(t *testing.T) {
+	claims := middleware.Claims{"scope": "a b", "scp": []any{"c d", 1, "e"}}
+	if got, want := claims.Scopes(), []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("got scopes %v, want %v", got, want)
+	}
+}
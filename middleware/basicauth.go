@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+type basicAuthUserKey struct{}
+
+// BasicAuth is a middleware protecting routes, such as admin or debug ones, with HTTP Basic
+// authentication. Credentials are checked with validate, which should compare them with
+// SecureCompare, or can be built with BasicAuthUsers. Unauthenticated requests are answered with
+// 401 Unauthorized and a WWW-Authenticate challenge for realm. The authenticated username is
+// stored in the request context, readable with GetBasicAuthUser, and as the principal
+// (see GetPrincipal).
+func BasicAuth(realm string, validate func(user, pass string) bool) Middleware {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || !validate(user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), basicAuthUserKey{}, user)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, user)))
+		})
+	}
+}
+
+// GetBasicAuthUser returns the username authenticated by the BasicAuth middleware.
+func GetBasicAuthUser(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(basicAuthUserKey{}).(string)
+	return user, ok
+}
+
+// SecureCompare reports whether a and b are equal in constant time, leaking neither their
+// content nor their length through timing.
+func SecureCompare(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// BasicAuthUsers returns a validate function for BasicAuth accepting the given username to
+// password pairs. Passwords are compared with SecureCompare.
+func BasicAuthUsers(users map[string]string) func(user, pass string) bool {
+	return func(user, pass string) bool {
+		want, ok := users[user]
+		// compare even for unknown users to keep timing uniform.
+		return SecureCompare(pass, want) && ok
+	}
+}
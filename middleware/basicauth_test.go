@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestBasicAuth(t *testing.T) {
+	h := middleware.BasicAuth("admin", middleware.BasicAuthUsers(map[string]string{"alice": "s3cret"}))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := middleware.GetBasicAuthUser(r.Context())
+			principal, _ := middleware.GetPrincipal(r.Context())
+			w.Write([]byte(user + ":" + principal))
+		}))
+
+	testCases := []struct {
+		name   string
+		user   string
+		pass   string
+		status int
+		body   string
+	}{
+		{"valid", "alice", "s3cret", http.StatusOK, "alice:alice"},
+		{"wrong_password", "alice", "nope", http.StatusUnauthorized, ""},
+		{"unknown_user", "bob", "", http.StatusUnauthorized, ""},
+		{"missing", "", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == http.StatusUnauthorized {
+				if got, want := rec.Header().Get("WWW-Authenticate"), `Basic realm="admin", charset="UTF-8"`; got != want {
+					t.Errorf("got WWW-Authenticate %q, want %q", got, want)
+				}
+				return
+			}
+			if rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !middleware.SecureCompare("abc", "abc") {
+		t.Error("equal strings must compare equal")
+	}
+	if middleware.SecureCompare("abc", "abcd") || middleware.SecureCompare("abc", "abd") {
+		t.Error("different strings must not compare equal")
+	}
+}
@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by the Cache middleware.
+type CachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Expires time.Time
+}
+
+// CacheStore keeps the responses of the Cache middleware. Keys start with the request URL path,
+// followed by "?" and the rest of the key.
+type CacheStore interface {
+	// Get returns the unexpired response stored under key.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores resp under key until resp.Expires.
+	Set(key string, resp *CachedResponse)
+	// Purge removes every response whose URL path matches pattern, using path.Match syntax,
+	// and returns how many were removed.
+	Purge(pattern string) int
+}
+
+type cacheConfig struct {
+	store        CacheStore
+	keyHeaders   []string
+	credentialed bool
+}
+
+// CacheOption configures the Cache middleware.
+type CacheOption func(*cacheConfig)
+
+// WithCacheStore sets the store responses are kept in. It defaults to a new MemoryCacheStore.
+func WithCacheStore(store CacheStore) CacheOption {
+	return func(c *cacheConfig) {
+		c.store = store
+	}
+}
+
+// WithCacheKeyHeaders adds the given request headers (e.g. "Accept", "Accept-Language") to the
+// cache key, so responses varying by them are cached separately.
+func WithCacheKeyHeaders(headers ...string) CacheOption {
+	return func(c *cacheConfig) {
+		c.keyHeaders = append(c.keyHeaders, headers...)
+	}
+}
+
+// WithCacheCredentialedRequests caches responses to requests carrying an Authorization or Cookie
+// header too, keyed by these headers so they are only served to the same credentials. Such
+// requests bypass the cache by default.
+func WithCacheCredentialedRequests() CacheOption {
+	return func(c *cacheConfig) {
+		c.credentialed = true
+	}
+}
+
+// ResponseCache caches responses of GET requests. Create it with Cache.
+type ResponseCache struct {
+	ttl    time.Duration
+	config cacheConfig
+}
+
+// Cache creates a ResponseCache keeping successful responses to GET requests for ttl, keyed by
+// host, path, query, the headers given with WithCacheKeyHeaders and those listed by the Vary
+// header of the response. Its Handler method is the middleware:
+//
+//	cache := middleware.Cache(time.Minute)
+//	rahjoo.NewHandler(listBooks, cache.Handler)
+//
+// Requests with credentials, unless WithCacheCredentialedRequests is given, and responses with
+// Set-Cookie, with Vary: * or with a Cache-Control header containing no-store or private are
+// not cached. Requests with Cache-Control: no-cache bypass the cached response and refresh it.
+// Served responses carry an X-Cache header set to HIT or MISS.
+func Cache(ttl time.Duration, opts ...CacheOption) *ResponseCache {
+	c := &ResponseCache{ttl: ttl}
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	if c.config.store == nil {
+		c.config.store = NewMemoryCacheStore()
+	}
+	if c.config.credentialed {
+		c.config.keyHeaders = append(c.config.keyHeaders, "Authorization", "Cookie")
+	}
+	return c
+}
+
+// Handler is the middleware serving cached responses.
+func (c *ResponseCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || (!c.config.credentialed && hasCredentials(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		base := requestKey(r, c.config.keyHeaders)
+		if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+			resp, ok := c.config.store.Get(base)
+			// A response varying by request headers is stored under a key including them,
+			// with an entry listing them under the base key.
+			if fields, _ := varyFields(resp.header()); ok && len(fields) > 0 {
+				resp, ok = c.config.store.Get(varyKey(base, fields, r.Header))
+			}
+			if ok {
+				h := w.Header()
+				for k, v := range resp.Header {
+					h[k] = slices.Clone(v)
+				}
+				h.Set("X-Cache", "HIT")
+				w.WriteHeader(resp.Status)
+				w.Write(resp.Body)
+				return
+			}
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		cw := &cacheWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(cw, r)
+
+		if cw.status != http.StatusOK || !cacheable(cw.header) {
+			return
+		}
+		fields, ok := varyFields(cw.header)
+		if !ok {
+			return
+		}
+		cw.header.Del("X-Cache")
+		expires := time.Now().Add(c.ttl)
+		if len(fields) > 0 {
+			c.config.store.Set(base, &CachedResponse{
+				Header:  http.Header{"Vary": {strings.Join(fields, ", ")}},
+				Expires: expires,
+			})
+		}
+		c.config.store.Set(varyKey(base, fields, r.Header), &CachedResponse{
+			Status:  cw.status,
+			Header:  cw.header,
+			Body:    cw.body.Bytes(),
+			Expires: expires,
+		})
+	})
+}
+
+// Purge removes every cached response whose URL path matches pattern, using path.Match syntax
+// (e.g. "/users/*"), and returns how many were removed.
+func (c *ResponseCache) Purge(pattern string) int {
+	return c.config.store.Purge(pattern)
+}
+
+// header returns the header of resp, nil if resp is.
+func (resp *CachedResponse) header() http.Header {
+	if resp == nil {
+		return nil
+	}
+	return resp.Header
+}
+
+// requestKey identifies r by its path, query, host and the given headers.
+func requestKey(r *http.Request, headers []string) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(r.URL.Query().Encode())
+	b.WriteByte('\x00')
+	b.WriteString(strings.ToLower(r.Host))
+	for _, h := range headers {
+		b.WriteByte('\x00')
+		b.WriteString(r.Header.Get(h))
+	}
+	return b.String()
+}
+
+// varyKey extends key with the values of the request headers fields.
+func varyKey(key string, fields []string, h http.Header) string {
+	if len(fields) == 0 {
+		return key
+	}
+	var b strings.Builder
+	b.WriteString(key)
+	for _, f := range fields {
+		b.WriteString("\x00")
+		b.WriteString(f)
+		b.WriteByte('=')
+		b.WriteString(strings.Join(h.Values(f), ","))
+	}
+	return b.String()
+}
+
+// varyFields returns the request headers listed by the Vary header of a response, or false if
+// it varies by "*", i.e. the response can not be reused.
+func varyFields(h http.Header) ([]string, bool) {
+	var fields []string
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			f = http.CanonicalHeaderKey(strings.TrimSpace(f))
+			switch {
+			case f == "*":
+				return nil, false
+			case f != "" && !slices.Contains(fields, f):
+				fields = append(fields, f)
+			}
+		}
+	}
+	slices.Sort(fields)
+	return fields, true
+}
+
+// varyMatches reports whether a response to a request with header a, varying as resp says, can
+// be reused for a request with header b.
+func varyMatches(resp, a, b http.Header) bool {
+	fields, ok := varyFields(resp)
+	if !ok {
+		return false
+	}
+	for _, f := range fields {
+		if !slices.Equal(a.Values(f), b.Values(f)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCredentials reports whether r carries credentials a shared response must not be served for.
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+func cacheable(h http.Header) bool {
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return h.Get("Set-Cookie") == "" && !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// cacheWriter copies the response into memory while writing it through.
+type cacheWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	header      http.Header
+	body        bytes.Buffer
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	cw.header = cw.Header().Clone()
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// cacheSweepInterval is how often expired responses are dropped from a MemoryCacheStore.
+const cacheSweepInterval = time.Minute
+
+// MemoryCacheStore is a CacheStore keeping responses in memory. It is safe for concurrent use
+// and drops expired responses periodically.
+type MemoryCacheStore struct {
+	mu        sync.Mutex
+	entries   map[string]*CachedResponse
+	lastSweep time.Time
+}
+
+// NewMemoryCacheStore creates an empty MemoryCacheStore.
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: map[string]*CachedResponse{}}
+}
+
+// Get implements CacheStore.
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(resp.Expires) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return resp, true
+}
+
+// Set implements CacheStore.
+func (s *MemoryCacheStore) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.lastSweep) >= cacheSweepInterval {
+		s.lastSweep = now
+		for k, e := range s.entries {
+			if now.After(e.Expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	s.entries[key] = resp
+}
+
+// Purge implements CacheStore.
+func (s *MemoryCacheStore) Purge(pattern string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key := range s.entries {
+		p, _, _ := strings.Cut(key, "?")
+		if ok, _ := path.Match(pattern, p); ok {
+			delete(s.entries, key)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,212 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	cache := middleware.Cache(time.Minute, middleware.WithCacheKeyHeaders("Accept"))
+	h := cache.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%s %d", r.URL.Path, calls)
+	}))
+
+	serve := func(method, target, accept, cacheControl string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, http.NoBody)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Cache-Control", cacheControl)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		accept       string
+		cacheControl string
+		xCache       string
+		body         string
+	}{
+		{"miss", http.MethodGet, "/users/1", "", "", "MISS", "/users/1 1"},
+		{"hit", http.MethodGet, "/users/1", "", "", "HIT", "/users/1 1"},
+		{"query", http.MethodGet, "/users/1?x=1", "", "", "MISS", "/users/1 2"},
+		{"key_header", http.MethodGet, "/users/1", "text/html", "", "MISS", "/users/1 3"},
+		{"no_cache", http.MethodGet, "/users/1", "", "no-cache", "MISS", "/users/1 4"},
+		{"refreshed", http.MethodGet, "/users/1", "", "", "HIT", "/users/1 4"},
+		{"post", http.MethodPost, "/users/1", "", "", "", "/users/1 5"},
+		{"private", http.MethodGet, "/private", "", "", "MISS", "/private 6"},
+		{"private_again", http.MethodGet, "/private", "", "", "MISS", "/private 7"},
+		{"not_found", http.MethodGet, "/missing", "", "", "MISS", "404 page not found\n"},
+		{"not_found_again", http.MethodGet, "/missing", "", "", "MISS", "404 page not found\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.method, tc.target, tc.accept, tc.cacheControl)
+			if got := rec.Header().Get("X-Cache"); got != tc.xCache {
+				t.Errorf("got X-Cache %q, want %q", got, tc.xCache)
+			}
+			if got := rec.Body.String(); got != tc.body {
+				t.Errorf("got body %q, want %q", got, tc.body)
+			}
+		})
+	}
+
+	t.Run("purge", func(t *testing.T) {
+		serve(http.MethodGet, "/books/1", "", "")
+		if n := cache.Purge("/users/*"); n != 3 {
+			t.Errorf("purged %d responses, want 3", n)
+		}
+		if got := serve(http.MethodGet, "/users/1", "", "").Header().Get("X-Cache"); got != "MISS" {
+			t.Errorf("got X-Cache %q after purge, want MISS", got)
+		}
+		if got := serve(http.MethodGet, "/books/1", "", "").Header().Get("X-Cache"); got != "HIT" {
+			t.Errorf("got X-Cache %q for unpurged path, want HIT", got)
+		}
+	})
+}
+
+func TestCacheExpiry(t *testing.T) {
+	cache := middleware.Cache(10 * time.Millisecond)
+	h := cache.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	xCache := func() string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		return rec.Header().Get("X-Cache")
+	}
+
+	xCache()
+	if got := xCache(); got != "HIT" {
+		t.Fatalf("got X-Cache %q, want HIT", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := xCache(); got != "MISS" {
+		t.Errorf("got X-Cache %q after expiry, want MISS", got)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	newHandler := func(opts ...middleware.CacheOption) http.Handler {
+		return middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", r.Host, r.Header.Get("Authorization"))
+		}), middleware.Cache(time.Minute, opts...).Handler, middleware.Compress(-1, middleware.WithCompressMinLength(0)))
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []middleware.CacheOption
+		host     string
+		encoding string
+		auth     string
+		xCache   string
+		gzipped  bool
+	}{
+		{"miss", nil, "a.example.com", "gzip", "", "MISS", true},
+		{"hit", nil, "a.example.com", "gzip", "", "HIT", true},
+		{"other_host", nil, "b.example.com", "gzip", "", "MISS", true},
+		{"other_encoding", nil, "a.example.com", "", "", "MISS", false},
+		{"other_encoding_hit", nil, "a.example.com", "", "", "HIT", false},
+		{"gzip_still_hit", nil, "a.example.com", "gzip", "", "HIT", true},
+		{"credentials_bypass", nil, "a.example.com", "gzip", "Bearer alice", "", true},
+		{"credentials_bypass_again", nil, "a.example.com", "gzip", "Bearer alice", "", true},
+	}
+
+	h := newHandler()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Host = tc.host
+			req.Header.Set("Accept-Encoding", tc.encoding)
+			req.Header.Set("Authorization", tc.auth)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := rec.Header().Get("X-Cache"); got != tc.xCache {
+				t.Errorf("got X-Cache %q, want %q", got, tc.xCache)
+			}
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tc.gzipped {
+				t.Errorf("got gzipped %v, want %v", got, tc.gzipped)
+			}
+		})
+	}
+
+	t.Run("credentialed_opt_in", func(t *testing.T) {
+		h := newHandler(middleware.WithCacheCredentialedRequests())
+		for _, step := range []struct{ auth, xCache string }{
+			{"Bearer alice", "MISS"},
+			{"Bearer alice", "HIT"},
+			{"Bearer bob", "MISS"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", step.auth)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := rec.Header().Get("X-Cache"); got != step.xCache {
+				t.Errorf("%s: got X-Cache %q, want %q", step.auth, got, step.xCache)
+			}
+		}
+	})
+}
+
+func TestCacheVaryStar(t *testing.T) {
+	h := middleware.Cache(time.Minute).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "*")
+		w.Write([]byte("ok"))
+	}))
+	for range 2 {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		if got := rec.Header().Get("X-Cache"); got != "MISS" {
+			t.Errorf("got X-Cache %q, want MISS", got)
+		}
+	}
+}
+
+func TestCacheVaryInStore(t *testing.T) {
+	store := middleware.NewMemoryCacheStore()
+	cache := middleware.Cache(time.Minute, middleware.WithCacheStore(store))
+	h := cache.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	serve := func(lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/greeting", http.NoBody)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	serve("en")
+	serve("fa")
+	if rec := serve("fa"); rec.Header().Get("X-Cache") != "HIT" || rec.Body.String() != "fa" {
+		t.Errorf("got %s %q, want a HIT for the fa variant", rec.Header().Get("X-Cache"), rec.Body)
+	}
+	// The variants and the entry listing the Vary fields all live in the store.
+	if n := cache.Purge("/greeting"); n != 3 {
+		t.Errorf("purged %d entries, want 3", n)
+	}
+	if got := serve("en").Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("got X-Cache %q after purge, want MISS", got)
+	}
+}
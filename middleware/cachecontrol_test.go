@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestCacheControl(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	override := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", middleware.NoStore)
+	})
+
+	testCases := []struct {
+		name    string
+		handler http.Handler
+		headers map[string]string
+	}{
+		{"directives", middleware.CacheControl(middleware.Public, middleware.MaxAge(time.Hour), middleware.SMaxAge(time.Minute))(ok),
+			map[string]string{"Cache-Control": "public, max-age=3600, s-maxage=60"}},
+		{"override", middleware.CacheControl(middleware.Public)(override),
+			map[string]string{"Cache-Control": "no-store"}},
+		{"no_cache", middleware.NoCache()(ok), map[string]string{
+			"Cache-Control": "no-cache, no-store, must-revalidate, private, max-age=0",
+			"Pragma":        "no-cache",
+			"Expires":       "0",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			for k, v := range tc.headers {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("got %s %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
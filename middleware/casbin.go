@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+// Enforcer is a policy engine deciding whether a request tuple is allowed. *casbin.Enforcer
+// and *casbin.SyncedEnforcer from github.com/casbin/casbin/v2 satisfy it.
+type Enforcer interface {
+	Enforce(rvals ...any) (bool, error)
+}
+
+type casbinConfig struct {
+	request func(r *http.Request, principal string) []any
+}
+
+// CasbinOption configures the Casbin middleware.
+type CasbinOption func(*casbinConfig)
+
+// WithCasbinRequest builds the request tuple passed to the enforcer from the request and its
+// principal, e.g. to add the tenant of a model with domains. It defaults to CasbinRequest.
+func WithCasbinRequest(request func(r *http.Request, principal string) []any) CasbinOption {
+	return func(c *casbinConfig) {
+		c.request = request
+	}
+}
+
+// CasbinRequest returns the default Casbin request tuple: the principal, the path pattern of the
+// matched route, e.g. "/users/{id}", and its method, so HEAD requests served by a GET route are
+// checked as GET. Outside of a route bound by the router, the request path and method stand in
+// for them.
+func CasbinRequest(r *http.Request, principal string) []any {
+	path, method := r.URL.Path, r.Method
+	if info := routectx.From(r.Context()); info != nil {
+		if info.Path != "" {
+			path = info.Path
+		}
+		if info.Method != "" {
+			method = info.Method
+		}
+	}
+	return []any{principal, path, method}
+}
+
+// Casbin is a middleware authorizing requests with Casbin policies, centralizing fine-grained
+// authorization in a policy file instead of per-handler checks. A model matching the default
+// request tuple looks like:
+//
+//	[request_definition]
+//	r = sub, obj, act
+//	[policy_definition]
+//	p = sub, obj, act
+//	[role_definition]
+//	g = _, _
+//	[policy_effect]
+//	e = some(where (p.eft == allow))
+//	[matchers]
+//	m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+//
+// Requests are answered as by Authorize, the enforcer being consulted for authenticated
+// principals only.
+func Casbin(enforcer Enforcer, opts ...CasbinOption) Middleware {
+	c := &casbinConfig{request: CasbinRequest}
+	for _, opt := range opts {
+		opt(c)
+	}
+	authorizer := AuthorizerFunc(func(r *http.Request, principal string) (bool, error) {
+		return enforcer.Enforce(c.request(r, principal)...)
+	})
+	return func(next http.Handler) http.Handler {
+		return authorize(next, authorizer, nil)
+	}
+}
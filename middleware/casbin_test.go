@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// policyEnforcer allows the request tuples it holds, like a Casbin enforcer with a plain
+// sub, obj, act model would.
+type policyEnforcer struct {
+	policies [][]any
+	err      error
+	got      []any
+}
+
+func (e *policyEnforcer) Enforce(rvals ...any) (bool, error) {
+	e.got = rvals
+	if e.err != nil {
+		return false, e.err
+	}
+	return slices.ContainsFunc(e.policies, func(p []any) bool { return slices.Equal(p, rvals) }), nil
+}
+
+func TestCasbin(t *testing.T) {
+	enforcer := &policyEnforcer{policies: [][]any{
+		{"alice", "/users/{id}", http.MethodGet},
+		{"alice", "/users/{id}", http.MethodDelete},
+		{"bob", "/users/{id}", http.MethodGet},
+	}}
+	// authenticate simulates an upstream auth middleware storing the principal.
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(middleware.WithPrincipal(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := func(http.ResponseWriter, *http.Request) {}
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux, rahjoo.Route{
+		"/users/{id}": {
+			http.MethodGet:    rahjoo.NewHandler(ok),
+			http.MethodDelete: rahjoo.NewHandler(ok),
+		},
+	}.SetMiddleware(authenticate, middleware.Casbin(enforcer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		user   string
+		method string
+		status int
+	}{
+		{"allowed", "alice", http.MethodGet, http.StatusOK},
+		{"allowed_delete", "alice", http.MethodDelete, http.StatusOK},
+		{"head_as_get", "bob", http.MethodHead, http.StatusOK},
+		{"denied", "bob", http.MethodDelete, http.StatusForbidden},
+		{"unknown", "eve", http.MethodGet, http.StatusForbidden},
+		{"anonymous", "", http.MethodGet, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/users/42", http.NoBody)
+			req.Header.Set("X-User", tc.user)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestCasbinOptions(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/reports", http.NoBody)
+	req.Header.Set("X-Tenant", "acme")
+	req = req.WithContext(middleware.WithPrincipal(req.Context(), "alice"))
+
+	enforcer := &policyEnforcer{policies: [][]any{{"alice", "acme", "/reports", http.MethodGet}}}
+	h := middleware.Casbin(enforcer, middleware.WithCasbinRequest(func(r *http.Request, principal string) []any {
+		return slices.Insert(middleware.CasbinRequest(r, principal), 1, any(r.Header.Get("X-Tenant")))
+	}))(ok)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status code %d with request %v, want %d", rec.Code, enforcer.got, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.Casbin(&policyEnforcer{err: errors.New("adapter closed")})(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
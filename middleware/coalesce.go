@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+)
+
+type coalesceConfig struct {
+	keyHeaders []string
+}
+
+// CoalesceOption configures the Coalesce middleware.
+type CoalesceOption func(*coalesceConfig)
+
+// WithCoalesceKeyHeaders adds the given request headers to the key identical requests are
+// grouped by, so requests whose responses vary by them (e.g. "Accept", "X-Api-Key") are not
+// collapsed together.
+func WithCoalesceKeyHeaders(headers ...string) CoalesceOption {
+	return func(c *coalesceConfig) {
+		c.keyHeaders = append(c.keyHeaders, headers...)
+	}
+}
+
+// coalescedCall is a handler execution shared by identical requests.
+type coalescedCall struct {
+	done   chan struct{}
+	header http.Header
+	resp   *CachedResponse
+}
+
+// Coalesce is a middleware collapsing concurrent identical GET requests, keyed like Cache by
+// host, path, query, the Authorization and Cookie headers and the headers given with
+// WithCoalesceKeyHeaders, into a single handler execution. The first request runs the handler;
+// the others wait for it and receive a copy of its buffered response, protecting expensive
+// endpoints from thundering herds. Unlike Cache, nothing is kept once the response is served.
+//
+// If the handler panics, or its response varies by a request header (see Vary) the waiting
+// request does not share, waiting requests run it themselves.
+func Coalesce(opts ...CoalesceOption) Middleware {
+	c := coalesceConfig{keyHeaders: []string{"Authorization", "Cookie"}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var (
+		mu    sync.Mutex
+		calls = map[string]*coalescedCall{}
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := requestKey(r, c.keyHeaders)
+			mu.Lock()
+			if call, ok := calls[key]; ok {
+				mu.Unlock()
+				select {
+				case <-call.done:
+				case <-r.Context().Done():
+					return
+				}
+				if call.resp == nil || !varyMatches(call.resp.Header, call.header, r.Header) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				h := w.Header()
+				for k, v := range call.resp.Header {
+					h[k] = slices.Clone(v)
+				}
+				w.WriteHeader(call.resp.Status)
+				w.Write(call.resp.Body)
+				return
+			}
+			call := &coalescedCall{done: make(chan struct{}), header: r.Header}
+			calls[key] = call
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(call.done)
+			}()
+
+			cw := &cacheWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			if !cw.wroteHeader {
+				cw.header = w.Header().Clone()
+			}
+			call.resp = &CachedResponse{Status: cw.status, Header: cw.header, Body: cw.body.Bytes()}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestCoalesce(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := middleware.Coalesce(middleware.WithCoalesceKeyHeaders("Accept"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		<-release
+		w.Header().Set("X-Call", fmt.Sprint(n))
+		fmt.Fprintf(w, "%s %s %s %s", r.Host, r.URL.RequestURI(), r.Header.Get("Accept"), r.Header.Get("Authorization"))
+	}))
+
+	testCases := []struct {
+		name   string
+		method string
+		host   string
+		target string
+		accept string
+		auth   string
+		want   string
+	}{
+		{"a", http.MethodGet, "example.com", "/report?year=2024", "text/csv", "", "example.com /report?year=2024 text/csv "},
+		{"a_duplicate", http.MethodGet, "example.com", "/report?year=2024", "text/csv", "", "example.com /report?year=2024 text/csv "},
+		{"a_equivalent_query", http.MethodGet, "example.com", "/report?year=2024&", "text/csv", "", "example.com /report?year=2024 text/csv "},
+		{"other_accept", http.MethodGet, "example.com", "/report?year=2024", "application/json", "", "example.com /report?year=2024 application/json "},
+		{"other_query", http.MethodGet, "example.com", "/report?year=2023", "text/csv", "", "example.com /report?year=2023 text/csv "},
+		{"other_host", http.MethodGet, "example.org", "/report?year=2024", "text/csv", "", "example.org /report?year=2024 text/csv "},
+		{"alice", http.MethodGet, "example.com", "/report?year=2024", "text/csv", "Bearer alice", "example.com /report?year=2024 text/csv Bearer alice"},
+		{"bob", http.MethodGet, "example.com", "/report?year=2024", "text/csv", "Bearer bob", "example.com /report?year=2024 text/csv Bearer bob"},
+		{"post", http.MethodPost, "example.com", "/report?year=2024", "text/csv", "", "example.com /report?year=2024 text/csv "},
+	}
+
+	recs := make([]*httptest.ResponseRecorder, len(testCases))
+	var wg sync.WaitGroup
+	for i, tc := range testCases {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(tc.method, tc.target, http.NoBody)
+			req.Host = tc.host
+			req.Header.Set("Accept", tc.accept)
+			req.Header.Set("Authorization", tc.auth)
+			h.ServeHTTP(recs[i], req)
+		}()
+	}
+	// Wait for the distinct requests to reach the handler and the duplicates to queue behind them.
+	for calls.Load() < 7 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 7 {
+		t.Errorf("handler called %d times, want 7", got)
+	}
+	for i, tc := range testCases {
+		if got := recs[i].Body.String(); got != tc.want {
+			t.Errorf("%s: got body %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if recs[0].Header().Get("X-Call") != recs[1].Header().Get("X-Call") || recs[0].Header().Get("X-Call") != recs[2].Header().Get("X-Call") {
+		t.Errorf("duplicates were not coalesced: X-Call %q, %q, %q",
+			recs[0].Header().Get("X-Call"), recs[1].Header().Get("X-Call"), recs[2].Header().Get("X-Call"))
+	}
+}
+
+func TestCoalesceVary(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := middleware.Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Vary", "Accept-Encoding")
+		fmt.Fprint(w, r.Header.Get("Accept-Encoding"))
+	}))
+
+	encodings := []string{"gzip", "gzip", "identity"}
+	recs := make([]*httptest.ResponseRecorder, len(encodings))
+	var wg sync.WaitGroup
+	for i, enc := range encodings {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept-Encoding", enc)
+			h.ServeHTTP(recs[i], req)
+		}()
+	}
+	for calls.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, enc := range encodings {
+		if got := recs[i].Body.String(); got != enc {
+			t.Errorf("request %d: got body %q, want %q", i, got, enc)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultCompressMinLength is the response size below which compressing is not worth it.
+const defaultCompressMinLength = 1024
+
+// defaultCompressExcludedTypes lists media types, or type prefixes ending with a slash,
+// that are already compressed.
+var defaultCompressExcludedTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-brotli", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf", "application/octet-stream",
+}
+
+// Encoder compresses a response body with a content coding. *gzip.Writer and *zlib.Writer
+// implement it, as do the writers of popular brotli and zstd packages.
+type Encoder interface {
+	io.WriteCloser
+	// Flush writes any pending data to the underlying writer.
+	Flush() error
+	// Reset discards the encoder state and makes it write to w, so encoders can be pooled.
+	Reset(w io.Writer)
+}
+
+// encoder is a content coding offered by the Compress middleware along with its encoder pool.
+type encoder struct {
+	coding string
+	pool   *sync.Pool
+}
+
+type compressConfig struct {
+	level         int
+	minLength     int
+	excludedTypes []string
+	// encoders are ordered by server preference.
+	encoders []encoder
+	// custom is the number of encoders added with WithEncoder, at the start of encoders.
+	custom int
+}
+
+// CompressOption configures the Compress middleware.
+type CompressOption func(*compressConfig)
+
+// WithCompressMinLength sets the response size, in bytes, below which responses are sent
+// uncompressed. It defaults to 1024.
+func WithCompressMinLength(n int) CompressOption {
+	return func(c *compressConfig) {
+		c.minLength = n
+	}
+}
+
+// WithCompressExcludedTypes replaces the media types that are never compressed because they
+// already are. A type ending with a slash (e.g. "image/") excludes the whole top-level type.
+func WithCompressExcludedTypes(types ...string) CompressOption {
+	return func(c *compressConfig) {
+		c.excludedTypes = types
+	}
+}
+
+// WithEncoder offers an additional content coding, such as "br" or "zstd", created by newEncoder.
+// Encoders are pooled and reused across responses. Codings added with WithEncoder are preferred
+// over the built-in gzip and deflate ones, in the order they are added, when the client accepts
+// several with the same quality. Adding a coding again replaces its encoder.
+func WithEncoder(coding string, newEncoder func() Encoder) CompressOption {
+	return func(c *compressConfig) {
+		c.encoders = slices.DeleteFunc(c.encoders, func(e encoder) bool { return e.coding == coding })
+		e := encoder{coding: coding, pool: &sync.Pool{New: func() any { return newEncoder() }}}
+		c.encoders = slices.Insert(c.encoders, c.custom, e)
+		c.custom++
+	}
+}
+
+// Compress is a middleware compressing responses with the best content coding accepted by the
+// client: gzip or deflate at the given level (gzip.DefaultCompression, gzip.BestSpeed ...
+// gzip.BestCompression), or any coding added with WithEncoder. Responses that are small, already
+// encoded or of an already compressed media type (images, archives...) are sent as is.
+// Every response carries Vary: Accept-Encoding. It panics if level is invalid.
+func Compress(level int, opts ...CompressOption) Middleware {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Sprintf("middleware: %v", err))
+	}
+	c := &compressConfig{level: level, minLength: defaultCompressMinLength, excludedTypes: defaultCompressExcludedTypes}
+	c.encoders = []encoder{
+		{coding: "gzip", pool: &sync.Pool{New: func() any {
+			gz, _ := gzip.NewWriterLevel(io.Discard, level)
+			return gz
+		}}},
+		{coding: "deflate", pool: &sync.Pool{New: func() any {
+			zw, _ := zlib.NewWriterLevel(io.Discard, level)
+			return zw
+		}}},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc, ok := c.negotiate(r.Header.Get("Accept-Encoding"))
+			if r.Method == http.MethodHead || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := compressWriterPool.Get().(*compressWriter)
+			cw.ResponseWriter, cw.config, cw.encoder, cw.status = w, c, enc, http.StatusOK
+			// On panic, nothing buffered is sent, so an outer Recovery can still answer with an
+			// error status.
+			completed := false
+			defer func() { cw.release(completed) }()
+			next.ServeHTTP(cw, r)
+			completed = true
+		})
+	}
+}
+
+// negotiate returns the offered encoder with the highest quality in the Accept-Encoding header
+// value, ties being broken by server preference.
+func (c *compressConfig) negotiate(header string) (encoder, bool) {
+	var best encoder
+	bestQ := 0.0
+	for _, e := range c.encoders {
+		if q := encodingQuality(header, e.coding); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// encodingQuality returns the q-value the Accept-Encoding header value gives to coding,
+// falling back to the one of "*".
+func encodingQuality(header, coding string) float64 {
+	q, wildcard := -1.0, 0.0
+	for header != "" {
+		var part string
+		part, header, _ = strings.Cut(header, ",")
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				quality = f
+			}
+		}
+		switch {
+		case strings.EqualFold(name, coding):
+			q = quality
+		case name == "*":
+			wildcard = quality
+		}
+	}
+	if q < 0 {
+		return wildcard
+	}
+	return q
+}
+
+// compressWriterPool recycles compressWriters along with their buffers.
+var compressWriterPool = sync.Pool{New: func() any { return new(compressWriter) }}
+
+// compressWriter buffers the start of a response until it can decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	config  *compressConfig
+	encoder encoder
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         Encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	cw.wroteHeader = true
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.config.minLength {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far, deciding on compression if not done yet.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(len(cw.buf) > 0)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over to the handler, e.g. for a WebSocket upgrade, leaving the
+// response to it.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(cw.ResponseWriter).Hijack()
+	if err == nil {
+		cw.decided = true
+		cw.buf = cw.buf[:0]
+		if cw.enc != nil {
+			cw.encoder.pool.Put(cw.enc)
+			cw.enc = nil
+		}
+	}
+	return conn, buf, err
+}
+
+func (cw *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := cw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide writes the header, compressing the response if eligible is true and its headers allow
+// it, then sends the buffered body.
+func (cw *compressWriter) decide(eligible bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if eligible && h.Get("Content-Encoding") == "" && !cw.excluded(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoder.coding)
+		cw.enc = cw.encoder.pool.Get().(Encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.Write(buf)
+	// Keep the buffer for the next response using cw.
+	cw.buf = buf[:0]
+	return err
+}
+
+func (cw *compressWriter) excluded(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range cw.config.excludedTypes {
+		if mt == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// close sends a response too small to be compressed and finishes the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.encoder.pool.Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+// release closes cw if the handler completed, or drops what it buffered otherwise, and returns
+// it to the pool, keeping its buffer unless it grew too large.
+func (cw *compressWriter) release(completed bool) {
+	if completed {
+		cw.close()
+	} else if cw.enc != nil {
+		cw.encoder.pool.Put(cw.enc)
+	}
+	buf := cw.buf[:0]
+	if cap(buf) > 64<<10 {
+		buf = nil
+	}
+	*cw = compressWriter{buf: buf}
+	compressWriterPool.Put(cw)
+}
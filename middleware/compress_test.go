@@ -0,0 +1,183 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("hello rahjoo ", 200)
+
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		status         int
+		gzipped        bool
+	}{
+		{"gzip", "gzip, deflate", "text/plain", large, http.StatusOK, true},
+		{"detected_type", "gzip", "", large, http.StatusOK, true},
+		{"not_accepted", "br", "text/plain", large, http.StatusOK, false},
+		{"rejected", "gzip;q=0, deflate;q=0, *", "text/plain", large, http.StatusOK, false},
+		{"wildcard", "*", "text/plain", large, http.StatusOK, true},
+		{"small", "gzip", "text/plain", "hello", http.StatusOK, false},
+		{"compressed_type", "gzip", "image/png", large, http.StatusOK, false},
+		{"no_content", "gzip", "", "", http.StatusNoContent, false},
+		{"error_status", "gzip", "text/plain", large, http.StatusInternalServerError, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := middleware.Compress(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				w.WriteHeader(tc.status)
+				// write in chunks to exercise buffering.
+				for body := tc.body; body != ""; {
+					n := min(100, len(body))
+					io.WriteString(w, body[:n])
+					body = body[n:]
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("got Vary %q, want %q", got, "Accept-Encoding")
+			}
+
+			body := rec.Body.String()
+			if gzipped := rec.Header().Get("Content-Encoding") == "gzip"; gzipped != tc.gzipped {
+				t.Fatalf("got gzipped %t, want %t", gzipped, tc.gzipped)
+			}
+			if tc.gzipped {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(b)
+			}
+			if body != tc.body {
+				t.Errorf("got body of length %d, want %d", len(body), len(tc.body))
+			}
+		})
+	}
+}
+
+// upperEncoder is a toy Encoder upper-casing its input, standing in for brotli or zstd.
+type upperEncoder struct{ w io.Writer }
+
+func (e *upperEncoder) Write(b []byte) (int, error) {
+	return e.w.Write([]byte(strings.ToUpper(string(b))))
+}
+func (e *upperEncoder) Close() error      { return nil }
+func (e *upperEncoder) Flush() error      { return nil }
+func (e *upperEncoder) Reset(w io.Writer) { e.w = w }
+
+func TestCompressNegotiation(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	h := middleware.Compress(gzip.BestSpeed,
+		middleware.WithEncoder("br", func() middleware.Encoder { return &upperEncoder{} }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, body)
+	}))
+
+	testCases := []struct {
+		acceptEncoding string
+		coding         string
+	}{
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip;q=0.2, deflate;q=0.4", "deflate"},
+		{"zstd", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tc.coding {
+				t.Fatalf("got Content-Encoding %q, want %q", got, tc.coding)
+			}
+			if tc.coding == "br" && rec.Body.String() != strings.ToUpper(body) {
+				t.Error("body was not encoded by the custom encoder")
+			}
+		})
+	}
+}
+
+func TestCompressPanic(t *testing.T) {
+	h := middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "partial")
+		panic("boom")
+	}), middleware.Recovery(log.New(io.Discard, "", 0)), middleware.Compress(gzip.DefaultCompression))
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(rec.Body.String(), "partial") {
+		t.Errorf("got body %q, want the buffered body dropped", rec.Body.String())
+	}
+}
+
+func TestCompressHijack(t *testing.T) {
+	srv := httptest.NewServer(middleware.Compress(-1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("expected the compressing writer to be an http.Hijacker")
+			http.Error(w, "not a hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("got status code %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+type contentTypeConfig struct {
+	bodyMethodsOnly bool
+	onError         func(w http.ResponseWriter, r *http.Request, status int, detail string)
+}
+
+// ContentTypeOption configures the EnforceContentType middleware.
+type ContentTypeOption func(*contentTypeConfig)
+
+// WithContentTypeBodyMethodsOnly enforces the Content-Type only for POST, PUT and PATCH
+// requests, letting requests of methods without body through, so the middleware can be
+// applied to a whole group of routes.
+func WithContentTypeBodyMethodsOnly() ContentTypeOption {
+	return func(c *contentTypeConfig) {
+		c.bodyMethodsOnly = true
+	}
+}
+
+// WithContentTypeErrorHandler sets the function writing the response to rejected requests,
+// instead of the default problem details. status is 400 Bad Request or 415 Unsupported Media Type.
+func WithContentTypeErrorHandler(onError func(w http.ResponseWriter, r *http.Request, status int, detail string)) ContentTypeOption {
+	return func(c *contentTypeConfig) {
+		c.onError = onError
+	}
+}
+
+// EnforceContentType is a middleware that ensures the Content-Type header of incoming requests
+// is one of the given media types (e.g. "application/json", "application/xml"). Parameters such
+// as charset are ignored, and a type of "text/*" allows every text subtype.
+//
+// Requests without a valid Content-Type get 400 Bad Request, requests with another media type
+// 415 Unsupported Media Type, both as problem details.
+func EnforceContentType(types ...string) Middleware {
+	return EnforceContentTypeWithOptions(types)
+}
+
+// EnforceContentTypeWithOptions is like EnforceContentType, configured by opts.
+func EnforceContentTypeWithOptions(types []string, opts ...ContentTypeOption) Middleware {
+	c := contentTypeConfig{
+		onError: func(w http.ResponseWriter, _ *http.Request, status int, detail string) {
+			problem.Write(w, status, problem.WithDetail(detail))
+		},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	allowed := make([]string, len(types))
+	for i, t := range types {
+		if mt, _, err := mime.ParseMediaType(t); err == nil {
+			t = mt
+		}
+		allowed[i] = strings.ToLower(t)
+	}
+	mismatch := "Content-Type header must be " + strings.Join(types, " or ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.bodyMethodsOnly && r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			contentType := r.Header.Get("Content-Type")
+			if contentType == "" {
+				c.onError(w, r, http.StatusBadRequest, "Content-Type header is not set")
+				return
+			}
+			mt, _, err := mime.ParseMediaType(contentType)
+			if err != nil {
+				c.onError(w, r, http.StatusBadRequest, "Content-Type header is invalid")
+				return
+			}
+			if !mediaTypeAllowed(allowed, mt) {
+				c.onError(w, r, http.StatusUnsupportedMediaType, mismatch)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// mediaTypeAllowed reports whether mt matches one of the allowed types or "type/*" ranges.
+func mediaTypeAllowed(allowed []string, mt string) bool {
+	typ, _, _ := strings.Cut(mt, "/")
+	for _, a := range allowed {
+		if a == mt || a == typ+"/*" {
+			return true
+		}
+	}
+	return false
+}
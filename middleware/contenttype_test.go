@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestEnforceContentType(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	plain := middleware.WithContentTypeErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, detail string) {
+		http.Error(w, detail, status)
+	})
+
+	testCases := []struct {
+		name        string
+		mw          middleware.Middleware
+		method      string
+		contentType string
+		status      int
+		body        string
+	}{
+		{"json", middleware.EnforceContentType("application/json", "application/xml"), http.MethodPost, "application/json", http.StatusOK, ""},
+		{"xml_with_charset", middleware.EnforceContentType("application/json", "application/xml"), http.MethodPost, "application/xml; charset=utf-8", http.StatusOK, ""},
+		{"case_insensitive", middleware.EnforceContentType("application/json"), http.MethodPost, "Application/JSON", http.StatusOK, ""},
+		{"wildcard", middleware.EnforceContentType("text/*"), http.MethodPost, "text/csv", http.StatusOK, ""},
+		{"missing", middleware.EnforceContentType("application/json"), http.MethodPost, "", http.StatusBadRequest, ""},
+		{"invalid", middleware.EnforceContentType("application/json"), http.MethodPost, "application/json; =", http.StatusBadRequest, ""},
+		{"unsupported", middleware.EnforceContentType("application/json", "application/xml"), http.MethodPost, "text/plain", http.StatusUnsupportedMediaType, ""},
+		{"get_enforced_by_default", middleware.EnforceContentType("application/json"), http.MethodGet, "", http.StatusBadRequest, ""},
+		{"get_skipped", middleware.EnforceContentTypeWithOptions([]string{"application/json"}, middleware.WithContentTypeBodyMethodsOnly()), http.MethodGet, "", http.StatusOK, ""},
+		{"patch_enforced", middleware.EnforceContentTypeWithOptions([]string{"application/json"}, middleware.WithContentTypeBodyMethodsOnly()), http.MethodPatch, "text/plain", http.StatusUnsupportedMediaType, ""},
+		{"custom_error", middleware.EnforceContentTypeWithOptions([]string{"application/json", "application/xml"}, plain), http.MethodPost, "text/plain", http.StatusUnsupportedMediaType,
+			"Content-Type header must be application/json or application/xml\n"},
+		{"enforce_json_wrapper", middleware.EnforceJSON, http.MethodPost, "application/xml", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", http.NoBody)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			tc.mw(ok).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body, tc.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package cookie reads and writes tamper-proof cookies.
+//
+// A Codec either signs cookie values with HMAC-SHA256, keeping them readable by the client, or
+// encrypts them with AES-GCM. Values are bound to the cookie name and to the time they were
+// written, so a value can neither be moved to another cookie nor used past the codec's max age.
+//
+// Keys are rotated by passing the new key first: values are always written with the first key
+// and read with any of them, so cookies written before the rotation stay valid.
+package cookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned for cookie values that were tampered with or written with unknown keys.
+	ErrInvalid = errors.New("cookie: invalid value")
+	// ErrExpired is returned for cookie values older than the codec's max age.
+	ErrExpired = errors.New("cookie: value expired")
+	// ErrNoKeys is returned when a codec is created without keys.
+	ErrNoKeys = errors.New("cookie: no keys")
+)
+
+const timestampLen = 8
+
+// Codec encodes and decodes cookie values.
+type Codec struct {
+	signers []signer
+	maxAge  time.Duration
+}
+
+type signer interface {
+	seal(name string, payload []byte) ([]byte, error)
+	open(name string, sealed []byte) ([]byte, error)
+}
+
+// Option configures a Codec.
+type Option func(*Codec)
+
+// WithMaxAge rejects values written more than d ago with ErrExpired. Zero, the default, disables
+// the check; the cookie's own MaxAge or Expires still limits how long the browser keeps it.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Codec) {
+		c.maxAge = d
+	}
+}
+
+// NewSigned returns a Codec signing values with HMAC-SHA256. Values are written with the first
+// key and verified with any of them.
+func NewSigned(keys [][]byte, opts ...Option) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	c := &Codec{}
+	for _, key := range keys {
+		c.signers = append(c.signers, hmacSigner(key))
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// NewEncrypted returns a Codec encrypting values with AES-GCM. Keys must be 16, 24 or 32 bytes
+// long, selecting AES-128, AES-192 or AES-256. Values are written with the first key and
+// decrypted with any of them.
+func NewEncrypted(keys [][]byte, opts ...Option) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	c := &Codec{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.signers = append(c.signers, gcmSigner{aead})
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Encode returns value protected for the cookie named name.
+func (c *Codec) Encode(name, value string) (string, error) {
+	payload := binary.BigEndian.AppendUint64(make([]byte, 0, timestampLen+len(value)), uint64(time.Now().Unix()))
+	sealed, err := c.signers[0].seal(name, append(payload, value...))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode returns the value encoded by Encode for the cookie named name.
+func (c *Codec) Decode(name, encoded string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	for _, s := range c.signers {
+		payload, err := s.open(name, sealed)
+		if err != nil || len(payload) < timestampLen {
+			continue
+		}
+		written := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+		if c.maxAge > 0 && time.Since(written) > c.maxAge {
+			return "", ErrExpired
+		}
+		return string(payload[timestampLen:]), nil
+	}
+	return "", ErrInvalid
+}
+
+// Set encodes the value of cookie and adds it to w.
+func (c *Codec) Set(w http.ResponseWriter, cookie *http.Cookie) error {
+	encoded, err := c.Encode(cookie.Name, cookie.Value)
+	if err != nil {
+		return err
+	}
+	protected := *cookie
+	protected.Value = encoded
+	http.SetCookie(w, &protected)
+	return nil
+}
+
+// Get returns the decoded value of the cookie named name of r. It returns http.ErrNoCookie if r
+// has no such cookie.
+func (c *Codec) Get(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.Decode(name, cookie.Value)
+}
+
+type hmacSigner []byte
+
+func (key hmacSigner) mac(name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (key hmacSigner) seal(name string, payload []byte) ([]byte, error) {
+	return append(payload, key.mac(name, payload)...), nil
+}
+
+func (key hmacSigner) open(name string, sealed []byte) ([]byte, error) {
+	if len(sealed) < sha256.Size {
+		return nil, ErrInvalid
+	}
+	payload, sum := sealed[:len(sealed)-sha256.Size], sealed[len(sealed)-sha256.Size:]
+	if !hmac.Equal(sum, key.mac(name, payload)) {
+		return nil, ErrInvalid
+	}
+	return payload, nil
+}
+
+type gcmSigner struct {
+	aead cipher.AEAD
+}
+
+func (g gcmSigner) seal(name string, payload []byte) ([]byte, error) {
+	nonce := make([]byte, g.aead.NonceSize(), g.aead.NonceSize()+len(payload)+g.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return g.aead.Seal(nonce, nonce, payload, []byte(name)), nil
+}
+
+func (g gcmSigner) open(name string, sealed []byte) ([]byte, error) {
+	if len(sealed) < g.aead.NonceSize() {
+		return nil, ErrInvalid
+	}
+	nonce, ciphertext := sealed[:g.aead.NonceSize()], sealed[g.aead.NonceSize():]
+	return g.aead.Open(nil, nonce, ciphertext, []byte(name))
+}
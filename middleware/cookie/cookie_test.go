@@ -0,0 +1,98 @@
+package cookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware/cookie"
+)
+
+func TestCodec(t *testing.T) {
+	oldKey := []byte(strings.Repeat("o", 32))
+	newKey := []byte(strings.Repeat("n", 32))
+
+	for _, tc := range []struct {
+		name string
+		new  func(keys [][]byte, opts ...cookie.Option) (*cookie.Codec, error)
+	}{
+		{"signed", cookie.NewSigned},
+		{"encrypted", cookie.NewEncrypted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oldCodec, err := tc.new([][]byte{oldKey})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rotated, err := tc.new([][]byte{newKey, oldKey})
+			if err != nil {
+				t.Fatal(err)
+			}
+			newOnly, err := tc.new([][]byte{newKey})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			if err := oldCodec.Set(rec, &http.Cookie{Name: "session", Value: "user=42", HttpOnly: true}); err != nil {
+				t.Fatal(err)
+			}
+			set := rec.Result().Cookies()[0]
+			if set.Value == "user=42" || !set.HttpOnly {
+				t.Fatalf("got cookie %v", set)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.AddCookie(set)
+			if got, err := rotated.Get(req, "session"); err != nil || got != "user=42" {
+				t.Errorf("rotated codec: got %q, %v", got, err)
+			}
+			if _, err := newOnly.Get(req, "session"); !errors.Is(err, cookie.ErrInvalid) {
+				t.Errorf("unknown key: got error %v, want ErrInvalid", err)
+			}
+			if _, err := rotated.Decode("other", set.Value); !errors.Is(err, cookie.ErrInvalid) {
+				t.Errorf("other cookie name: got error %v, want ErrInvalid", err)
+			}
+			tampered := []byte(set.Value)
+			tampered[len(tampered)/2] ^= 1
+			if _, err := rotated.Decode("session", string(tampered)); !errors.Is(err, cookie.ErrInvalid) {
+				t.Errorf("tampered value: got error %v, want ErrInvalid", err)
+			}
+			if _, err := rotated.Get(httptest.NewRequest(http.MethodGet, "/", http.NoBody), "session"); !errors.Is(err, http.ErrNoCookie) {
+				t.Errorf("missing cookie: got error %v, want http.ErrNoCookie", err)
+			}
+		})
+	}
+}
+
+func TestCodecMaxAge(t *testing.T) {
+	codec, err := cookie.NewSigned([][]byte{[]byte("key")}, cookie.WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := codec.Encode("flash", "saved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codec.Decode("flash", encoded); err != nil {
+		t.Errorf("fresh value: got error %v", err)
+	}
+
+	codec, _ = cookie.NewSigned([][]byte{[]byte("key")}, cookie.WithMaxAge(time.Nanosecond))
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := codec.Decode("flash", encoded); !errors.Is(err, cookie.ErrExpired) {
+		t.Errorf("got error %v, want ErrExpired", err)
+	}
+}
+
+func TestNewEncryptedInvalidKey(t *testing.T) {
+	if _, err := cookie.NewEncrypted([][]byte{[]byte("short")}); err == nil {
+		t.Error("expected error for an invalid AES key length")
+	}
+	if _, err := cookie.NewSigned(nil); !errors.Is(err, cookie.ErrNoKeys) {
+		t.Errorf("got error %v, want ErrNoKeys", err)
+	}
+}
@@ -1,9 +1,12 @@
 package cors
 
 import (
+	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var DefaultOriginAllowList = []string{"*"}
@@ -24,13 +27,25 @@ type corsHandler struct {
 	allowedMethods,
 	allowedOrigins,
 	allowedHeaders []string
+	credentials bool
+	maxAge      time.Duration
+	exposed     string
+	origins     originMatcher
+	headers     headerMatcher
+	logger      *slog.Logger
+	originFunc  func(r *http.Request, origin string) bool
+
+	preflightPassthrough bool
+	preflightStatus      int
+	preflightDenied      http.Handler
 }
 
 func newCorsHandler() *corsHandler {
 	return &corsHandler{
-		allowedMethods: DefaultMethodAllowList,
-		allowedOrigins: DefaultOriginAllowList,
-		allowedHeaders: DefaultHeadersAllowList,
+		allowedMethods:  DefaultMethodAllowList,
+		allowedOrigins:  DefaultOriginAllowList,
+		allowedHeaders:  DefaultHeadersAllowList,
+		preflightStatus: http.StatusNoContent,
 	}
 }
 
@@ -40,61 +55,205 @@ func isPreflight(r *http.Request) bool {
 		r.Header.Get("Access-Control-Request-Method") != ""
 }
 
-func (c *corsHandler) hasOrigin(origin string) bool {
-	return slices.Contains(c.allowedOrigins, "*") || slices.Contains(c.allowedOrigins, origin)
+func (c *corsHandler) hasOrigin(r *http.Request, origin string) bool {
+	if c.originFunc != nil {
+		return c.originFunc(r, origin)
+	}
+	return c.origins.match(origin)
 }
 
 func (c *corsHandler) hasMethod(method string) bool {
 	return slices.Contains(c.allowedMethods, method) || method == http.MethodOptions
 }
 
+// allowOrigin sets the Access-Control-Allow-Origin header. The request origin is echoed rather
+// than "*", which browsers reject for credentialed requests.
+func (c *corsHandler) allowOrigin(h http.Header, origin string) {
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// logRejection logs why the request was denied CORS access, if a logger is set.
+func (c *corsHandler) logRejection(r *http.Request, reason string, attrs ...slog.Attr) {
+	if c.logger == nil {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("reason", reason),
+		slog.String("origin", r.Header.Get("Origin")),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+	}, attrs...)
+	c.logger.LogAttrs(r.Context(), slog.LevelDebug, "cors request rejected", attrs...)
+}
+
 func (c *corsHandler) handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Origin")
 
 		origin := r.Header.Get("Origin")
 		if isPreflight(r) {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
 			requestedMethod := r.Header.Get("Access-Control-Request-Method")
-			if c.hasMethod(requestedMethod) && c.hasOrigin(origin) {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
-				w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.allowedHeaders, ", "))
-				w.WriteHeader(http.StatusNoContent)
+			requestedHeaders := r.Header.Values("Access-Control-Request-Headers")
+			allowedHeaders, headersOK := c.headers.match(requestedHeaders)
+			var reason string
+			switch {
+			case !c.hasOrigin(r, origin):
+				reason = "origin not allowed"
+			case !c.hasMethod(requestedMethod):
+				reason = "method not allowed"
+			case !headersOK:
+				reason = "header not allowed"
+			}
+			if reason != "" {
+				c.logRejection(r, reason,
+					slog.String("requested_method", requestedMethod),
+					slog.String("requested_headers", strings.Join(requestedHeaders, ", ")),
+				)
+				switch {
+				case c.preflightDenied != nil:
+					c.preflightDenied.ServeHTTP(w, r)
+				case c.preflightPassthrough:
+					next.ServeHTTP(w, r)
+				}
+				return
+			}
+			c.allowOrigin(w.Header(), origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
+			if allowedHeaders != "" {
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+			}
+			if c.maxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(int64(c.maxAge/time.Second), 10))
+			}
+			if c.preflightPassthrough {
+				next.ServeHTTP(w, r)
+				return
 			}
+			w.WriteHeader(c.preflightStatus)
 			return
 		}
 
-		if c.hasOrigin(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+		if origin != "" {
+			if c.hasOrigin(r, origin) {
+				c.allowOrigin(w.Header(), origin)
+				if c.exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+				}
+			} else {
+				c.logRejection(r, "origin not allowed")
+			}
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func CORSHandler(opts ...optionCorsFunc) func(next http.Handler) http.Handler {
+func CORSHandler(opts ...Option) func(next http.Handler) http.Handler {
 	cors := newCorsHandler()
 	for _, opt := range opts {
 		opt(cors)
 	}
+	cors.origins = compileOrigins(cors.allowedOrigins)
+	cors.headers = compileHeaders(cors.allowedHeaders)
 	return cors.handler
 }
 
-type optionCorsFunc func(*corsHandler)
+// Option configures the CORS handler.
+type Option func(*corsHandler)
 
-func WithMethods(methods []string) optionCorsFunc {
+func WithMethods(methods []string) Option {
 	return func(ch *corsHandler) {
 		ch.allowedMethods = methods
 	}
 }
 
-func WithHeaders(headers []string) optionCorsFunc {
+// WithHeaders sets the request headers browsers may send, matched case-insensitively against
+// Access-Control-Request-Headers. Preflights requesting other headers are denied, and "*"
+// allows any header by reflecting the requested ones.
+func WithHeaders(headers []string) Option {
 	return func(ch *corsHandler) {
 		ch.allowedHeaders = headers
 	}
 }
 
-func WithOrigins(origins []string) optionCorsFunc {
+// WithOrigins sets the allowed origins. Besides exact origins and "*" for any, patterns may
+// use a wildcard for subdomains, as in "https://*.example.com" which does not match
+// "https://example.com" itself, or for the port, as in "http://localhost:*".
+// CORSHandler panics on a pattern with a wildcard elsewhere.
+func WithOrigins(origins []string) Option {
 	return func(ch *corsHandler) {
 		ch.allowedOrigins = origins
 	}
 }
+
+// WithCredentials sets whether browsers may send cookies and HTTP authentication with
+// cross-origin requests, via the Access-Control-Allow-Credentials header. As the "*" wildcard
+// is not allowed with credentials, the origin of the request is always echoed instead, so a
+// wildcard allow list with credentials accepts every origin with credentials; prefer an
+// explicit list of origins.
+func WithCredentials(allow bool) Option {
+	return func(ch *corsHandler) {
+		ch.credentials = allow
+	}
+}
+
+// WithMaxAge sets how long browsers may cache preflight responses, via the
+// Access-Control-Max-Age header, in whole seconds. Browsers cap it, Chromium at two hours.
+func WithMaxAge(d time.Duration) Option {
+	return func(ch *corsHandler) {
+		ch.maxAge = d
+	}
+}
+
+// WithExposedHeaders sets the response headers, beyond the CORS-safelisted ones, that browser
+// scripts may read, via the Access-Control-Expose-Headers header (e.g. X-Request-Id or Link).
+func WithExposedHeaders(headers []string) Option {
+	return func(ch *corsHandler) {
+		ch.exposed = strings.Join(headers, ", ")
+	}
+}
+
+// WithOriginFunc sets the function deciding at request time whether an origin is allowed, e.g.
+// against a database or the configuration of a tenant, replacing the allowed origins.
+func WithOriginFunc(allow func(r *http.Request, origin string) bool) Option {
+	return func(ch *corsHandler) {
+		ch.originFunc = allow
+	}
+}
+
+// WithPreflightPassthrough passes preflight requests on to the next handler once the CORS
+// headers are set, instead of answering them, for handlers serving OPTIONS themselves.
+func WithPreflightPassthrough() Option {
+	return func(ch *corsHandler) {
+		ch.preflightPassthrough = true
+	}
+}
+
+// WithPreflightStatus sets the status code of successful preflight responses, 204 No Content by
+// default. Some legacy browsers require 200 OK.
+func WithPreflightStatus(code int) Option {
+	return func(ch *corsHandler) {
+		ch.preflightStatus = code
+	}
+}
+
+// WithPreflightDeniedHandler sets the handler serving preflight requests of disallowed origins
+// or methods. By default they get an empty response without CORS headers, which browsers treat
+// as a denial.
+func WithPreflightDeniedHandler(h http.Handler) Option {
+	return func(ch *corsHandler) {
+		ch.preflightDenied = h
+	}
+}
+
+// WithLogger logs, at debug level, why preflight and actual requests were denied CORS access:
+// origin, method or header not allowed. Browsers only report such denials in their console.
+func WithLogger(logger *slog.Logger) Option {
+	return func(ch *corsHandler) {
+		ch.logger = logger
+	}
+}
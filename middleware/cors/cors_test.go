@@ -1,12 +1,16 @@
 package cors_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/amirzayi/rahjoo/middleware/cors"
 )
@@ -77,3 +81,358 @@ func TestCors(t *testing.T) {
 		})
 	}
 }
+
+func TestCorsCredentials(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name,
+		method,
+		origin,
+		expectedOrigin,
+		expectedCredentials string
+		opts []func(*http.Request)
+	}{
+		{"Simple", http.MethodGet, "https://app.example.com", "https://app.example.com", "true", nil},
+		{"Preflight", http.MethodOptions, "https://app.example.com", "https://app.example.com", "true", []func(*http.Request){
+			func(r *http.Request) { r.Header.Set("Access-Control-Request-Method", http.MethodPost) },
+		}},
+		{"Disallowed origin", http.MethodGet, "https://evil.example.org", "", "", nil},
+		{"No origin", http.MethodGet, "", "", "", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := cors.CORSHandler(
+				cors.WithOrigins([]string{"https://app.example.com"}),
+				cors.WithCredentials(true),
+			)(next)
+			req := httptest.NewRequest(tc.method, routeCorsPath, http.NoBody)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			for _, opt := range tc.opts {
+				opt(req)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.expectedOrigin {
+				t.Fatalf("expected allowed origin %q, got %q", tc.expectedOrigin, got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tc.expectedCredentials {
+				t.Fatalf("expected allow credentials %q, got %q", tc.expectedCredentials, got)
+			}
+		})
+	}
+}
+
+func TestCorsCredentialsWildcard(t *testing.T) {
+	handler := cors.CORSHandler(cors.WithCredentials(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, routeCorsPath, http.NoBody)
+	req.Header.Set("Origin", "https://app.example.com")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("expected the request origin instead of a wildcard, got %q", got)
+	}
+}
+
+func TestCorsMaxAge(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name,
+		method,
+		expectedMaxAge string
+		opts []cors.Option
+	}{
+		{"Preflight", http.MethodOptions, "600", []cors.Option{cors.WithMaxAge(10 * time.Minute)}},
+		{"Truncated", http.MethodOptions, "1", []cors.Option{cors.WithMaxAge(1500 * time.Millisecond)}},
+		{"Unset", http.MethodOptions, "", nil},
+		{"Not preflight", http.MethodGet, "", []cors.Option{cors.WithMaxAge(10 * time.Minute)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, routeCorsPath, http.NoBody)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+			rec := httptest.NewRecorder()
+			cors.CORSHandler(tc.opts...)(next).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Max-Age"); got != tc.expectedMaxAge {
+				t.Fatalf("expected max age %q, got %q", tc.expectedMaxAge, got)
+			}
+		})
+	}
+}
+
+func TestCorsExposedHeaders(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name,
+		method,
+		origin,
+		expectedExposed string
+		opts []cors.Option
+	}{
+		{"Exposed", http.MethodGet, "https://app.example.com", "X-Request-Id, Link", []cors.Option{cors.WithExposedHeaders([]string{"X-Request-Id", "Link"})}},
+		{"Unset", http.MethodGet, "https://app.example.com", "", nil},
+		{"Disallowed origin", http.MethodGet, "https://evil.example.org", "", []cors.Option{
+			cors.WithOrigins([]string{"https://app.example.com"}),
+			cors.WithExposedHeaders([]string{"X-Request-Id"}),
+		}},
+		{"No origin", http.MethodGet, "", "", []cors.Option{cors.WithExposedHeaders([]string{"X-Request-Id"})}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, routeCorsPath, http.NoBody)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			cors.CORSHandler(tc.opts...)(next).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Expose-Headers"); got != tc.expectedExposed {
+				t.Fatalf("expected exposed headers %q, got %q", tc.expectedExposed, got)
+			}
+		})
+	}
+}
+
+func TestCorsOriginPatterns(t *testing.T) {
+	handler := cors.CORSHandler(cors.WithOrigins([]string{
+		"https://app.example.com",
+		"https://*.preview.example.com",
+		"http://localhost:*",
+		"https://*.example.org:8443",
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"https://pr-42.preview.example.com", true},
+		{"https://a.b.preview.example.com", true},
+		{"https://preview.example.com", false},
+		{"https://evilpreview.example.com", false},
+		{"http://pr-42.preview.example.com", false},
+		{"https://pr-42.preview.example.com:444", false},
+		{"http://localhost:3000", true},
+		{"http://localhost", true},
+		{"http://localhost.evil.com:3000", false},
+		{"https://api.example.org:8443", true},
+		{"https://api.example.org", false},
+		{"null", false},
+	} {
+		t.Run(tc.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, routeCorsPath, http.NoBody)
+			req.Header.Set("Origin", tc.origin)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != tc.allowed {
+				t.Fatalf("expected allowed %t, got %t", tc.allowed, got)
+			}
+		})
+	}
+}
+
+func TestCorsInvalidOriginPattern(t *testing.T) {
+	for _, pattern := range []string{"https://app.*.example.com", "https://*", "https://app.example.com:8*", "*.example.com"} {
+		t.Run(pattern, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for pattern %q", pattern)
+				}
+			}()
+			cors.CORSHandler(cors.WithOrigins([]string{pattern}))
+		})
+	}
+}
+
+func TestCorsOriginFunc(t *testing.T) {
+	tenants := map[string]string{"acme": "https://acme.example.com"}
+	handler := cors.CORSHandler(
+		cors.WithOrigins([]string{"https://static.example.com"}),
+		cors.WithOriginFunc(func(r *http.Request, origin string) bool {
+			return tenants[r.Header.Get("X-Tenant")] == origin
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		name,
+		tenant,
+		origin string
+		allowed bool
+	}{
+		{"Tenant origin", "acme", "https://acme.example.com", true},
+		{"Other tenant", "globex", "https://acme.example.com", false},
+		{"Static list replaced", "acme", "https://static.example.com", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, routeCorsPath, http.NoBody)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("X-Tenant", tc.tenant)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != tc.allowed {
+				t.Fatalf("expected allowed %t, got %t", tc.allowed, got)
+			}
+		})
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusOK)
+	})
+	denied := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	for _, tc := range []struct {
+		name,
+		origin,
+		method string
+		opts           []cors.Option
+		expectedStatus int
+		expectedAllow,
+		expectedOrigin string
+	}{
+		{"Default", "https://app.example.com", http.MethodPost, nil, http.StatusNoContent, "", "https://app.example.com"},
+		{"Status", "https://app.example.com", http.MethodPost, []cors.Option{cors.WithPreflightStatus(http.StatusOK)}, http.StatusOK, "", "https://app.example.com"},
+		{"Passthrough", "https://app.example.com", http.MethodPost, []cors.Option{cors.WithPreflightPassthrough()}, http.StatusOK, "GET, POST", "https://app.example.com"},
+		{"Denied", "https://evil.example.org", http.MethodPost, nil, http.StatusOK, "", ""},
+		{"Denied method", "https://app.example.com", "TRACE", []cors.Option{cors.WithPreflightDeniedHandler(denied)}, http.StatusForbidden, "", ""},
+		{"Denied handler", "https://evil.example.org", http.MethodPost, []cors.Option{cors.WithPreflightDeniedHandler(denied)}, http.StatusForbidden, "", ""},
+		{"Denied passthrough", "https://evil.example.org", http.MethodPost, []cors.Option{cors.WithPreflightPassthrough()}, http.StatusOK, "GET, POST", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := cors.CORSHandler(append(tc.opts, cors.WithOrigins([]string{"https://app.example.com"}))...)(next)
+			req := httptest.NewRequest(http.MethodOptions, routeCorsPath, http.NoBody)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tc.expectedAllow {
+				t.Fatalf("expected Allow %q, got %q", tc.expectedAllow, got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.expectedOrigin {
+				t.Fatalf("expected allowed origin %q, got %q", tc.expectedOrigin, got)
+			}
+			vary := rec.Header().Values("Vary")
+			expectedVary := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+			if slices.Compare(vary, expectedVary) != 0 {
+				t.Fatalf("expected Vary %v, got %v", expectedVary, vary)
+			}
+		})
+	}
+}
+
+func TestCorsAllowedHeaders(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name,
+		requested string
+		opts            []cors.Option
+		expectedAllowed bool
+		expectedHeaders string
+	}{
+		{"Default", "content-type, AUTHORIZATION", nil, true, strings.Join(cors.DefaultHeadersAllowList, ", ")},
+		{"No headers requested", "", nil, true, strings.Join(cors.DefaultHeadersAllowList, ", ")},
+		{"Not allowed", "content-type, x-api-key", nil, false, ""},
+		{"Custom case", "x-api-key", []cors.Option{cors.WithHeaders([]string{"X-API-KEY"})}, true, "X-Api-Key"},
+		{"Comma joined entry", "x-tenant", []cors.Option{cors.WithHeaders([]string{"Content-Type, X-Tenant"})}, true, "Content-Type, X-Tenant"},
+		{"Wildcard", "x-custom, content-type", []cors.Option{cors.WithHeaders([]string{"*"})}, true, "X-Custom, Content-Type"},
+		{"Wildcard none requested", "", []cors.Option{cors.WithHeaders([]string{"*"})}, true, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, routeCorsPath, http.NoBody)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			if tc.requested != "" {
+				req.Header.Set("Access-Control-Request-Headers", tc.requested)
+			}
+
+			rec := httptest.NewRecorder()
+			cors.CORSHandler(tc.opts...)(next).ServeHTTP(rec, req)
+
+			if allowed := rec.Header().Get("Access-Control-Allow-Origin") != ""; allowed != tc.expectedAllowed {
+				t.Fatalf("expected allowed %t, got %t", tc.expectedAllowed, allowed)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Headers"); got != tc.expectedHeaders {
+				t.Fatalf("expected allowed headers %q, got %q", tc.expectedHeaders, got)
+			}
+		})
+	}
+}
+
+func TestCorsLogger(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name,
+		method,
+		origin,
+		requestedMethod,
+		requestedHeaders,
+		expectedReason string
+	}{
+		{"Origin", http.MethodOptions, "https://evil.example.org", http.MethodGet, "", "origin not allowed"},
+		{"Method", http.MethodOptions, "https://app.example.com", "TRACE", "", "method not allowed"},
+		{"Header", http.MethodOptions, "https://app.example.com", http.MethodGet, "X-Api-Key", "header not allowed"},
+		{"Actual request origin", http.MethodGet, "https://evil.example.org", "", "", "origin not allowed"},
+		{"Allowed", http.MethodOptions, "https://app.example.com", http.MethodGet, "Content-Type", ""},
+		{"Same origin", http.MethodGet, "", "", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			handler := cors.CORSHandler(
+				cors.WithOrigins([]string{"https://app.example.com"}),
+				cors.WithLogger(logger),
+			)(next)
+
+			req := httptest.NewRequest(tc.method, routeCorsPath, http.NoBody)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.requestedMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tc.requestedMethod)
+			}
+			if tc.requestedHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tc.requestedHeaders)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tc.expectedReason == "" {
+				if buf.Len() != 0 {
+					t.Fatalf("expected no log, got %s", buf.String())
+				}
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("expected a log entry, got %q: %v", buf.String(), err)
+			}
+			if entry["level"] != "DEBUG" || entry["reason"] != tc.expectedReason || entry["origin"] != tc.origin {
+				t.Fatalf("unexpected log entry %v", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// headerMatcher matches the headers of Access-Control-Request-Headers against the allowed
+// headers, compiled once from WithHeaders in canonical form.
+type headerMatcher struct {
+	any     bool
+	allowed map[string]struct{}
+	// value is the Access-Control-Allow-Headers value sent unless any is set.
+	value string
+}
+
+// compileHeaders compiles the allowed headers. Entries may themselves be comma-separated
+// lists, and "*" allows any header.
+func compileHeaders(headers []string) headerMatcher {
+	m := headerMatcher{allowed: make(map[string]struct{})}
+	var names []string
+	for _, h := range headers {
+		for _, name := range splitHeaderList(h) {
+			if name == "*" {
+				m.any = true
+				continue
+			}
+			if _, ok := m.allowed[name]; !ok {
+				m.allowed[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+	m.value = strings.Join(names, ", ")
+	return m
+}
+
+// match reports whether every header listed in the Access-Control-Request-Headers values
+// is allowed, and returns the Access-Control-Allow-Headers value to send.
+func (m headerMatcher) match(requested []string) (string, bool) {
+	var names []string
+	for _, v := range requested {
+		names = append(names, splitHeaderList(v)...)
+	}
+	if m.any {
+		return strings.Join(names, ", "), true
+	}
+	for _, name := range names {
+		if _, ok := m.allowed[name]; !ok {
+			return "", false
+		}
+	}
+	return m.value, true
+}
+
+// splitHeaderList splits a comma-separated list of header names into canonical names.
+func splitHeaderList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name != "*" {
+			name = http.CanonicalHeaderKey(name)
+		}
+		names = append(names, name)
+	}
+	return names
+}
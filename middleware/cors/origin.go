@@ -0,0 +1,99 @@
+package cors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// originMatcher matches request origins against the allowed origins, compiled once from
+// WithOrigins: exact origins are looked up in a set and wildcard ones matched by parts.
+type originMatcher struct {
+	any      bool
+	exact    map[string]struct{}
+	patterns []originPattern
+}
+
+// originPattern is an origin with a wildcard subdomain, such as "https://*.example.com",
+// and/or a wildcard port, such as "http://localhost:*".
+type originPattern struct {
+	scheme string
+	// host is the host, or the domain suffix including the leading dot if subdomain is set.
+	host      string
+	subdomain bool
+	// port is the port, empty for none or "*" for any, including none.
+	port string
+}
+
+// compileOrigins compiles the allowed origins. It panics if a pattern has a wildcard anywhere
+// but as the leftmost host label or the port.
+func compileOrigins(origins []string) originMatcher {
+	m := originMatcher{exact: make(map[string]struct{})}
+	for _, o := range origins {
+		o = strings.ToLower(o)
+		if o == "*" {
+			m.any = true
+			continue
+		}
+		if !strings.Contains(o, "*") {
+			m.exact[o] = struct{}{}
+			continue
+		}
+
+		scheme, host, port, ok := splitOrigin(o)
+		p := originPattern{scheme: scheme, host: host, port: port}
+		if rest, found := strings.CutPrefix(host, "*."); found {
+			p.host = "." + rest
+			p.subdomain = true
+		}
+		if !ok || strings.Contains(p.host, "*") || (strings.Contains(port, "*") && port != "*") {
+			panic(fmt.Sprintf("cors: invalid origin pattern %q", o))
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m
+}
+
+func (m originMatcher) match(origin string) bool {
+	if m.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := m.exact[origin]; ok {
+		return true
+	}
+	if len(m.patterns) == 0 {
+		return false
+	}
+
+	scheme, host, port, ok := splitOrigin(origin)
+	if !ok {
+		return false
+	}
+	for _, p := range m.patterns {
+		if p.scheme != scheme || (p.port != "*" && p.port != port) {
+			continue
+		}
+		if p.subdomain {
+			// The wildcard stands for at least one label.
+			if len(host) > len(p.host) && strings.HasSuffix(host, p.host) {
+				return true
+			}
+		} else if host == p.host {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOrigin splits a serialized origin, scheme://host[:port], into its parts.
+func splitOrigin(origin string) (scheme, host, port string, ok bool) {
+	scheme, hostport, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" || hostport == "" || strings.Contains(hostport, "/") {
+		return "", "", "", false
+	}
+	host = hostport
+	if i := strings.LastIndexByte(hostport, ':'); i > strings.LastIndexByte(hostport, ']') {
+		host, port = hostport[:i], hostport[i+1:]
+	}
+	return scheme, host, port, host != ""
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestTimeoutHeader is the default header Deadline reads the client-supplied timeout from.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+type deadlineConfig struct {
+	header   string
+	max      time.Duration
+	fallback time.Duration
+}
+
+// DeadlineOption configures the Deadline middleware.
+type DeadlineOption func(*deadlineConfig)
+
+// WithDeadlineHeader sets the header the timeout is read from. For the grpc-timeout header
+// values use the gRPC format, an integer followed by a unit among H, M, S, m, u and n
+// (e.g. "100m" for 100 milliseconds).
+func WithDeadlineHeader(header string) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.header = header
+	}
+}
+
+// WithDeadlineMax caps the timeout a client can ask for.
+func WithDeadlineMax(d time.Duration) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.max = d
+	}
+}
+
+// WithDeadlineDefault sets the timeout applied to requests without the header.
+// By default they get no deadline.
+func WithDeadlineDefault(d time.Duration) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.fallback = d
+	}
+}
+
+// Deadline is a middleware applying the timeout a client sent in the X-Request-Timeout header,
+// either a duration (e.g. "1.5s", "250ms") or a number of seconds, to the request context so
+// timeouts propagate across service hops. Use PropagateDeadline to forward the remaining time
+// to downstream requests.
+//
+// Handlers are expected to honor the context; if the deadline is exceeded before the handler
+// writes a response, 504 Gateway Timeout is written once it returns. Malformed headers get
+// 400 Bad Request.
+func Deadline(opts ...DeadlineOption) Middleware {
+	c := deadlineConfig{header: RequestTimeoutHeader}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	parse := parseTimeout
+	if strings.EqualFold(c.header, "grpc-timeout") {
+		parse = parseGRPCTimeout
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := c.fallback
+			if v := r.Header.Get(c.header); v != "" {
+				d, err := parse(v)
+				if err != nil {
+					http.Error(w, "malformed "+c.header+" header", http.StatusBadRequest)
+					return
+				}
+				timeout = d
+			}
+			if c.max > 0 && (timeout <= 0 || timeout > c.max) {
+				timeout = c.max
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+			if !rw.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+			}
+		})
+	}
+}
+
+// PropagateDeadline sets the X-Request-Timeout header of an outgoing request to the time left
+// before the deadline of ctx, if any.
+func PropagateDeadline(ctx context.Context, header http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	remaining := max(time.Until(deadline).Milliseconds(), 0)
+	header.Set(RequestTimeoutHeader, strconv.FormatInt(remaining, 10)+"ms")
+}
+
+func parseTimeout(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs < 0 || secs > float64(1<<63-1)/float64(time.Second) {
+			return 0, errors.New("timeout out of range")
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("negative timeout")
+	}
+	return d, nil
+}
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+func parseGRPCTimeout(v string) (time.Duration, error) {
+	// The gRPC spec allows at most 8 digits.
+	if len(v) < 2 || len(v) > 9 {
+		return 0, errors.New("malformed grpc-timeout")
+	}
+	unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+	if !ok {
+		return 0, errors.New("unknown grpc-timeout unit")
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	// The largest values, up to 99999999 hours, overflow a Duration: clamp them to the longest one.
+	if n > uint64(math.MaxInt64/unit) {
+		return math.MaxInt64, nil
+	}
+	return time.Duration(n) * unit, nil
+}
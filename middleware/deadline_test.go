@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestDeadline(t *testing.T) {
+	// Reports the remaining time of the request context, or waits for it to end when asked to.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if r.URL.Query().Has("wait") {
+			<-r.Context().Done()
+			return
+		}
+		if !ok {
+			w.Write([]byte("none"))
+			return
+		}
+		w.Write([]byte(strconv.FormatInt(time.Until(deadline).Round(time.Second).Milliseconds(), 10)))
+	})
+
+	testCases := []struct {
+		name   string
+		opts   []middleware.DeadlineOption
+		header string
+		value  string
+		target string
+		status int
+		body   string
+	}{
+		{"no_header", nil, "", "", "/", http.StatusOK, "none"},
+		{"duration", nil, middleware.RequestTimeoutHeader, "10s", "/", http.StatusOK, "10000"},
+		{"seconds", nil, middleware.RequestTimeoutHeader, "5", "/", http.StatusOK, "5000"},
+		{"malformed", nil, middleware.RequestTimeoutHeader, "soon", "/", http.StatusBadRequest, ""},
+		{"negative", nil, middleware.RequestTimeoutHeader, "-1s", "/", http.StatusBadRequest, ""},
+		{"capped", []middleware.DeadlineOption{middleware.WithDeadlineMax(3 * time.Second)}, middleware.RequestTimeoutHeader, "1m", "/", http.StatusOK, "3000"},
+		{"default", []middleware.DeadlineOption{middleware.WithDeadlineDefault(2 * time.Second)}, "", "", "/", http.StatusOK, "2000"},
+		{"grpc", []middleware.DeadlineOption{middleware.WithDeadlineHeader("grpc-timeout")}, "Grpc-Timeout", "4S", "/", http.StatusOK, "4000"},
+		{"grpc_millis", []middleware.DeadlineOption{middleware.WithDeadlineHeader("grpc-timeout")}, "Grpc-Timeout", "7000m", "/", http.StatusOK, "7000"},
+		{"grpc_bad_unit", []middleware.DeadlineOption{middleware.WithDeadlineHeader("grpc-timeout")}, "Grpc-Timeout", "7s", "/", http.StatusBadRequest, ""},
+		{"grpc_too_long", []middleware.DeadlineOption{middleware.WithDeadlineHeader("grpc-timeout")}, "Grpc-Timeout", "123456789m", "/", http.StatusBadRequest, ""},
+		{"exceeded", nil, middleware.RequestTimeoutHeader, "10ms", "/?wait", http.StatusGatewayTimeout, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rec := httptest.NewRecorder()
+			middleware.Deadline(tc.opts...)(handler).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestPropagateDeadline(t *testing.T) {
+	var got http.Header
+	h := middleware.Deadline()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = http.Header{}
+		middleware.PropagateDeadline(r.Context(), got)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set(middleware.RequestTimeoutHeader, "10s")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	v := got.Get(middleware.RequestTimeoutHeader)
+	ms, err := strconv.Atoi(strings.TrimSuffix(v, "ms"))
+	if err != nil || ms <= 9000 || ms > 10000 {
+		t.Errorf("got propagated timeout %q, want about 10000ms", v)
+	}
+
+	none := http.Header{}
+	middleware.PropagateDeadline(req.Context(), none)
+	if len(none) != 0 {
+		t.Errorf("got header %v without a deadline", none)
+	}
+}
+
+func TestDeadlineGRPCOverflow(t *testing.T) {
+	var deadline time.Time
+	h := middleware.Deadline(middleware.WithDeadlineHeader("grpc-timeout"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Grpc-Timeout", "99999999H")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || time.Until(deadline) < 100*365*24*time.Hour {
+		t.Errorf("got %d with deadline %v, want the longest timeout", rec.Code, deadline)
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultDecompressMaxSize is the default cap on a decompressed request body.
+const defaultDecompressMaxSize = 10 << 20
+
+// Decoder decompresses a request body encoded with a content coding.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+type decompressConfig struct {
+	maxSize  int64
+	decoders map[string]Decoder
+}
+
+// DecompressOption configures the Decompress middleware.
+type DecompressOption func(*decompressConfig)
+
+// WithDecompressMaxSize caps the size of the decompressed body to n bytes, protecting handlers
+// from zip bombs. Reading past it fails, as with http.MaxBytesReader. It defaults to 10 MiB.
+func WithDecompressMaxSize(n int64) DecompressOption {
+	return func(c *decompressConfig) {
+		c.maxSize = n
+	}
+}
+
+// WithDecoder adds or replaces the decoder of a content coding, such as "br" or "zstd".
+func WithDecoder(coding string, decoder Decoder) DecompressOption {
+	return func(c *decompressConfig) {
+		c.decoders[strings.ToLower(coding)] = decoder
+	}
+}
+
+// Decompress is a middleware transparently decompressing request bodies sent with a
+// Content-Encoding header, so handlers read them as plain bodies. gzip and deflate are supported
+// out of the box, other codings such as br can be added with WithDecoder. Requests using a coding
+// without decoder are answered with 415 Unsupported Media Type, corrupt ones with 400 Bad Request.
+func Decompress(opts ...DecompressOption) Middleware {
+	c := decompressConfig{
+		maxSize: defaultDecompressMaxSize,
+		decoders: map[string]Decoder{
+			"gzip":   func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+			"x-gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+			"deflate": func(r io.Reader) (io.ReadCloser, error) {
+				return zlib.NewReader(r)
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var codings []string
+			for _, v := range r.Header.Values("Content-Encoding") {
+				for _, coding := range strings.Split(v, ",") {
+					if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" && coding != "identity" {
+						codings = append(codings, coding)
+					}
+				}
+			}
+			if len(codings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := r.Body
+			// codings are listed in the order they were applied, so undo them backwards.
+			for i := len(codings) - 1; i >= 0; i-- {
+				decode, ok := c.decoders[codings[i]]
+				if !ok {
+					http.Error(w, "unsupported Content-Encoding "+codings[i], http.StatusUnsupportedMediaType)
+					return
+				}
+				decoded, err := decode(body)
+				if err != nil {
+					http.Error(w, "malformed "+codings[i]+" request body", http.StatusBadRequest)
+					return
+				}
+				body = decoded
+			}
+
+			r.Body = http.MaxBytesReader(w, body, c.maxSize)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestDecompress(t *testing.T) {
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		io.WriteString(zw, s)
+		zw.Close()
+		return buf.Bytes()
+	}
+	deflated := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		io.WriteString(zw, s)
+		zw.Close()
+		return buf.Bytes()
+	}
+	// rotted shifts every byte up by one, the toy "rot" coding decoded below.
+	rotted := func(b []byte) []byte {
+		for i := range b {
+			b[i]++
+		}
+		return b
+	}
+
+	h := middleware.Decompress(
+		middleware.WithDecompressMaxSize(16),
+		middleware.WithDecoder("rot", func(r io.Reader) (io.ReadCloser, error) {
+			b, err := io.ReadAll(r)
+			for i := range b {
+				b[i]--
+			}
+			return io.NopCloser(bytes.NewReader(b)), err
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Error("Content-Encoding must be removed once decoded")
+		}
+		w.Write(b)
+	}))
+
+	testCases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		want     string
+	}{
+		{"plain", "", []byte("hello"), http.StatusOK, "hello"},
+		{"gzip", "gzip", gzipped("hello"), http.StatusOK, "hello"},
+		{"deflate", "deflate", deflated("hello"), http.StatusOK, "hello"},
+		{"stacked", "gzip, rot", rotted(gzipped("hello")), http.StatusOK, "hello"},
+		{"custom", "rot", rotted([]byte("hello")), http.StatusOK, "hello"},
+		{"unsupported", "br", []byte("x"), http.StatusUnsupportedMediaType, ""},
+		{"corrupt", "gzip", []byte("not gzip"), http.StatusBadRequest, ""},
+		{"bomb", "gzip", gzipped(strings.Repeat("a", 1<<20)), http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+			if tc.encoding != "" {
+				req.Header.Set("Content-Encoding", tc.encoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.want != "" && rec.Body.String() != tc.want {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default body size captured by the Dump middleware.
+const defaultDumpMaxBody = 64 << 10
+
+// defaultDumpRedactedHeaders carry credentials and are redacted unless configured otherwise.
+var defaultDumpRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+type dumpConfig struct {
+	maxBody  int
+	redacted []string
+	trigger  string
+}
+
+// DumpOption configures the Dump middleware.
+type DumpOption func(*dumpConfig)
+
+// WithDumpMaxBody caps the number of body bytes dumped per request and response. It defaults
+// to 64 KiB; longer bodies are truncated in the dump but reach the handler and the client whole.
+func WithDumpMaxBody(n int) DumpOption {
+	return func(c *dumpConfig) {
+		c.maxBody = n
+	}
+}
+
+// WithDumpRedactedHeaders sets the headers whose values are replaced by [REDACTED]. It defaults
+// to Authorization, Proxy-Authorization, Cookie and Set-Cookie.
+func WithDumpRedactedHeaders(headers ...string) DumpOption {
+	return func(c *dumpConfig) {
+		c.redacted = headers
+	}
+}
+
+// WithDumpTrigger only dumps requests carrying header, e.g. "X-Debug-Dump", so a client
+// integration can be debugged in production without dumping all traffic.
+func WithDumpTrigger(header string) DumpOption {
+	return func(c *dumpConfig) {
+		c.trigger = header
+	}
+}
+
+// Dump is a middleware writing the full request and response, headers and bodies, to w, for
+// debugging client integrations. Apply it to selected routes with NewHandler or SetMiddleware,
+// or to all requests carrying a debug header with WithDumpTrigger.
+//
+// Each exchange is written at once when the response is complete, so concurrent dumps do not
+// interleave.
+func Dump(w io.Writer, opts ...DumpOption) Middleware {
+	c := dumpConfig{maxBody: defaultDumpMaxBody, redacted: defaultDumpRedactedHeaders}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if c.trigger != "" && r.Header.Get(c.trigger) == "" {
+				next.ServeHTTP(rw, r)
+				return
+			}
+			start := time.Now()
+
+			var reqBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(c.maxBody)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "--- request %s\n%s %s %s\nHost: %s\n", requestIDOf(r), r.Method, r.URL.RequestURI(), r.Proto, r.Host)
+			c.writeHeaders(&buf, r.Header)
+			c.writeBody(&buf, reqBody)
+
+			dw := &dumpWriter{ResponseWriter: WrapResponseWriter(rw), max: c.maxBody}
+			next.ServeHTTP(dw, r)
+
+			fmt.Fprintf(&buf, "--- response %d %s (%s)\n", dw.Status(), http.StatusText(dw.Status()), time.Since(start))
+			c.writeHeaders(&buf, rw.Header())
+			c.writeBody(&buf, dw.body.Bytes())
+
+			mu.Lock()
+			defer mu.Unlock()
+			w.Write(buf.Bytes())
+		})
+	}
+}
+
+func (c *dumpConfig) writeHeaders(buf *bytes.Buffer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if slices.ContainsFunc(c.redacted, func(r string) bool { return http.CanonicalHeaderKey(r) == k }) {
+				v = redacted
+			}
+			fmt.Fprintf(buf, "%s: %s\n", k, v)
+		}
+	}
+	buf.WriteByte('\n')
+}
+
+func (c *dumpConfig) writeBody(buf *bytes.Buffer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	if len(body) > c.maxBody {
+		buf.Write(body[:c.maxBody])
+		buf.WriteString("\n[TRUNCATED]\n")
+		return
+	}
+	buf.Write(body)
+	buf.WriteByte('\n')
+}
+
+// dumpWriter copies up to max+1 bytes of the response body, enough to detect truncation.
+type dumpWriter struct {
+	ResponseWriter
+	max  int
+	body bytes.Buffer
+}
+
+func (dw *dumpWriter) Write(b []byte) (int, error) {
+	if room := dw.max + 1 - dw.body.Len(); room > 0 {
+		dw.body.Write(b[:min(len(b), room)])
+	}
+	return dw.ResponseWriter.Write(b)
+}
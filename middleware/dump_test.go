@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestDump(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+
+	testCases := []struct {
+		name    string
+		opts    []middleware.DumpOption
+		body    string
+		trigger bool
+		want    []string
+		notWant []string
+	}{
+		{"full", nil, `{"name":"amir"}`, false,
+			[]string{"--- request \nPOST /users?x=1 HTTP/1.1\nHost: example.com\n", "Authorization: [REDACTED]\n", "X-Client: mobile\n", `{"name":"amir"}`, "--- response 201 Created", "Set-Cookie: [REDACTED]\n"},
+			[]string{"Bearer", "secret"}},
+		{"truncated", []middleware.DumpOption{middleware.WithDumpMaxBody(4)}, "abcdefgh", false,
+			[]string{"abcd\n[TRUNCATED]\n"}, []string{"abcde"}},
+		{"custom_redaction", []middleware.DumpOption{middleware.WithDumpRedactedHeaders("x-client")}, "", false,
+			[]string{"X-Client: [REDACTED]\n", "Authorization: Bearer token\n"}, nil},
+		{"trigger_absent", []middleware.DumpOption{middleware.WithDumpTrigger("X-Debug-Dump")}, "", false,
+			nil, []string{"request"}},
+		{"trigger_present", []middleware.DumpOption{middleware.WithDumpTrigger("X-Debug-Dump")}, "", true,
+			[]string{"X-Debug-Dump: 1\n"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/users?x=1", strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("X-Client", "mobile")
+			if tc.trigger {
+				req.Header.Set("X-Debug-Dump", "1")
+			}
+			rec := httptest.NewRecorder()
+			middleware.Dump(&out, tc.opts...)(echo).ServeHTTP(rec, req)
+
+			if rec.Body.String() != tc.body {
+				t.Errorf("handler got body %q, want %q", rec.Body, tc.body)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("dump does not contain %q:\n%s", s, out.String())
+				}
+			}
+			for _, s := range tc.notWant {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("dump contains %q:\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/internal/errctx"
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+// StatusCoder is implemented by errors carrying the status code they should be answered with,
+// such as rahjoo.HTTPError and problem.Details. Their message is exposed to the client.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type errorHandlerConfig struct {
+	logger *slog.Logger
+	mapper func(error) int
+}
+
+// ErrorHandlerOption configures the ErrorHandler middleware.
+type ErrorHandlerOption func(*errorHandlerConfig)
+
+// WithErrorLogger logs server errors (5xx) with logger, so their details, hidden from clients,
+// are not lost.
+func WithErrorLogger(logger *slog.Logger) ErrorHandlerOption {
+	return func(c *errorHandlerConfig) {
+		c.logger = logger
+	}
+}
+
+// WithErrorMapper sets a function mapping errors not implementing StatusCoder, e.g. sentinel
+// errors of a storage layer, to status codes. Returning 0, or any code outside 100-599, falls
+// back to 500 Internal Server Error.
+func WithErrorMapper(mapper func(error) int) ErrorHandlerOption {
+	return func(c *errorHandlerConfig) {
+		c.mapper = mapper
+	}
+}
+
+// ErrorHandler is a middleware rendering the errors returned by handlers adapted with
+// rahjoo.HandlerE as RFC 7807 problem+json responses.
+//
+// A *problem.Details returned as is is written unchanged, except for a missing instance set to
+// the request path. Otherwise the status code is taken from errors implementing StatusCoder,
+// whose message becomes the detail of the problem, then from the mapper set with
+// WithErrorMapper. Any other error, or status code outside 100-599, is answered with 500 Internal
+// Server Error without detail, so internals do not leak. Nothing is written if the handler
+// already started the response.
+func ErrorHandler(opts ...ErrorHandlerOption) Middleware {
+	var c errorHandlerConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, sink := errctx.With(r)
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			if sink.Err == nil || rw.Written() {
+				return
+			}
+
+			if details := (*problem.Details)(nil); errors.As(sink.Err, &details) {
+				if details.Instance == "" || !validStatus(details.Status) {
+					fixed := *details
+					if fixed.Instance == "" {
+						fixed.Instance = r.URL.Path
+					}
+					if !validStatus(fixed.Status) {
+						fixed.Status = http.StatusInternalServerError
+					}
+					details = &fixed
+				}
+				details.Write(w)
+				return
+			}
+
+			status, detail := http.StatusInternalServerError, ""
+			if sc := StatusCoder(nil); errors.As(sink.Err, &sc) {
+				if code := sc.StatusCode(); validStatus(code) {
+					status = code
+					if err, ok := sc.(error); ok {
+						detail = err.Error()
+					}
+				}
+			} else if c.mapper != nil {
+				if code := c.mapper(sink.Err); validStatus(code) {
+					status = code
+				}
+			}
+			if status >= http.StatusInternalServerError && c.logger != nil {
+				c.logger.LogAttrs(context.WithoutCancel(r.Context()), slog.LevelError, "handler error",
+					slog.String("error", sink.Err.Error()),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", requestIDOf(r)),
+				)
+			}
+			problem.Write(w, status, problem.WithDetail(detail), problem.WithInstance(r.URL.Path))
+		})
+	}
+}
+
+// validStatus reports whether code can be written as the status code of a response.
+func validStatus(code int) bool {
+	return code >= 100 && code <= 599
+}
@@ -0,0 +1,116 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestErrorHandler(t *testing.T) {
+	var logs bytes.Buffer
+	mw := middleware.ErrorHandler(
+		middleware.WithErrorLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		middleware.WithErrorMapper(func(err error) int {
+			if errors.Is(err, errNotFound) {
+				return http.StatusNotFound
+			}
+			return 0
+		}),
+	)
+
+	testCases := []struct {
+		name    string
+		err     error
+		started bool
+		status  int
+		detail  string
+		logged  bool
+	}{
+		{"http_error", &rahjoo.HTTPError{Code: http.StatusConflict, Message: "email taken"}, false, http.StatusConflict, "email taken", false},
+		{"mapped", errNotFound, false, http.StatusNotFound, "", false},
+		{"internal", errors.New("db: secret dsn"), false, http.StatusInternalServerError, "", true},
+		{"already_started", errors.New("write failed"), true, http.StatusAccepted, "", false},
+		{"zero_status", &rahjoo.HTTPError{}, false, http.StatusInternalServerError, "", true},
+		{"invalid_status", rahjoo.Error(1000, "boom"), false, http.StatusInternalServerError, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			h := mw(rahjoo.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+				if tc.started {
+					w.WriteHeader(http.StatusAccepted)
+				}
+				return tc.err
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", http.NoBody))
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := logs.Len() > 0; got != tc.logged {
+				t.Errorf("got logged %t, want %t", got, tc.logged)
+			}
+			if tc.started {
+				if rec.Body.Len() != 0 {
+					t.Errorf("got body %q written after the response started", rec.Body)
+				}
+				return
+			}
+
+			var problem map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem["status"] != float64(tc.status) || problem["title"] != http.StatusText(tc.status) || problem["instance"] != "/users" {
+				t.Errorf("got problem %v", problem)
+			}
+			if detail, _ := problem["detail"].(string); detail != tc.detail || strings.Contains(rec.Body.String(), "secret") {
+				t.Errorf("got detail %q, want %q", detail, tc.detail)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerProblemDetails(t *testing.T) {
+	h := middleware.ErrorHandler()(rahjoo.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return problem.New(http.StatusPaymentRequired,
+			problem.WithType("https://example.com/probs/credit", "Out of credit"),
+			problem.WithExtension("balance", 30),
+		)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/purchases", http.NoBody))
+
+	var details problem.Details
+	if err := json.Unmarshal(rec.Body.Bytes(), &details); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusPaymentRequired || details.Title != "Out of credit" ||
+		details.Instance != "/purchases" || details.Extensions["balance"] != 30.0 {
+		t.Errorf("got %d %+v", rec.Code, details)
+	}
+}
+
+func TestErrorHandlerProblemDetailsInvalidStatus(t *testing.T) {
+	h := middleware.ErrorHandler()(rahjoo.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return &problem.Details{Title: "Broken"}
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/purchases", http.NoBody))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorRegistry maps sentinel and domain errors to the status codes they are answered with, so
+// a service declares the mapping once:
+//
+//	errs := middleware.NewErrorRegistry()
+//	errs.Register(store.ErrNotFound, http.StatusNotFound)
+//	errs.Register(store.ErrConflict, http.StatusConflict)
+//
+//	middleware.ErrorHandler(middleware.WithErrorMapper(errs.Status))
+//
+// It is safe for concurrent use.
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	entries []errorStatus
+}
+
+type errorStatus struct {
+	target error
+	code   int
+}
+
+// NewErrorRegistry returns an empty ErrorRegistry.
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{}
+}
+
+// Register maps the errors matching target with errors.Is to code. Registering target again
+// replaces its code.
+func (reg *ErrorRegistry) Register(target error, code int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for i, e := range reg.entries {
+		if e.target == target {
+			reg.entries[i].code = code
+			return
+		}
+	}
+	reg.entries = append(reg.entries, errorStatus{target: target, code: code})
+}
+
+// Status returns the code of the first registered target err matches, or 0 if there is none.
+// Its signature fits WithErrorMapper.
+func (reg *ErrorRegistry) Status(err error) int {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, e := range reg.entries {
+		if errors.Is(err, e.target) {
+			return e.code
+		}
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+var (
+	errMissing  = errors.New("missing")
+	errConflict = errors.New("conflict")
+)
+
+func TestErrorRegistry(t *testing.T) {
+	reg := middleware.NewErrorRegistry()
+	reg.Register(errMissing, http.StatusNotFound)
+	reg.Register(errConflict, http.StatusBadRequest)
+	reg.Register(errConflict, http.StatusConflict)
+
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"sentinel", errMissing, http.StatusNotFound},
+		{"wrapped", fmt.Errorf("load user 42: %w", errMissing), http.StatusNotFound},
+		{"re_registered", errConflict, http.StatusConflict},
+		{"unregistered", errors.New("disk full"), 0},
+		{"nil", nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := reg.Status(tc.err); got != tc.status {
+				t.Errorf("got status %d, want %d", got, tc.status)
+			}
+		})
+	}
+}
+
+func TestErrorRegistryWithErrorHandler(t *testing.T) {
+	reg := middleware.NewErrorRegistry()
+	reg.Register(errMissing, http.StatusNotFound)
+
+	h := middleware.ErrorHandler(middleware.WithErrorMapper(reg.Status))(rahjoo.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("load user: %w", errMissing)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", http.NoBody))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if strings.Contains(rec.Body.String(), "load user") {
+		t.Errorf("got internal error message in body %q", rec.Body)
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import "net/http"
+
+// Heartbeat is a middleware answering GET and HEAD requests to path (e.g. "/ping") with
+// 200 OK and a "." body without calling the next handler. Placed before the logging, auth and
+// metrics middlewares, it keeps load balancer health checks cheap and out of the access logs.
+func Heartbeat(path string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodGet {
+				w.Write([]byte("."))
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestHeartbeat(t *testing.T) {
+	h := middleware.Heartbeat("/ping")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"get", http.MethodGet, "/ping", http.StatusOK, "."},
+		{"head", http.MethodHead, "/ping", http.StatusOK, ""},
+		{"post_passes_through", http.MethodPost, "/ping", http.StatusUnauthorized, ""},
+		{"other_path", http.MethodGet, "/ping/x", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, http.NoBody))
+			if rec.Code != tc.status || rec.Body.String() != tc.body {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body, tc.status, tc.body)
+			}
+		})
+	}
+}
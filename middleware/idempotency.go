@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	// ErrIdempotencyInProgress is returned by IdempotencyStore.Start when a request with the same
+	// key is still being handled.
+	ErrIdempotencyInProgress = errors.New("idempotency: request in progress")
+	// ErrIdempotencyMismatch is returned by IdempotencyStore.Start when the key was used by a
+	// request with a different fingerprint.
+	ErrIdempotencyMismatch = errors.New("idempotency: key reused with a different request")
+)
+
+// IdempotencyStore keeps the responses of the Idempotency middleware.
+type IdempotencyStore interface {
+	// Start reserves key for a request identified by fingerprint until expires. It returns the
+	// response stored by a completed request with the same key and fingerprint, or nil if the
+	// key was free. It returns ErrIdempotencyInProgress if the key is reserved by a request still
+	// being handled and ErrIdempotencyMismatch if it was used with another fingerprint.
+	Start(ctx context.Context, key, fingerprint string, expires time.Time) (*CachedResponse, error)
+	// Complete stores the response of the request that reserved key.
+	Complete(ctx context.Context, key string, resp *CachedResponse) error
+	// Release frees key without storing a response, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// defaultIdempotencyMaxBody is the default cap on the size of the bodies Idempotency reads.
+const defaultIdempotencyMaxBody = 10 << 20
+
+type idempotencyConfig struct {
+	maxBody int64
+}
+
+// IdempotencyOption configures the Idempotency middleware.
+type IdempotencyOption func(*idempotencyConfig)
+
+// WithIdempotencyMaxBody caps the size of the bodies read to fingerprint requests. It defaults to
+// 10 MiB.
+func WithIdempotencyMaxBody(n int64) IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.maxBody = n
+	}
+}
+
+// Idempotency is a middleware making POST and PATCH requests carrying an Idempotency-Key header
+// safe to retry: the first response for a key is stored for ttl and replayed, with an
+// Idempotent-Replayed header, to retries instead of handling them again.
+//
+// Keys are scoped by the principal (see GetPrincipal) when there is one. A retry arriving while
+// the first request is still handled is answered with 409 Conflict, and reusing a key for a
+// request with a different method, path or body with 422 Unprocessable Entity. Server errors
+// (5xx) are not stored, so the client can retry them. If the store fails, the request is
+// answered with 503 Service Unavailable rather than risking a duplicate execution. Bodies over
+// the limit set by WithIdempotencyMaxBody get 413 Content Too Large.
+func Idempotency(store IdempotencyStore, ttl time.Duration, opts ...IdempotencyOption) Middleware {
+	c := idempotencyConfig{maxBody: defaultIdempotencyMaxBody}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(IdempotencyKeyHeader)
+			if idemKey == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if principal, ok := GetPrincipal(r.Context()); ok {
+				idemKey = principal + "\x00" + idemKey
+			}
+
+			body, err := readBody(r, c.maxBody)
+			if errors.Is(err, errBodyTooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			sum := sha256.Sum256(body)
+			fingerprint := r.Method + " " + r.URL.RequestURI() + " " + hex.EncodeToString(sum[:])
+
+			ctx := r.Context()
+			stored, err := store.Start(ctx, idemKey, fingerprint, time.Now().Add(ttl))
+			switch {
+			case errors.Is(err, ErrIdempotencyInProgress):
+				http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+				return
+			case errors.Is(err, ErrIdempotencyMismatch):
+				http.Error(w, "idempotency key reused with a different request", http.StatusUnprocessableEntity)
+				return
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			case stored != nil:
+				h := w.Header()
+				for k, v := range stored.Header {
+					h[k] = slices.Clone(v)
+				}
+				h.Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(context.WithoutCancel(ctx), idemKey)
+				}
+			}()
+
+			cw := &cacheWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			if cw.status >= http.StatusInternalServerError {
+				return
+			}
+			if !cw.wroteHeader {
+				cw.header = w.Header().Clone()
+			}
+			completed = store.Complete(context.WithoutCancel(ctx), idemKey, &CachedResponse{
+				Status:  cw.status,
+				Header:  cw.header,
+				Body:    cw.body.Bytes(),
+				Expires: time.Now().Add(ttl),
+			}) == nil
+		})
+	}
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	resp        *CachedResponse
+	expires     time.Time
+}
+
+// idempotencySweepInterval is how often expired entries are dropped from a
+// MemoryIdempotencyStore.
+const idempotencySweepInterval = time.Minute
+
+// MemoryIdempotencyStore is an IdempotencyStore keeping responses in memory. It is safe for
+// concurrent use and drops expired entries periodically.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]*idempotencyEntry{}}
+}
+
+// Start implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Start(_ context.Context, key, fingerprint string, expires time.Time) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= idempotencySweepInterval {
+		s.lastSweep = now
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	e, ok := s.entries[key]
+	switch {
+	case !ok || now.After(e.expires):
+		s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expires: expires}
+		return nil, nil
+	case e.fingerprint != fingerprint:
+		return nil, ErrIdempotencyMismatch
+	case e.resp == nil:
+		return nil, ErrIdempotencyInProgress
+	}
+	return e.resp, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, resp *CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.resp = resp
+		e.expires = resp.Expires
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
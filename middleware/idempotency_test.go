@@ -0,0 +1,122 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := middleware.Idempotency(middleware.NewMemoryIdempotencyStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/slow":
+			<-release
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/orders/%d", n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "order %d", n)
+	}))
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	testCases := []struct {
+		name                    string
+		method, path, key, body string
+		status                  int
+		wantBody                string
+		replayed                bool
+	}{
+		{"first", http.MethodPost, "/orders", "k1", "a", http.StatusCreated, "order 1", false},
+		{"retry", http.MethodPost, "/orders", "k1", "a", http.StatusCreated, "order 1", true},
+		{"different_body", http.MethodPost, "/orders", "k1", "b", http.StatusUnprocessableEntity, "", false},
+		{"no_key", http.MethodPost, "/orders", "", "a", http.StatusCreated, "order 2", false},
+		{"other_key", http.MethodPost, "/orders", "k2", "a", http.StatusCreated, "order 3", false},
+		{"put_ignored", http.MethodPut, "/orders", "k2", "a", http.StatusCreated, "order 4", false},
+		{"server_error", http.MethodPost, "/fail", "k3", "", http.StatusBadGateway, "", false},
+		{"server_error_retried", http.MethodPost, "/fail", "k3", "", http.StatusBadGateway, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(tc.method, tc.path, tc.key, tc.body)
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.wantBody != "" && rec.Body.String() != tc.wantBody {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.wantBody)
+			}
+			if got := rec.Header().Get("Idempotent-Replayed") == "true"; got != tc.replayed {
+				t.Errorf("got replayed %t, want %t", got, tc.replayed)
+			}
+		})
+	}
+	if got := calls.Load(); got != 6 {
+		t.Errorf("handler called %d times, want 6", got)
+	}
+
+	t.Run("concurrent_duplicate", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- do(http.MethodPost, "/slow", "k4", "") }()
+		for calls.Load() != 7 {
+			time.Sleep(time.Millisecond)
+		}
+		if rec := do(http.MethodPost, "/slow", "k4", ""); rec.Code != http.StatusConflict {
+			t.Errorf("got status code %d, want %d", rec.Code, http.StatusConflict)
+		}
+		close(release)
+		if rec := <-done; rec.Code != http.StatusCreated {
+			t.Errorf("got status code %d, want %d", rec.Code, http.StatusCreated)
+		}
+		if rec := do(http.MethodPost, "/slow", "k4", ""); rec.Header().Get("Location") != "/orders/7" {
+			t.Errorf("got Location %q, want the replayed /orders/7", rec.Header().Get("Location"))
+		}
+	})
+}
+
+func TestIdempotencyMaxBody(t *testing.T) {
+	h := middleware.Idempotency(middleware.NewMemoryIdempotencyStore(), time.Hour, middleware.WithIdempotencyMaxBody(4))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("hello world"))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "k1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := middleware.NewMemoryIdempotencyStore()
+	ctx := context.Background()
+	if _, err := store.Start(ctx, "k1", "POST /orders", time.Now().Add(10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Start(ctx, "k1", "POST /orders", time.Now().Add(time.Hour)); !errors.Is(err, middleware.ErrIdempotencyInProgress) {
+		t.Fatalf("got error %v, want %v", err, middleware.ErrIdempotencyInProgress)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if resp, err := store.Start(ctx, "k1", "POST /other", time.Now().Add(time.Hour)); resp != nil || err != nil {
+		t.Errorf("got %v, %v for an expired key, want it free", resp, err)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxInFlight is a middleware bounding the number of requests served concurrently to n.
+// A request arriving while n requests are in flight waits up to queueTimeout for a slot;
+// if none frees up in time, it is answered with 503 Service Unavailable and a Retry-After header.
+//
+// All handlers wrapped by the returned Middleware share the bound: wrap the mux to limit
+// globally, or pass it to NewHandler or SetMiddleware to limit a route or a group.
+func MaxInFlight(n int, queueTimeout time.Duration) Middleware {
+	slots := make(chan struct{}, n)
+	retryAfter := strconv.Itoa(max(1, int(math.Ceil(queueTimeout.Seconds()))))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !acquire(slots, r, queueTimeout) {
+				w.Header().Set("Retry-After", retryAfter)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquire takes a slot, waiting up to timeout or until the request is canceled.
+func acquire(slots chan struct{}, r *http.Request, timeout time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
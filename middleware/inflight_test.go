@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	testCases := []struct {
+		name         string
+		queueTimeout time.Duration
+		releaseAfter time.Duration
+		status       int
+	}{
+		{"saturated", 10 * time.Millisecond, 100 * time.Millisecond, http.StatusServiceUnavailable},
+		{"queued", time.Second, 10 * time.Millisecond, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			h := middleware.MaxInFlight(1, tc.queueTimeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					close(started)
+					<-release
+				}
+			}))
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", http.NoBody))
+			}()
+			<-started
+			time.AfterFunc(tc.releaseAfter, func() { close(release) })
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "1" {
+				t.Errorf("got Retry-After %q, want %q", rec.Header().Get("Retry-After"), "1")
+			}
+			<-done
+		})
+	}
+}
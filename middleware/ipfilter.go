@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+)
+
+type ipFilterConfig struct {
+	denied http.Handler
+}
+
+// IPFilterOption configures the IPFilter middleware.
+type IPFilterOption func(*ipFilterConfig)
+
+// WithIPFilterDeniedHandler sets the handler serving rejected requests instead of the default
+// 403 Forbidden response.
+func WithIPFilterDeniedHandler(h http.Handler) IPFilterOption {
+	return func(c *ipFilterConfig) {
+		c.denied = h
+	}
+}
+
+// IPFilter is a middleware restricting access by client IP, given as CIDR ranges or plain IPs.
+// Requests from a denied address are rejected; when allow is not empty, so are requests from
+// addresses it does not contain. Deny takes precedence over allow.
+//
+// The client IP is taken from r.RemoteAddr, so IPFilter should come after RealIP when the
+// server sits behind proxies. Rejected requests get 403 Forbidden.
+// It panics if a range is malformed.
+func IPFilter(allow, deny []string, opts ...IPFilterOption) Middleware {
+	allowed := mustParsePrefixes("allowed IP", allow)
+	denied := mustParsePrefixes("denied IP", deny)
+	c := ipFilterConfig{
+		denied: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, err := parseNodeIP(remoteIP(r))
+			if err != nil || denied.contains(ip) || (len(allowed) > 0 && !allowed.contains(ip)) {
+				c.denied.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// prefixes is a set of IP ranges.
+type prefixes []netip.Prefix
+
+// mustParsePrefixes parses CIDR ranges or single IPs, panicking on malformed ones.
+func mustParsePrefixes(what string, cidrs []string) prefixes {
+	p := make(prefixes, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("middleware: invalid %s %q: %v", what, cidr, err))
+		}
+		p = append(p, prefix)
+	}
+	return p
+}
+
+func (p prefixes) contains(ip netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestIPFilter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	teapot := middleware.WithIPFilterDeniedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	testCases := []struct {
+		name        string
+		allow, deny []string
+		opts        []middleware.IPFilterOption
+		remoteAddr  string
+		status      int
+	}{
+		{"no_rules", nil, nil, nil, "203.0.113.9:1234", http.StatusOK},
+		{"allowed", []string{"10.0.0.0/8"}, nil, nil, "10.1.2.3:1234", http.StatusOK},
+		{"not_allowed", []string{"10.0.0.0/8"}, nil, nil, "203.0.113.9:1234", http.StatusForbidden},
+		{"denied", nil, []string{"203.0.113.9"}, nil, "203.0.113.9:1234", http.StatusForbidden},
+		{"deny_wins", []string{"10.0.0.0/8"}, []string{"10.0.0.5"}, nil, "10.0.0.5:1234", http.StatusForbidden},
+		{"real_ip_without_port", []string{"1.2.3.4"}, nil, nil, "1.2.3.4", http.StatusOK},
+		{"ipv4_mapped", []string{"1.2.3.0/24"}, nil, nil, "[::ffff:1.2.3.4]:1234", http.StatusOK},
+		{"ipv6", []string{"2001:db8::/32"}, nil, nil, "[2001:db8::1]:1234", http.StatusOK},
+		{"unparsable", []string{"0.0.0.0/0"}, nil, nil, "unknown", http.StatusForbidden},
+		{"custom_handler", []string{"10.0.0.0/8"}, nil, []middleware.IPFilterOption{teapot}, "203.0.113.9:1234", http.StatusTeapot},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", http.NoBody)
+			req.RemoteAddr = tc.remoteAddr
+			rec := httptest.NewRecorder()
+			middleware.IPFilter(tc.allow, tc.deny, tc.opts...)(ok).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidCIDR(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid CIDR")
+		}
+	}()
+	middleware.IPFilter(nil, []string{"not-an-ip"})
+}
@@ -0,0 +1,70 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+// Default body size validated by the Validate middleware.
+const defaultMaxBody = 1 << 20
+
+type config struct {
+	maxBody int64
+}
+
+// Option configures the Validate middleware.
+type Option func(*config)
+
+// WithMaxBody caps the size of validated bodies. It defaults to 1 MiB.
+func WithMaxBody(n int64) Option {
+	return func(c *config) {
+		c.maxBody = n
+	}
+}
+
+// Validate is a middleware validating JSON request bodies against schema before the handler
+// runs; the body is then passed on unchanged. Malformed bodies are answered with 400 Bad
+// Request, and bodies violating the schema with 422 Unprocessable Entity, both as problem
+// details; the latter lists every violation in an "errors" member.
+func Validate(schema *Schema, opts ...Option) middleware.Middleware {
+	c := config{maxBody: defaultMaxBody}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBody))
+			if err != nil {
+				status := http.StatusBadRequest
+				if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				problem.Write(w, status, problem.WithDetail(err.Error()))
+				return
+			}
+
+			var doc any
+			if err := json.Unmarshal(body, &doc); err != nil {
+				problem.Write(w, http.StatusBadRequest, problem.WithDetail("request body is not valid JSON: "+err.Error()))
+				return
+			}
+			if violations := schema.Validate(doc); len(violations) > 0 {
+				problem.Write(w, http.StatusUnprocessableEntity,
+					problem.WithDetail("request body does not match its schema"),
+					problem.WithExtension("errors", violations),
+				)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware/jsonschema"
+)
+
+func TestValidate(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}`))
+	h := jsonschema.Validate(schema, jsonschema.WithMaxBody(64))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	testCases := []struct {
+		name       string
+		body       string
+		status     int
+		violations int
+	}{
+		{"valid", `{"name":"amir"}`, http.StatusOK, 0},
+		{"invalid", `{"name":1,"x":2}`, http.StatusUnprocessableEntity, 1},
+		{"missing", `{}`, http.StatusUnprocessableEntity, 1},
+		{"malformed", `{"name":`, http.StatusBadRequest, 0},
+		{"too_large", `{"name":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.body)))
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.status == http.StatusOK {
+				if rec.Body.String() != tc.body {
+					t.Errorf("handler got body %q, want %q", rec.Body, tc.body)
+				}
+				return
+			}
+			var problem struct {
+				Errors []jsonschema.Violation `json:"errors"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &problem)
+			if len(problem.Errors) != tc.violations {
+				t.Errorf("got violations %v, want %d", problem.Errors, tc.violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+// Package jsonschema validates request bodies against JSON Schemas attached to routes, for
+// gateways and services that can not share Go types with their clients:
+//
+//	schema := jsonschema.MustCompile(createUserSchema)
+//	rahjoo.NewHandler(createUser, jsonschema.Validate(schema))
+//
+// The validator covers the assertion keywords of JSON Schema 2020-12 used in practice: type,
+// enum, const, the numeric, string, array and object constraints, properties, required,
+// additionalProperties, items, allOf, anyOf, oneOf, not, and $ref to the local document
+// (e.g. "#/$defs/address"). Annotations such as format, title or description are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema. It is safe for concurrent use.
+type Schema struct {
+	always *bool // set for the boolean schemas true and false
+
+	types    []string
+	enum     []any
+	constVal any
+	hasConst bool
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	items              *Schema
+	minItems, maxItems *int
+	uniqueItems        bool
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+
+	ref      string
+	compiler *compiler
+}
+
+// Violation is a part of a document failing its schema.
+type Violation struct {
+	// Path is the JSON Pointer of the offending value, empty for the whole document.
+	Path string `json:"path"`
+	// Message describes the violated constraint.
+	Message string `json:"message"`
+}
+
+// String formats the violation as "path: message".
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	c := &compiler{root: doc, refs: map[string]*Schema{}}
+	s, err := c.compile(doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	// Resolve every reference now, so invalid ones are reported by Compile.
+	for len(c.pending) > 0 {
+		ref := c.pending[0]
+		c.pending = c.pending[1:]
+		if _, err := c.resolve(ref); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics if the schema is invalid.
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate checks a document decoded by encoding/json into an any and returns its violations.
+func (s *Schema) Validate(doc any) []Violation {
+	var violations []Violation
+	s.validate(doc, "", &violations)
+	return violations
+}
+
+type compiler struct {
+	root    any
+	refs    map[string]*Schema
+	pending []string
+}
+
+func (c *compiler) compile(node any, at string) (*Schema, error) {
+	fail := func(format string, args ...any) (*Schema, error) {
+		return nil, fmt.Errorf("jsonschema: %s: %s", at, fmt.Sprintf(format, args...))
+	}
+
+	switch n := node.(type) {
+	case bool:
+		return &Schema{always: &n}, nil
+	case map[string]any:
+		s := &Schema{compiler: c}
+		for key, value := range n {
+			var err error
+			switch key {
+			case "type":
+				switch t := value.(type) {
+				case string:
+					s.types = []string{t}
+				case []any:
+					for _, v := range t {
+						name, ok := v.(string)
+						if !ok {
+							return fail("type must be a string or an array of strings")
+						}
+						s.types = append(s.types, name)
+					}
+				default:
+					return fail("type must be a string or an array of strings")
+				}
+			case "enum":
+				values, ok := value.([]any)
+				if !ok {
+					return fail("enum must be an array")
+				}
+				s.enum = values
+			case "const":
+				s.constVal, s.hasConst = value, true
+			case "minimum":
+				s.minimum, err = number(value)
+			case "maximum":
+				s.maximum, err = number(value)
+			case "exclusiveMinimum":
+				s.exclusiveMinimum, err = number(value)
+			case "exclusiveMaximum":
+				s.exclusiveMaximum, err = number(value)
+			case "multipleOf":
+				s.multipleOf, err = number(value)
+				if err == nil && *s.multipleOf <= 0 {
+					err = errors.New("must be greater than 0")
+				}
+			case "minLength":
+				s.minLength, err = count(value)
+			case "maxLength":
+				s.maxLength, err = count(value)
+			case "pattern":
+				p, ok := value.(string)
+				if !ok {
+					return fail("pattern must be a string")
+				}
+				if s.pattern, err = regexp.Compile(p); err != nil {
+					return fail("invalid pattern: %v", err)
+				}
+			case "items":
+				s.items, err = c.compile(value, at+"/items")
+			case "minItems":
+				s.minItems, err = count(value)
+			case "maxItems":
+				s.maxItems, err = count(value)
+			case "uniqueItems":
+				s.uniqueItems, _ = value.(bool)
+			case "properties":
+				props, ok := value.(map[string]any)
+				if !ok {
+					return fail("properties must be an object")
+				}
+				s.properties = make(map[string]*Schema, len(props))
+				for name, prop := range props {
+					if s.properties[name], err = c.compile(prop, at+"/properties/"+escape(name)); err != nil {
+						return nil, err
+					}
+				}
+			case "required":
+				names, ok := value.([]any)
+				if !ok {
+					return fail("required must be an array of strings")
+				}
+				for _, v := range names {
+					name, ok := v.(string)
+					if !ok {
+						return fail("required must be an array of strings")
+					}
+					s.required = append(s.required, name)
+				}
+			case "additionalProperties":
+				s.additionalProperties, err = c.compile(value, at+"/additionalProperties")
+			case "minProperties":
+				s.minProperties, err = count(value)
+			case "maxProperties":
+				s.maxProperties, err = count(value)
+			case "allOf", "anyOf", "oneOf":
+				list, ok := value.([]any)
+				if !ok || len(list) == 0 {
+					return fail("%s must be a non-empty array", key)
+				}
+				schemas := make([]*Schema, len(list))
+				for i, sub := range list {
+					if schemas[i], err = c.compile(sub, at+"/"+key+"/"+strconv.Itoa(i)); err != nil {
+						return nil, err
+					}
+				}
+				switch key {
+				case "allOf":
+					s.allOf = schemas
+				case "anyOf":
+					s.anyOf = schemas
+				default:
+					s.oneOf = schemas
+				}
+			case "not":
+				s.not, err = c.compile(value, at+"/not")
+			case "$ref":
+				ref, ok := value.(string)
+				if !ok || !strings.HasPrefix(ref, "#") {
+					return fail("only local references are supported, got %v", value)
+				}
+				s.ref = ref
+				c.pending = append(c.pending, ref)
+			}
+			if err != nil {
+				if strings.HasPrefix(err.Error(), "jsonschema:") {
+					return nil, err
+				}
+				return fail("%s %v", key, err)
+			}
+		}
+		return s, nil
+	}
+	return fail("schema must be an object or a boolean")
+}
+
+// resolve returns the schema referenced by a local JSON Pointer reference, compiling it once.
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if s, ok := c.refs[ref]; ok {
+		return s, nil
+	}
+	node := c.root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer != "" {
+		for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			switch n := node.(type) {
+			case map[string]any:
+				var ok bool
+				if node, ok = n[token]; !ok {
+					return nil, fmt.Errorf("jsonschema: unresolvable reference %q", ref)
+				}
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(n) {
+					return nil, fmt.Errorf("jsonschema: unresolvable reference %q", ref)
+				}
+				node = n[i]
+			default:
+				return nil, fmt.Errorf("jsonschema: unresolvable reference %q", ref)
+			}
+		}
+	}
+	s, err := c.compile(node, ref)
+	if err != nil {
+		return nil, err
+	}
+	c.refs[ref] = s
+	return s, nil
+}
+
+func number(v any) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, errors.New("must be a number")
+	}
+	return &f, nil
+}
+
+func count(v any) (*int, error) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+// escape escapes a JSON Pointer reference token.
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func (s *Schema) validate(v any, path string, violations *[]Violation) {
+	add := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			add("no value is allowed")
+		}
+		return
+	}
+	if s.ref != "" {
+		// References were resolved by Compile, so the lookup can not fail.
+		ref, _ := s.compiler.resolve(s.ref)
+		ref.validate(v, path, violations)
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(v, t) }) {
+		add("must be of type %s", strings.Join(s.types, " or "))
+		return
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return equal(e, v) }) {
+		add("must be one of %s", mustJSON(s.enum))
+	}
+	if s.hasConst && !equal(s.constVal, v) {
+		add("must be %s", mustJSON(s.constVal))
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			add("must be greater than or equal to %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			add("must be less than or equal to %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			add("must be greater than %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			add("must be less than %v", *s.exclusiveMaximum)
+		}
+		if s.multipleOf != nil {
+			if q := v / *s.multipleOf; q != math.Trunc(q) {
+				add("must be a multiple of %v", *s.multipleOf)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			add("must be at least %d characters long", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			add("must be at most %d characters long", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			add("must match pattern %q", s.pattern.String())
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			add("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			add("must have at most %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+		unique:
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if equal(v[i], v[j]) {
+						add("items must be unique")
+						break unique
+					}
+				}
+			}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, path+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Path: path + "/" + escape(name), Message: "is required"})
+			}
+		}
+		if s.minProperties != nil && len(v) < *s.minProperties {
+			add("must have at least %d properties", *s.minProperties)
+		}
+		if s.maxProperties != nil && len(v) > *s.maxProperties {
+			add("must have at most %d properties", *s.maxProperties)
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				prop.validate(v[name], path+"/"+escape(name), violations)
+			} else if s.additionalProperties != nil {
+				if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+					*violations = append(*violations, Violation{Path: path + "/" + escape(name), Message: "is not allowed"})
+					continue
+				}
+				s.additionalProperties.validate(v[name], path+"/"+escape(name), violations)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, violations)
+	}
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(sub *Schema) bool { return sub.valid(v) }) {
+		add("must match at least one of the anyOf schemas")
+	}
+	if s.oneOf != nil {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(v) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			add("must match exactly one of the oneOf schemas, matched %d", matches)
+		}
+	}
+	if s.not != nil && s.not.valid(v) {
+		add("must not match the not schema")
+	}
+}
+
+func (s *Schema) valid(v any) bool {
+	return len(s.Validate(v)) == 0
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
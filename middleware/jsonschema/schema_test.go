@@ -0,0 +1,93 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware/jsonschema"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["name", "email"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "maxLength": 10},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"age": {"type": "integer", "minimum": 18, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2, "uniqueItems": true},
+		"address": {"$ref": "#/$defs/address"},
+		"contact": {"oneOf": [{"required": ["phone"]}, {"required": ["fax"]}]},
+		"score": {"type": ["number", "null"], "multipleOf": 0.5}
+	},
+	"$defs": {
+		"address": {
+			"type": "object",
+			"required": ["city"],
+			"properties": {"city": {"type": "string"}, "next": {"$ref": "#/$defs/address"}}
+		}
+	}
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(userSchema))
+
+	testCases := []struct {
+		name string
+		doc  string
+		want []jsonschema.Violation
+	}{
+		{"valid", `{"name":"amir","email":"a@b.c","age":30,"role":"admin","tags":["x"],"address":{"city":"Tehran","next":{"city":"Shiraz"}},"contact":{"phone":"1"},"score":1.5}`, nil},
+		{"null_allowed", `{"name":"amir","email":"a@b.c","score":null}`, nil},
+		{"not_an_object", `[]`, []jsonschema.Violation{{Path: "", Message: "must be of type object"}}},
+		{"missing_required", `{"name":"amir"}`, []jsonschema.Violation{{Path: "/email", Message: "is required"}}},
+		{"additional_property", `{"name":"amir","email":"a@b.c","admin":true}`, []jsonschema.Violation{{Path: "/admin", Message: "is not allowed"}}},
+		{"constraints", `{"name":"a","email":"nope","age":17.5,"role":"root","tags":["x","x","y"],"score":0.3}`, []jsonschema.Violation{
+			{Path: "/age", Message: "must be of type integer"},
+			{Path: "/email", Message: `must match pattern "^[^@]+@[^@]+$"`},
+			{Path: "/name", Message: "must be at least 2 characters long"},
+			{Path: "/role", Message: `must be one of ["admin","user"]`},
+			{Path: "/score", Message: "must be a multiple of 0.5"},
+			{Path: "/tags", Message: "must have at most 2 items"},
+			{Path: "/tags", Message: "items must be unique"},
+		}},
+		{"nested_ref", `{"name":"amir","email":"a@b.c","address":{"next":{"city":1}}}`, []jsonschema.Violation{
+			{Path: "/address/city", Message: "is required"},
+			{Path: "/address/next/city", Message: "must be of type string"},
+		}},
+		{"one_of", `{"name":"amir","email":"a@b.c","contact":{"phone":"1","fax":"2"}}`, []jsonschema.Violation{
+			{Path: "/contact", Message: "must match exactly one of the oneOf schemas, matched 2"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tc.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := schema.Validate(doc); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got violations %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, schema := range []string{
+		`not json`,
+		`"string"`,
+		`{"type": 1}`,
+		`{"pattern": "("}`,
+		`{"minLength": -1}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"properties": {"a": {"anyOf": []}}}`,
+	} {
+		if _, err := jsonschema.Compile([]byte(schema)); err == nil {
+			t.Errorf("expected error compiling %s", schema)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWT validation errors.
+var (
+	ErrJWTMissing     = errors.New("token is missing")
+	ErrJWTMalformed   = errors.New("token is malformed")
+	ErrJWTAlgorithm   = errors.New("token signing algorithm is not allowed")
+	ErrJWTSignature   = errors.New("token signature is invalid")
+	ErrJWTExpired     = errors.New("token is expired")
+	ErrJWTNotValidYet = errors.New("token is not valid yet")
+	ErrJWTIssuer      = errors.New("token issuer is not accepted")
+	ErrJWTAudience    = errors.New("token audience is not accepted")
+	ErrJWTKeyNotFound = errors.New("token signing key not found")
+	ErrJWTInvalidKey  = errors.New("key is invalid for the token signing algorithm")
+)
+
+// Claims are the claims of a JSON Web Token.
+type Claims map[string]any
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string { return c.String("sub") }
+
+// Issuer returns the "iss" claim.
+func (c Claims) Issuer() string { return c.String("iss") }
+
+// Audience returns the "aud" claim, which may be a single string or a list.
+func (c Claims) Audience() []string { return c.Strings("aud") }
+
+// Scopes returns the OAuth 2.0 scopes of the token, from the space-separated "scope" claim or,
+// as some providers issue it, the "scp" claim, which may also be a list.
+func (c Claims) Scopes() []string {
+	var scopes []string
+	for _, name := range []string{"scope", "scp"} {
+		for _, s := range c.Strings(name) {
+			scopes = append(scopes, strings.Fields(s)...)
+		}
+	}
+	return scopes
+}
+
+// Roles returns the "roles" claim, which may be a single string or a list.
+func (c Claims) Roles() []string { return c.Strings("roles") }
+
+// Strings returns the claim name if it is a string or a list of strings, skipping elements of
+// other types.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			if e, ok := e.(string); ok {
+				s = append(s, e)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+// String returns the claim name if it is a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Time returns the claim name if it is a NumericDate.
+func (c Claims) Time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, frac := int64(v), v-float64(int64(v))
+	return time.Unix(sec, int64(frac*float64(time.Second))), true
+}
+
+// Token is a parsed JSON Web Token.
+type Token struct {
+	// Raw is the compact serialization the token was parsed from.
+	Raw string
+	// Header is the decoded JOSE header.
+	Header map[string]any
+	// Claims are the decoded claims.
+	Claims Claims
+}
+
+// Algorithm returns the "alg" header parameter.
+func (t *Token) Algorithm() string {
+	alg, _ := t.Header["alg"].(string)
+	return alg
+}
+
+// KeyID returns the "kid" header parameter.
+func (t *Token) KeyID() string {
+	kid, _ := t.Header["kid"].(string)
+	return kid
+}
+
+// Keyfunc returns the key verifying the signature of a token, whose header and claims are
+// decoded but not yet trusted: a []byte secret for HS algorithms, an *rsa.PublicKey for RS and
+// PS ones, an *ecdsa.PublicKey for ES ones and an ed25519.PublicKey for EdDSA.
+type Keyfunc func(t *Token) (any, error)
+
+// TokenExtractor extracts a raw token from a request. It returns ErrJWTMissing if there is none.
+type TokenExtractor func(r *http.Request) (string, error)
+
+// FromAuthHeader extracts a bearer token from the Authorization header.
+func FromAuthHeader(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrJWTMissing
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// FromCookie returns a TokenExtractor reading the token from the named cookie.
+func FromCookie(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return "", ErrJWTMissing
+		}
+		return c.Value, nil
+	}
+}
+
+// FromQuery returns a TokenExtractor reading the token from the named query parameter.
+func FromQuery(param string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		if token := r.URL.Query().Get(param); token != "" {
+			return token, nil
+		}
+		return "", ErrJWTMissing
+	}
+}
+
+type jwtConfig struct {
+	algorithms []string
+	extractors []TokenExtractor
+	issuers    []string
+	audiences  []string
+	leeway     time.Duration
+	realm      string
+}
+
+// JWTOption configures the JWT middleware and ParseJWT.
+type JWTOption func(*jwtConfig)
+
+// WithJWTAlgorithms restricts the accepted signing algorithms. By default every supported
+// algorithm is accepted, the key type returned by the Keyfunc still having to match it.
+func WithJWTAlgorithms(algorithms ...string) JWTOption {
+	return func(c *jwtConfig) {
+		c.algorithms = algorithms
+	}
+}
+
+// WithJWTExtractors sets where tokens are looked for, in order. It defaults to FromAuthHeader.
+func WithJWTExtractors(extractors ...TokenExtractor) JWTOption {
+	return func(c *jwtConfig) {
+		c.extractors = extractors
+	}
+}
+
+// WithJWTIssuer only accepts tokens issued by one of issuers.
+func WithJWTIssuer(issuers ...string) JWTOption {
+	return func(c *jwtConfig) {
+		c.issuers = issuers
+	}
+}
+
+// WithJWTAudience only accepts tokens intended for one of audiences.
+func WithJWTAudience(audiences ...string) JWTOption {
+	return func(c *jwtConfig) {
+		c.audiences = audiences
+	}
+}
+
+// WithJWTLeeway tolerates clock skew of up to d when checking exp and nbf.
+func WithJWTLeeway(d time.Duration) JWTOption {
+	return func(c *jwtConfig) {
+		c.leeway = d
+	}
+}
+
+// WithJWTRealm sets the realm of the WWW-Authenticate challenge.
+func WithJWTRealm(realm string) JWTOption {
+	return func(c *jwtConfig) {
+		c.realm = realm
+	}
+}
+
+func newJWTConfig(opts []JWTOption) *jwtConfig {
+	c := &jwtConfig{extractors: []TokenExtractor{FromAuthHeader}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type jwtClaimsKey struct{}
+
+// JWT is a middleware authenticating requests with a JSON Web Token, signed with one of the HS,
+// RS, PS or ES algorithms or EdDSA and verified with the key returned by keyfunc. The exp and nbf
+// claims are always checked, the issuer and audience when configured.
+//
+// The claims are stored in the request context, readable with GetJWTClaims, and the subject as
+// the principal (see GetPrincipal). Requests without a valid token are answered with 401
+// Unauthorized and a WWW-Authenticate challenge as specified by RFC 6750.
+func JWT(keyfunc Keyfunc, opts ...JWTOption) Middleware {
+	c := newJWTConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, err := extractToken(r, c.extractors)
+			if err == nil {
+				var token *Token
+				if token, err = c.parse(raw, keyfunc); err == nil {
+					ctx := context.WithValue(r.Context(), jwtClaimsKey{}, token.Claims)
+					if sub := token.Claims.Subject(); sub != "" {
+						ctx = WithPrincipal(ctx, sub)
+					}
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			challenge := "Bearer"
+			if c.realm != "" {
+				challenge += " realm=" + strconv.Quote(c.realm)
+			}
+			if !errors.Is(err, ErrJWTMissing) {
+				if c.realm != "" {
+					challenge += ","
+				}
+				challenge += ` error="invalid_token", error_description=` + strconv.Quote(err.Error())
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// GetJWTClaims returns the claims of the token authenticated by the JWT middleware.
+func GetJWTClaims(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(Claims)
+	return claims, ok
+}
+
+// ParseJWT parses and validates a compact serialized JSON Web Token like the JWT middleware does.
+func ParseJWT(raw string, keyfunc Keyfunc, opts ...JWTOption) (*Token, error) {
+	return newJWTConfig(opts).parse(raw, keyfunc)
+}
+
+func extractToken(r *http.Request, extractors []TokenExtractor) (string, error) {
+	for _, extract := range extractors {
+		if token, err := extract(r); !errors.Is(err, ErrJWTMissing) {
+			return token, err
+		}
+	}
+	return "", ErrJWTMissing
+}
+
+func (c *jwtConfig) parse(raw string, keyfunc Keyfunc) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+	token := &Token{Raw: raw}
+	if err := decodeJWTSegment(parts[0], &token.Header); err != nil {
+		return nil, err
+	}
+	if err := decodeJWTSegment(parts[1], &token.Claims); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	alg := token.Algorithm()
+	if c.algorithms != nil && !slices.Contains(c.algorithms, alg) {
+		return nil, ErrJWTAlgorithm
+	}
+	key, err := keyfunc(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWS(alg, parts[0]+"."+parts[1], sig, key); err != nil {
+		return nil, err
+	}
+	if err := c.validate(token.Claims); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (c *jwtConfig) validate(claims Claims) error {
+	now := time.Now()
+	if exp, ok := claims.Time("exp"); ok && !now.Before(exp.Add(c.leeway)) {
+		return ErrJWTExpired
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(c.leeway).Before(nbf) {
+		return ErrJWTNotValidYet
+	}
+	if c.issuers != nil && !slices.Contains(c.issuers, claims.Issuer()) {
+		return ErrJWTIssuer
+	}
+	if c.audiences != nil && !slices.ContainsFunc(claims.Audience(), func(aud string) bool {
+		return slices.Contains(c.audiences, aud)
+	}) {
+		return ErrJWTAudience
+	}
+	return nil
+}
+
+func decodeJWTSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+// jwsHashes maps the suffix of JWS algorithm names to their hash function.
+var jwsHashes = map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+
+// verifyJWS checks sig over signingInput with key according to alg (RFC 7518).
+func verifyJWS(alg, signingInput string, sig []byte, key any) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrJWTInvalidKey
+		}
+		if !ed25519.Verify(pub, []byte(signingInput), sig) {
+			return ErrJWTSignature
+		}
+		return nil
+	}
+
+	if len(alg) != 5 {
+		return ErrJWTAlgorithm
+	}
+	hash, ok := jwsHashes[alg[2:]]
+	if !ok {
+		return ErrJWTAlgorithm
+	}
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrJWTInvalidKey
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrJWTSignature
+		}
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTInvalidKey
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return ErrJWTSignature
+		}
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrJWTInvalidKey
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrJWTSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrJWTSignature
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrJWTAlgorithm, alg)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package middleware_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// signJWT builds a compact JWS over claims with alg and key, for testing.
+func signJWT(t *testing.T, alg string, key any, header, claims map[string]any) string {
+	t.Helper()
+	if header == nil {
+		header = map[string]any{}
+	}
+	header["alg"], header["typ"] = alg, "JWT"
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, e := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		sig, err = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...), e
+	case "EdDSA":
+		sig = ed25519.Sign(key.(ed25519.PrivateKey), []byte(input))
+	case "none":
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	keys := map[string]any{"hs": secret, "rs": &rsaKey.PublicKey, "es": &ecKey.PublicKey, "ed": edPub}
+	keyfunc := func(t *middleware.Token) (any, error) {
+		key, ok := keys[t.KeyID()]
+		if !ok {
+			return nil, middleware.ErrJWTKeyNotFound
+		}
+		return key, nil
+	}
+
+	now := time.Now().Unix()
+	valid := map[string]any{"sub": "alice", "iss": "issuer", "aud": []string{"api"}, "exp": now + 60}
+
+	testCases := []struct {
+		name  string
+		token string
+		opts  []middleware.JWTOption
+		err   error
+	}{
+		{"hs256", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, valid), nil, nil},
+		{"rs256", signJWT(t, "RS256", rsaKey, map[string]any{"kid": "rs"}, valid), nil, nil},
+		{"es256", signJWT(t, "ES256", ecKey, map[string]any{"kid": "es"}, valid), nil, nil},
+		{"eddsa", signJWT(t, "EdDSA", edKey, map[string]any{"kid": "ed"}, valid), nil, nil},
+		{"issuer_audience", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, valid),
+			[]middleware.JWTOption{middleware.WithJWTIssuer("issuer"), middleware.WithJWTAudience("other", "api")}, nil},
+		{"expired", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, map[string]any{"exp": now - 10}), nil, middleware.ErrJWTExpired},
+		{"leeway", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, map[string]any{"exp": now - 10}),
+			[]middleware.JWTOption{middleware.WithJWTLeeway(time.Minute)}, nil},
+		{"not_yet_valid", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, map[string]any{"nbf": now + 60}), nil, middleware.ErrJWTNotValidYet},
+		{"bad_signature", signJWT(t, "HS256", []byte("other"), map[string]any{"kid": "hs"}, valid), nil, middleware.ErrJWTSignature},
+		{"none", signJWT(t, "none", nil, map[string]any{"kid": "hs"}, valid), nil, middleware.ErrJWTAlgorithm},
+		{"alg_confusion", signJWT(t, "HS256", []byte("x"), map[string]any{"kid": "rs"}, valid), nil, middleware.ErrJWTInvalidKey},
+		{"disallowed_alg", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, valid),
+			[]middleware.JWTOption{middleware.WithJWTAlgorithms("RS256")}, middleware.ErrJWTAlgorithm},
+		{"wrong_issuer", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, valid),
+			[]middleware.JWTOption{middleware.WithJWTIssuer("someone")}, middleware.ErrJWTIssuer},
+		{"wrong_audience", signJWT(t, "HS256", secret, map[string]any{"kid": "hs"}, valid),
+			[]middleware.JWTOption{middleware.WithJWTAudience("web")}, middleware.ErrJWTAudience},
+		{"unknown_key", signJWT(t, "HS256", secret, map[string]any{"kid": "?"}, valid), nil, middleware.ErrJWTKeyNotFound},
+		{"malformed", "a.b", nil, middleware.ErrJWTMalformed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := middleware.ParseJWT(tc.token, keyfunc, tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && token.Raw != tc.token {
+				t.Errorf("got raw token %q, want %q", token.Raw, tc.token)
+			}
+		})
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	keyfunc := func(*middleware.Token) (any, error) { return secret, nil }
+	token := signJWT(t, "HS256", secret, nil, map[string]any{"sub": "alice", "role": "admin"})
+
+	h := middleware.JWT(keyfunc,
+		middleware.WithJWTRealm("api"),
+		middleware.WithJWTExtractors(middleware.FromAuthHeader, middleware.FromCookie("jwt"), middleware.FromQuery("access_token")),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := middleware.GetJWTClaims(r.Context())
+		principal, _ := middleware.GetPrincipal(r.Context())
+		w.Write([]byte(principal + ":" + claims.String("role")))
+	}))
+
+	testCases := []struct {
+		name      string
+		prepare   func(r *http.Request)
+		status    int
+		challenge string
+	}{
+		{"header", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }, http.StatusOK, ""},
+		{"cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "jwt", Value: token}) }, http.StatusOK, ""},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "access_token=" + token }, http.StatusOK, ""},
+		{"missing", func(r *http.Request) {}, http.StatusUnauthorized, `Bearer realm="api"`},
+		{"invalid", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token+"x") }, http.StatusUnauthorized,
+			`Bearer realm="api", error="invalid_token", error_description="token signature is invalid"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			tc.prepare(req)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tc.challenge {
+				t.Errorf("got WWW-Authenticate %q, want %q", got, tc.challenge)
+			}
+			if tc.status == http.StatusOK && !strings.HasPrefix(rec.Body.String(), "alice:admin") {
+				t.Errorf("got body %q, want %q", rec.Body.String(), "alice:admin")
+			}
+		})
+	}
+}
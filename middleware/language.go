@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type languageKey struct{}
+
+// Language is a middleware negotiating the language of the response from the Accept-Language
+// header among the supported BCP 47 language tags (e.g. "en", "en-GB", "fa"). The chosen tag is
+// stored in the request context, read with GetLanguage, and set as the Content-Language header.
+//
+// Accepted languages are tried by decreasing quality: an exact match wins, then a supported tag
+// sharing the primary language (a request for "en-US" gets "en" or "en-GB"). Requests accepting
+// no supported language, or without Accept-Language, get fallback.
+func Language(supported []string, fallback string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := matchLanguage(r.Header.Values("Accept-Language"), supported, fallback)
+			w.Header().Add("Vary", "Accept-Language")
+			w.Header().Set("Content-Language", lang)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), languageKey{}, lang)))
+		})
+	}
+}
+
+// GetLanguage returns the language tag chosen by the Language middleware, or an empty string.
+func GetLanguage(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// matchLanguage returns the supported language best matching the Accept-Language headers.
+func matchLanguage(accept []string, supported []string, fallback string) string {
+	ranges := parseAccept(strings.Join(accept, ","))
+	slices.SortStableFunc(ranges, func(a, b acceptValue) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	for _, lr := range ranges {
+		if lr.q == 0 || lr.value == "*" {
+			continue
+		}
+		for _, tag := range supported {
+			if strings.EqualFold(tag, lr.value) {
+				return tag
+			}
+		}
+		base := primaryLanguage(lr.value)
+		for _, tag := range supported {
+			if strings.EqualFold(primaryLanguage(tag), base) {
+				return tag
+			}
+		}
+	}
+	return fallback
+}
+
+// primaryLanguage returns the primary language subtag of a language tag, e.g. "en" for "en-US".
+func primaryLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestLanguage(t *testing.T) {
+	var got string
+	h := middleware.Language([]string{"en", "fa", "de-CH"}, "en")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetLanguage(r.Context())
+	}))
+
+	testCases := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"missing", "", "en"},
+		{"exact", "fa", "fa"},
+		{"case_insensitive", "FA", "fa"},
+		{"region_falls_back_to_base", "fa-IR", "fa"},
+		{"base_matches_region", "de", "de-CH"},
+		{"q_values", "de;q=0.5, fa;q=0.8, en;q=0.1", "fa"},
+		{"unsupported_then_supported", "ja, fa;q=0.3", "fa"},
+		{"excluded", "fa;q=0", "en"},
+		{"wildcard", "*", "en"},
+		{"unsupported", "ja, zh", "en"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.accept != "" {
+				req.Header.Set("Accept-Language", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got != tc.want {
+				t.Errorf("got language %q, want %q", got, tc.want)
+			}
+			if rec.Header().Get("Content-Language") != tc.want || rec.Header().Get("Vary") != "Accept-Language" {
+				t.Errorf("got headers %v", rec.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+// redacted replaces the value of redacted log fields.
+const redacted = "[REDACTED]"
+
+type loggerConfig struct {
+	level   func(status int) slog.Level
+	headers []string
+	redact  []string
+}
+
+// LoggerOption configures the Logger middleware.
+type LoggerOption func(*loggerConfig)
+
+// WithLogLevel sets the function choosing the log level from the response status code.
+// By default 5xx responses are logged at slog.LevelError, 4xx at slog.LevelWarn and the rest at slog.LevelInfo.
+func WithLogLevel(level func(status int) slog.Level) LoggerOption {
+	return func(c *loggerConfig) {
+		c.level = level
+	}
+}
+
+// WithLoggedHeaders adds the given request headers to the log entries, grouped under "headers".
+func WithLoggedHeaders(headers ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.headers = append(c.headers, headers...)
+	}
+}
+
+// WithRedactedFields replaces the value of the given fields (e.g. "remote_ip") or logged
+// headers (e.g. "Authorization") with "[REDACTED]".
+func WithRedactedFields(fields ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.redact = append(c.redact, fields...)
+	}
+}
+
+func defaultLogLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Logger is a middleware that logs every request with the given structured logger once it is served.
+// Each entry carries the method, the matched route pattern (when the router matched one), the path,
+// the status code, the number of bytes written, the latency, the request ID and the remote IP.
+func Logger(logger *slog.Logger, opts ...LoggerOption) Middleware {
+	c := loggerConfig{level: defaultLogLevel}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	field := func(key string, value any) slog.Attr {
+		if slices.Contains(c.redact, key) {
+			return slog.String(key, redacted)
+		}
+		return slog.Any(key, value)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, route := routectx.With(r)
+			rw := AcquireResponseWriter(w)
+			defer ReleaseResponseWriter(rw, w)
+
+			next.ServeHTTP(rw, r)
+
+			attrs := []slog.Attr{
+				field("method", r.Method),
+				field("route", route.Path),
+				field("path", r.URL.Path),
+				field("status", rw.Status()),
+				field("bytes", rw.BytesWritten()),
+				field("latency", time.Since(start)),
+				field("request_id", requestIDOf(r)),
+				field("remote_ip", remoteIP(r)),
+			}
+			if len(c.headers) > 0 {
+				headers := make([]any, 0, len(c.headers))
+				for _, h := range c.headers {
+					headers = append(headers, field(h, r.Header.Get(h)))
+				}
+				attrs = append(attrs, slog.Group("headers", headers...))
+			}
+			logger.LogAttrs(context.WithoutCancel(r.Context()), c.level(rw.Status()), "request", attrs...)
+		})
+	}
+}
+
+// requestIDOf returns the request ID set by the RequestID middleware, falling back to the
+// X-Request-Id header when the logger runs before it.
+func requestIDOf(r *http.Request) string {
+	if id := GetRequestID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
+
+// remoteIP returns the IP part of r.RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
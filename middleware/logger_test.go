@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux, rahjoo.Route{
+		"/users/{id}": {http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("hello"))
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := middleware.Logger(logger,
+		middleware.WithLoggedHeaders("Authorization", "User-Agent"),
+		middleware.WithRedactedFields("Authorization", "remote_ip"),
+	)(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", http.NoBody)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		Level     string
+		Method    string
+		Route     string
+		Path      string
+		Status    int
+		Bytes     int
+		RequestID string `json:"request_id"`
+		RemoteIP  string `json:"remote_ip"`
+		Headers   map[string]string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Level != "WARN" {
+		t.Errorf("got level %q, want %q", entry.Level, "WARN")
+	}
+	if entry.Method != http.MethodGet || entry.Route != "/users/{id}" || entry.Path != "/users/42" {
+		t.Errorf("got method %q, route %q, path %q", entry.Method, entry.Route, entry.Path)
+	}
+	if entry.Status != http.StatusTeapot || entry.Bytes != 5 {
+		t.Errorf("got status %d, bytes %d, want %d, %d", entry.Status, entry.Bytes, http.StatusTeapot, 5)
+	}
+	if entry.RequestID != "abc" {
+		t.Errorf("got request id %q, want %q", entry.RequestID, "abc")
+	}
+	if entry.RemoteIP != "[REDACTED]" || entry.Headers["Authorization"] != "[REDACTED]" {
+		t.Errorf("got remote ip %q, authorization %q, want them redacted", entry.RemoteIP, entry.Headers["Authorization"])
+	}
+	if entry.Headers["User-Agent"] != "test" {
+		t.Errorf("got user agent %q, want %q", entry.Headers["User-Agent"], "test")
+	}
+}
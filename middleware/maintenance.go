@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default Retry-After of the Maintenance middleware.
+const defaultMaintenanceRetryAfter = 5 * time.Minute
+
+type maintenanceConfig struct {
+	retryAfter  time.Duration
+	contentType string
+	body        []byte
+
+	allowed                   prefixes
+	bypassHeader, bypassValue string
+}
+
+// MaintenanceOption configures the Maintenance middleware.
+type MaintenanceOption func(*maintenanceConfig)
+
+// WithMaintenanceRetryAfter sets the Retry-After advertised to clients. It defaults to 5 minutes.
+func WithMaintenanceRetryAfter(d time.Duration) MaintenanceOption {
+	return func(c *maintenanceConfig) {
+		c.retryAfter = d
+	}
+}
+
+// WithMaintenanceBody sets the body served during maintenance, e.g. an HTML page or a JSON error.
+func WithMaintenanceBody(contentType string, body []byte) MaintenanceOption {
+	return func(c *maintenanceConfig) {
+		c.contentType, c.body = contentType, body
+	}
+}
+
+// WithMaintenanceAllowedIPs lets clients from the given CIDR ranges or IPs through during
+// maintenance, e.g. the office network verifying a deployment. It panics if a range is malformed.
+func WithMaintenanceAllowedIPs(cidrs ...string) MaintenanceOption {
+	allowed := mustParsePrefixes("maintenance bypass IP", cidrs)
+	return func(c *maintenanceConfig) {
+		c.allowed = append(c.allowed, allowed...)
+	}
+}
+
+// WithMaintenanceBypassHeader lets requests carrying header set to secret through during maintenance.
+func WithMaintenanceBypassHeader(header, secret string) MaintenanceOption {
+	return func(c *maintenanceConfig) {
+		c.bypassHeader, c.bypassValue = header, secret
+	}
+}
+
+// Maintenance is a middleware answering requests with 503 Service Unavailable and a Retry-After
+// header while enabled reports true. enabled is called on every request, so maintenance can be
+// toggled at runtime, e.g. from an atomic.Bool flipped by a deployment hook.
+//
+// Like IPFilter, it reads the client IP from r.RemoteAddr, so it should come after RealIP when
+// allowing IPs behind proxies.
+func Maintenance(enabled func() bool, opts ...MaintenanceOption) Middleware {
+	c := maintenanceConfig{
+		retryAfter:  defaultMaintenanceRetryAfter,
+		contentType: "text/plain; charset=utf-8",
+		body:        []byte("service under maintenance, please retry later\n"),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	retryAfter := strconv.Itoa(max(1, int(math.Ceil(c.retryAfter.Seconds()))))
+
+	bypass := func(r *http.Request) bool {
+		if c.bypassHeader != "" {
+			if v := r.Header.Get(c.bypassHeader); v != "" && SecureCompare(v, c.bypassValue) {
+				return true
+			}
+		}
+		if len(c.allowed) > 0 {
+			ip, err := parseNodeIP(remoteIP(r))
+			return err == nil && c.allowed.contains(ip)
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled() || bypass(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", c.contentType)
+			w.Header().Set("Retry-After", retryAfter)
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(c.body)
+		})
+	}
+}
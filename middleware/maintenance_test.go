@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestMaintenance(t *testing.T) {
+	var enabled atomic.Bool
+	h := middleware.Maintenance(enabled.Load,
+		middleware.WithMaintenanceRetryAfter(90*time.Second),
+		middleware.WithMaintenanceBody("application/json", []byte(`{"error":"maintenance"}`)),
+		middleware.WithMaintenanceAllowedIPs("10.0.0.0/8"),
+		middleware.WithMaintenanceBypassHeader("X-Maintenance-Bypass", "letmein"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	testCases := []struct {
+		name       string
+		enabled    bool
+		remoteAddr string
+		bypass     string
+		status     int
+		body       string
+	}{
+		{"disabled", false, "203.0.113.9:1234", "", http.StatusOK, "ok"},
+		{"enabled", true, "203.0.113.9:1234", "", http.StatusServiceUnavailable, `{"error":"maintenance"}`},
+		{"allowed_ip", true, "10.0.0.1:1234", "", http.StatusOK, "ok"},
+		{"bypass_header", true, "203.0.113.9:1234", "letmein", http.StatusOK, "ok"},
+		{"wrong_secret", true, "203.0.113.9:1234", "guess", http.StatusServiceUnavailable, `{"error":"maintenance"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enabled.Store(tc.enabled)
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.bypass != "" {
+				req.Header.Set("X-Maintenance-Bypass", tc.bypass)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status || rec.Body.String() != tc.body {
+				t.Fatalf("got %d %q, want %d %q", rec.Code, rec.Body, tc.status, tc.body)
+			}
+			if tc.status == http.StatusServiceUnavailable {
+				if got := rec.Header().Get("Retry-After"); got != "90" {
+					t.Errorf("got Retry-After %q, want %q", got, "90")
+				}
+				if got := rec.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("got Content-Type %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { order = append(order, "handler") })
+
+	shared := []middleware.Middleware{trace("first"), trace("second"), trace("third")}
+	want := []string{"first", "second", "third", "handler"}
+
+	// The shared slice is chained twice: a chain reordering it would break the second one.
+	for i := range 2 {
+		order = nil
+		middleware.Chain(handler, shared...).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		if !slices.Equal(order, want) {
+			t.Errorf("chain %d: got order %v, want %v", i, order, want)
+		}
+	}
+
+	order = nil
+	shared[0](http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if !slices.Equal(order, []string{"first"}) {
+		t.Errorf("Chain reordered the middlewares slice: its first element is %v", order)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	handler := http.NotFoundHandler()
+	rec := httptest.NewRecorder()
+	middleware.Chain(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+type multipartKey struct{}
+
+// Bounds of the default limit of multipart request bodies: room for this many files of the
+// maximum size, plus the other fields.
+const (
+	defaultMultipartMaxFiles  = 10
+	defaultMultipartMaxFields = 1 << 20
+)
+
+type multipartConfig struct {
+	maxBody int64
+}
+
+// MultipartOption configures the Multipart middleware.
+type MultipartOption func(*multipartConfig)
+
+// WithMultipartMaxBody limits the size of the whole request body, which is read, beyond
+// maxMemory into temporary files, before the size of each file is checked. It defaults to ten
+// times maxFileSize plus 1 MiB; a negative n removes the limit.
+func WithMultipartMaxBody(n int64) MultipartOption {
+	return func(c *multipartConfig) {
+		c.maxBody = n
+	}
+}
+
+// Multipart is a middleware parsing multipart/form-data request bodies up front, keeping up to
+// maxMemory bytes of file parts in memory and the rest in temporary files removed once the
+// handler returns. Every file must be at most maxFileSize bytes and, if allowedTypes is not
+// empty, its content type, sniffed from its first bytes rather than trusted from the client,
+// must be one of allowedTypes (e.g. "image/png", "image/*").
+//
+// Requests of another content type get 415 Unsupported Media Type, malformed bodies 400 Bad
+// Request, bodies over the limit set by WithMultipartMaxBody and files too large 413 Content
+// Too Large and files of another type 415, all as problem details. Use GetMultipartForm to read
+// the parsed form.
+func Multipart(maxMemory, maxFileSize int64, allowedTypes []string, opts ...MultipartOption) Middleware {
+	c := multipartConfig{maxBody: -1}
+	if maxFileSize <= (math.MaxInt64-defaultMultipartMaxFields)/defaultMultipartMaxFiles {
+		c.maxBody = defaultMultipartMaxFiles*maxFileSize + defaultMultipartMaxFields
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	allowed := make([]string, len(allowedTypes))
+	for i, t := range allowedTypes {
+		allowed[i] = strings.ToLower(t)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mt != "multipart/form-data" {
+				problem.Write(w, http.StatusUnsupportedMediaType, problem.WithDetail("Content-Type header must be multipart/form-data"))
+				return
+			}
+			if c.maxBody >= 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, c.maxBody)
+			}
+			if err := r.ParseMultipartForm(maxMemory); err != nil {
+				if r.MultipartForm != nil {
+					r.MultipartForm.RemoveAll()
+				}
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					problem.Write(w, http.StatusRequestEntityTooLarge, problem.WithDetail("request body too large"))
+					return
+				}
+				problem.Write(w, http.StatusBadRequest, problem.WithDetail("malformed multipart body"))
+				return
+			}
+			form := r.MultipartForm
+			defer form.RemoveAll()
+
+			for field, files := range form.File {
+				for _, fh := range files {
+					if fh.Size > maxFileSize {
+						problem.Write(w, http.StatusRequestEntityTooLarge,
+							problem.WithDetail("file "+field+" is too large"))
+						return
+					}
+					if len(allowed) == 0 {
+						continue
+					}
+					mt, err := sniffFile(fh)
+					if err != nil {
+						problem.Write(w, http.StatusBadRequest, problem.WithDetail("malformed multipart body"))
+						return
+					}
+					if !mediaTypeAllowed(allowed, mt) {
+						problem.Write(w, http.StatusUnsupportedMediaType,
+							problem.WithDetail("file "+field+" has unsupported type "+mt))
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), multipartKey{}, form)))
+		})
+	}
+}
+
+// GetMultipartForm returns the form parsed by the Multipart middleware, or nil if there is none.
+// The files are removed once the handler returns.
+func GetMultipartForm(ctx context.Context) *multipart.Form {
+	form, _ := ctx.Value(multipartKey{}).(*multipart.Form)
+	return form
+}
+
+// sniffFile returns the media type of the file detected from its first 512 bytes.
+func sniffFile(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	mt, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mt, nil
+}
@@ -0,0 +1,99 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+func multipartBody(t *testing.T, files map[string][]byte) (io.Reader, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "avatar")
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestMultipart(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		form := middleware.GetMultipartForm(r.Context())
+		if form == nil {
+			t.Error("expected parsed form in context")
+			return
+		}
+		w.Write([]byte(form.Value["title"][0]))
+	})
+
+	testCases := []struct {
+		name   string
+		opts   []middleware.MultipartOption
+		files  map[string][]byte
+		ctype  string
+		status int
+	}{
+		{"allowed", nil, map[string][]byte{"avatar": pngHeader}, "", http.StatusOK},
+		{"no_files", nil, nil, "", http.StatusOK},
+		{"sniffed_type", nil, map[string][]byte{"avatar": []byte("<html><body>hi</body></html>")}, "", http.StatusUnsupportedMediaType},
+		{"file_too_large", nil, map[string][]byte{"avatar": append(pngHeader, make([]byte, 1024)...)}, "", http.StatusRequestEntityTooLarge},
+		{"body_too_large", []middleware.MultipartOption{middleware.WithMultipartMaxBody(64)}, map[string][]byte{"avatar": pngHeader}, "", http.StatusRequestEntityTooLarge},
+		{"not_multipart", nil, nil, "application/json", http.StatusUnsupportedMediaType},
+		{"malformed", nil, nil, "multipart/form-data; boundary=x", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, ctype := multipartBody(t, tc.files)
+			if tc.ctype != "" {
+				body, ctype = strings.NewReader("garbage"), tc.ctype
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", body)
+			req.Header.Set("Content-Type", ctype)
+			rec := httptest.NewRecorder()
+			middleware.Multipart(1<<10, 512, []string{"image/*"}, tc.opts...)(handler).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMultipartAnyType(t *testing.T) {
+	body, ctype := multipartBody(t, map[string][]byte{"doc": []byte("plain text")})
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", ctype)
+	rec := httptest.NewRecorder()
+	middleware.Multipart(1<<10, 512, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMultipartDefaultMaxBody(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", strings.Repeat("a", 2<<20))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	middleware.Multipart(1<<10, 512, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
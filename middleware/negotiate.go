@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+type mediaTypeKey struct{}
+
+// Negotiate is a middleware choosing, among the offered media types in order of server
+// preference (e.g. "application/json", "application/xml"), the one the client prefers according
+// to the q-values of its Accept header. The choice is stored in the request context, read with
+// GetMediaType; the render package serializes responses to it.
+//
+// Requests without an Accept header get the first offered type. Requests accepting none of the
+// offered types are answered with 406 Not Acceptable.
+func Negotiate(offered ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			mediaType, ok := NegotiateMediaType(r.Header.Values("Accept"), offered)
+			if !ok {
+				problem.Write(w, http.StatusNotAcceptable,
+					problem.WithDetail("acceptable media types: "+strings.Join(offered, ", ")))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), mediaTypeKey{}, mediaType)))
+		})
+	}
+}
+
+// GetMediaType returns the media type chosen by the Negotiate middleware, or an empty string.
+func GetMediaType(ctx context.Context) string {
+	mediaType, _ := ctx.Value(mediaTypeKey{}).(string)
+	return mediaType
+}
+
+// NegotiateMediaType returns the offered media type with the highest quality in the Accept
+// header values, preferring earlier offers on ties, as the Negotiate middleware does. Without
+// Accept header, the first offered type is returned. ok is false if no offer is acceptable.
+func NegotiateMediaType(accept []string, offered []string) (mediaType string, ok bool) {
+	if len(offered) == 0 {
+		return "", false
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+	if len(ranges) == 0 {
+		return offered[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		if q := mediaTypeQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaTypeQuality returns the quality of offer given by the most specific matching media range.
+func mediaTypeQuality(ranges []acceptValue, offer string) float64 {
+	offer, _, _ = mime.ParseMediaType(offer)
+	typ, sub, _ := strings.Cut(offer, "/")
+
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		rtyp, rsub, _ := strings.Cut(strings.ToLower(ar.value), "/")
+		var s int
+		switch {
+		case rtyp == typ && rsub == sub:
+			s = 2
+		case rtyp == typ && rsub == "*":
+			s = 1
+		case rtyp == "*" && rsub == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
+
+// acceptValue is an element of an Accept-style header with its quality.
+type acceptValue struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses a header of comma-separated values with optional parameters and q-values,
+// such as Accept or Accept-Language. Parameters other than q are dropped.
+func parseAccept(header string) []acceptValue {
+	var values []acceptValue
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+		values = append(values, acceptValue{value: value, q: q})
+	}
+	return values
+}
@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestNegotiate(t *testing.T) {
+	var got string
+	h := middleware.Negotiate("application/json", "application/xml", "text/plain")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetMediaType(r.Context())
+	}))
+
+	testCases := []struct {
+		name   string
+		accept string
+		want   string
+		status int
+	}{
+		{"no_accept", "", "application/json", http.StatusOK},
+		{"exact", "application/xml", "application/xml", http.StatusOK},
+		{"wildcard", "*/*", "application/json", http.StatusOK},
+		{"subtype_wildcard", "text/*", "text/plain", http.StatusOK},
+		{"q_values", "application/json;q=0.5, application/xml;q=0.9", "application/xml", http.StatusOK},
+		{"specific_overrides_wildcard", "application/*;q=0.8, application/json;q=0.1", "application/xml", http.StatusOK},
+		{"excluded", "application/json;q=0, */*;q=0.1", "application/xml", http.StatusOK},
+		{"parameters", "text/html, application/xml;charset=utf-8;q=0.7", "application/xml", http.StatusOK},
+		{"tie_prefers_offer_order", "application/xml, application/json", "application/json", http.StatusOK},
+		{"not_acceptable", "image/png", "", http.StatusNotAcceptable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status || got != tc.want {
+				t.Errorf("got %d %q, want %d %q", rec.Code, got, tc.status, tc.want)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Errorf("got Vary %q, want Accept", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
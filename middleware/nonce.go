@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NonceHeader is the default header carrying the nonce of a request.
+const NonceHeader = "X-Nonce"
+
+// Bounds on the length of accepted nonces.
+const (
+	minNonceLength = 16
+	maxNonceLength = 256
+)
+
+type nonceConfig struct {
+	header string
+}
+
+// NonceOption configures the RequireNonce middleware.
+type NonceOption func(*nonceConfig)
+
+// WithNonceHeader sets the header carrying the nonce. It defaults to NonceHeader.
+func WithNonceHeader(header string) NonceOption {
+	return func(c *nonceConfig) {
+		c.header = header
+	}
+}
+
+// RequireNonce is a middleware rejecting requests whose nonce, a unique value of 16 to 256
+// characters chosen by the client, was already used within window, protecting endpoints such as
+// payment callbacks from replayed requests. Nonces are remembered in store per principal (see
+// GetPrincipal), so clients can not exhaust each other's nonces.
+//
+// Requests without a valid nonce are answered with 400 Bad Request, replays with 409 Conflict
+// and requests the store fails to check with 503 Service Unavailable. Behind VerifySignature,
+// the nonce must be covered by the signature, e.g. by sending it in the body or the URL, for an
+// attacker not to replace it; VerifySignature's own replay cache protects the signature instead.
+// window should not be shorter than the time requests are otherwise accepted for, such as the
+// clock skew of VerifySignature.
+func RequireNonce(store ReplayCache, window time.Duration, opts ...NonceOption) Middleware {
+	c := nonceConfig{header: NonceHeader}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := r.Header.Get(c.header)
+			if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+				http.Error(w, "missing or invalid nonce", http.StatusBadRequest)
+				return
+			}
+			principal, _ := GetPrincipal(r.Context())
+			seen, err := store.Seen(r.Context(), principal+"\n"+nonce, time.Now().Add(window))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if seen {
+				http.Error(w, "nonce already used", http.StatusConflict)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// replayCacheSweepInterval is how often expired entries are dropped from a MemoryReplayCache.
+const replayCacheSweepInterval = time.Minute
+
+// MemoryReplayCache is a ReplayCache keeping values in memory, for RequireNonce and
+// VerifySignature in single instance services. It is safe for concurrent use and drops expired
+// values periodically.
+type MemoryReplayCache struct {
+	mu        sync.Mutex
+	expires   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryReplayCache creates an empty MemoryReplayCache.
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{expires: map[string]time.Time{}}
+}
+
+// Seen implements ReplayCache.
+func (c *MemoryReplayCache) Seen(_ context.Context, value string, expires time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= replayCacheSweepInterval {
+		c.lastSweep = now
+		for v, exp := range c.expires {
+			if now.After(exp) {
+				delete(c.expires, v)
+			}
+		}
+	}
+
+	if exp, ok := c.expires[value]; ok && !now.After(exp) {
+		return true, nil
+	}
+	c.expires[value] = expires
+	return false, nil
+}
@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRequireNonce(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := middleware.RequireNonce(middleware.NewMemoryReplayCache(), time.Minute)(ok)
+
+	nonce := "b2f1c7e0-6a4d-4a53-9d1e-0c6f7d2b8e11"
+	testCases := []struct {
+		name      string
+		principal string
+		nonce     string
+		status    int
+	}{
+		{"first", "shop-1", nonce, http.StatusOK},
+		{"replay", "shop-1", nonce, http.StatusConflict},
+		{"other_principal", "shop-2", nonce, http.StatusOK},
+		{"new_nonce", "shop-1", nonce + "-2", http.StatusOK},
+		{"missing", "shop-1", "", http.StatusBadRequest},
+		{"too_short", "shop-1", "123", http.StatusBadRequest},
+		{"too_long", "shop-1", strings.Repeat("a", 257), http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/callbacks/payment", http.NoBody)
+			req.Header.Set(middleware.NonceHeader, tc.nonce)
+			req = req.WithContext(middleware.WithPrincipal(req.Context(), tc.principal))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+type failingReplayCache struct{}
+
+func (failingReplayCache) Seen(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func TestRequireNonceOptions(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.Header.Set("Idempotency-Nonce", "0123456789abcdef")
+
+	rec := httptest.NewRecorder()
+	middleware.RequireNonce(middleware.NewMemoryReplayCache(), time.Minute, middleware.WithNonceHeader("Idempotency-Nonce"))(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.RequireNonce(failingReplayCache{}, time.Minute, middleware.WithNonceHeader("Idempotency-Nonce"))(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	c := middleware.NewMemoryReplayCache()
+	ctx := context.Background()
+	if seen, _ := c.Seen(ctx, "a", time.Now().Add(time.Minute)); seen {
+		t.Error("got a new value seen")
+	}
+	if seen, _ := c.Seen(ctx, "a", time.Now().Add(time.Minute)); !seen {
+		t.Error("got a used value not seen")
+	}
+
+	c.Seen(ctx, "b", time.Now().Add(-time.Second))
+	if seen, _ := c.Seen(ctx, "b", time.Now().Add(time.Minute)); seen {
+		t.Error("got an expired value seen")
+	}
+}
@@ -0,0 +1,429 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the JWKS key cache.
+const (
+	defaultJWKSCacheTTL        = time.Hour
+	defaultJWKSRefreshInterval = time.Minute
+	defaultJWKSFetchTimeout    = 10 * time.Second
+)
+
+// JWKS fetches and caches the JSON Web Key Set published by an OIDC provider. Its Keyfunc method
+// resolves the signing key of a token by key ID, for use with the JWT middleware. Keys are
+// refetched when the cache expires, or when a token names an unknown key ID, at most once per
+// refresh interval so forged key IDs can not make it hammer the provider.
+type JWKS struct {
+	url             string
+	client          *http.Client
+	ttl             time.Duration
+	refreshInterval time.Duration
+	fetchTimeout    time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+	// fetching is the fetch in progress, shared by the lookups waiting for it.
+	fetching *jwksFetch
+}
+
+// jwksFetch is a fetch of the key set, done when done is closed.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// JWKSOption configures a JWKS.
+type JWKSOption func(*JWKS)
+
+// WithJWKSClient sets the HTTP client the key set is fetched with. It defaults to http.DefaultClient.
+func WithJWKSClient(client *http.Client) JWKSOption {
+	return func(j *JWKS) {
+		j.client = client
+	}
+}
+
+// WithJWKSCacheTTL sets how long fetched keys are used before being refetched. It defaults to an hour.
+func WithJWKSCacheTTL(ttl time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.ttl = ttl
+	}
+}
+
+// WithJWKSRefreshInterval sets the minimum interval between two fetches. It defaults to a minute.
+func WithJWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.refreshInterval = d
+	}
+}
+
+// WithJWKSFetchTimeout bounds the duration of a fetch of the key set. It defaults to 10 seconds.
+func WithJWKSFetchTimeout(d time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.fetchTimeout = d
+	}
+}
+
+// NewJWKS creates a JWKS fetching the key set from url on first use.
+func NewJWKS(url string, opts ...JWKSOption) *JWKS {
+	j := &JWKS{
+		url:             url,
+		client:          http.DefaultClient,
+		ttl:             defaultJWKSCacheTTL,
+		refreshInterval: defaultJWKSRefreshInterval,
+		fetchTimeout:    defaultJWKSFetchTimeout,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Keyfunc is a Keyfunc returning the key named by the token's kid header parameter.
+// If the token has no key ID and the set holds a single key, that key is returned.
+//
+// A single fetch runs at a time, without blocking lookups of keys already known; lookups of
+// unknown keys wait for it.
+func (j *JWKS) Keyfunc(t *Token) (any, error) {
+	j.mu.Lock()
+	now := time.Now()
+	key, ok := j.lookup(t.KeyID())
+	if ok && now.Sub(j.fetchedAt) <= j.ttl {
+		j.mu.Unlock()
+		return key, nil
+	}
+	call, leader := j.fetching, false
+	if call == nil {
+		if now.Sub(j.fetchedAt) < j.refreshInterval {
+			j.mu.Unlock()
+			return keyResult(key, ok, nil)
+		}
+		j.fetchedAt = now
+		call, leader = &jwksFetch{done: make(chan struct{})}, true
+		j.fetching = call
+	}
+	j.mu.Unlock()
+
+	if leader {
+		ctx, cancel := context.WithTimeout(context.Background(), j.fetchTimeout)
+		keys, err := j.fetch(ctx)
+		cancel()
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.fetching = nil
+		j.mu.Unlock()
+		call.err = err
+		close(call.done)
+	} else if ok {
+		// Keep using the expired key while another lookup refreshes the set.
+		return key, nil
+	} else {
+		<-call.done
+	}
+
+	j.mu.Lock()
+	newKey, found := j.lookup(t.KeyID())
+	j.mu.Unlock()
+	if found {
+		return newKey, nil
+	}
+	return keyResult(key, ok, call.err)
+}
+
+// keyResult returns key if ok, or else err or ErrJWTKeyNotFound.
+func keyResult(key any, ok bool, err error) (any, error) {
+	switch {
+	case ok:
+		return key, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, ErrJWTKeyNotFound
+}
+
+func (j *JWKS) lookup(kid string) (any, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// jwk is a JSON Web Key (RFC 7517) holding a public key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch returns the keys currently published.
+func (j *JWKS) fetch(ctx context.Context) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, j.client, j.url, &set); err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// keys of unsupported types are skipped, as RFC 7517 requires.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// OIDC discovers the OpenID Connect provider at issuer and returns a JWT middleware accepting
+// access tokens signed with the provider's published keys, issued by issuer and intended for
+// audience. Further options, e.g. WithJWTExtractors, are passed to JWT.
+func OIDC(ctx context.Context, issuer, audience string, opts ...JWTOption) (Middleware, error) {
+	var config struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	discovery := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, http.DefaultClient, discovery, &config); err != nil {
+		return nil, fmt.Errorf("discover OIDC provider: %w", err)
+	}
+	if config.Issuer != issuer {
+		return nil, fmt.Errorf("discover OIDC provider: issuer %q does not match %q", config.Issuer, issuer)
+	}
+
+	jwks := NewJWKS(config.JWKSURI)
+	opts = append([]JWTOption{
+		WithJWTIssuer(issuer),
+		WithJWTAudience(audience),
+		WithJWTAlgorithms("RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"),
+	}, opts...)
+	return JWT(jwks.Keyfunc, opts...), nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+type introspectionConfig struct {
+	client     *http.Client
+	cacheTTL   time.Duration
+	extractors []TokenExtractor
+}
+
+// IntrospectionOption configures the Introspection middleware.
+type IntrospectionOption func(*introspectionConfig)
+
+// WithIntrospectionClient sets the HTTP client the endpoint is called with.
+// It defaults to http.DefaultClient.
+func WithIntrospectionClient(client *http.Client) IntrospectionOption {
+	return func(c *introspectionConfig) {
+		c.client = client
+	}
+}
+
+// WithIntrospectionCacheTTL caches the introspection result of each active token for up to ttl,
+// never beyond the token's expiry. Inactive tokens are not cached, so random tokens can not
+// fill the cache. Caching is disabled by default.
+func WithIntrospectionCacheTTL(ttl time.Duration) IntrospectionOption {
+	return func(c *introspectionConfig) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithIntrospectionExtractors sets where tokens are looked for, in order. It defaults to FromAuthHeader.
+func WithIntrospectionExtractors(extractors ...TokenExtractor) IntrospectionOption {
+	return func(c *introspectionConfig) {
+		c.extractors = extractors
+	}
+}
+
+// introspectionSweepInterval is how often expired results are dropped from the introspection
+// cache.
+const introspectionSweepInterval = time.Minute
+
+type introspectionEntry struct {
+	claims  Claims
+	expires time.Time
+}
+
+// Introspection is a middleware validating opaque access tokens against an OAuth 2.0 token
+// introspection endpoint (RFC 7662), authenticating to it with clientID and clientSecret.
+// The introspection response of an active token is stored in the request context, readable with
+// GetJWTClaims, and its subject as the principal. Inactive tokens are rejected like the JWT
+// middleware does; failures to reach the endpoint are answered with 503 Service Unavailable.
+func Introspection(endpoint, clientID, clientSecret string, opts ...IntrospectionOption) Middleware {
+	c := introspectionConfig{client: http.DefaultClient, extractors: []TokenExtractor{FromAuthHeader}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var (
+		mu        sync.Mutex
+		cache     = map[string]introspectionEntry{}
+		lastSweep time.Time
+	)
+
+	introspect := func(ctx context.Context, token string) (Claims, error) {
+		now := time.Now()
+		mu.Lock()
+		entry, ok := cache[token]
+		if ok && now.After(entry.expires) {
+			delete(cache, token)
+			ok = false
+		}
+		mu.Unlock()
+		if ok {
+			return entry.claims, nil
+		}
+
+		form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+		res, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("introspection: unexpected status %s", res.Status)
+		}
+		var claims Claims
+		if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+			return nil, err
+		}
+
+		if active, _ := claims["active"].(bool); active && c.cacheTTL > 0 {
+			expires := now.Add(c.cacheTTL)
+			if exp, ok := claims.Time("exp"); ok && exp.Before(expires) {
+				expires = exp
+			}
+			mu.Lock()
+			if now.Sub(lastSweep) >= introspectionSweepInterval {
+				lastSweep = now
+				for t, e := range cache {
+					if now.After(e.expires) {
+						delete(cache, t)
+					}
+				}
+			}
+			cache[token] = introspectionEntry{claims: claims, expires: expires}
+			mu.Unlock()
+		}
+		return claims, nil
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := extractToken(r, c.extractors)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			claims, err := introspect(r.Context(), token)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if active, _ := claims["active"].(bool); !active {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description=`+strconv.Quote("token is not active"))
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), jwtClaimsKey{}, claims)
+			if sub := claims.Subject(); sub != "" {
+				ctx = WithPrincipal(ctx, sub)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
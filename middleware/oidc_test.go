@@ -0,0 +1,184 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestOIDC(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var jwksFetches atomic.Int32
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": srv.URL, "jwks_uri": srv.URL + "/jwks"})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwksFetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	auth, err := middleware.OIDC(context.Background(), srv.URL, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := middleware.GetPrincipal(r.Context())
+		w.Write([]byte(principal))
+	}))
+
+	exp := time.Now().Add(time.Minute).Unix()
+	testCases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid", signJWT(t, "RS256", key, map[string]any{"kid": "k1"}, map[string]any{"sub": "alice", "iss": srv.URL, "aud": "api", "exp": exp}), http.StatusOK},
+		{"wrong_audience", signJWT(t, "RS256", key, map[string]any{"kid": "k1"}, map[string]any{"sub": "alice", "iss": srv.URL, "aud": "web", "exp": exp}), http.StatusUnauthorized},
+		{"wrong_issuer", signJWT(t, "RS256", key, map[string]any{"kid": "k1"}, map[string]any{"sub": "alice", "iss": "evil", "aud": "api", "exp": exp}), http.StatusUnauthorized},
+		{"unknown_kid", signJWT(t, "RS256", key, map[string]any{"kid": "k2"}, map[string]any{"sub": "alice", "iss": srv.URL, "aud": "api", "exp": exp}), http.StatusUnauthorized},
+		{"hmac", signJWT(t, "HS256", []byte("x"), map[string]any{"kid": "k1"}, map[string]any{"sub": "alice", "iss": srv.URL, "aud": "api", "exp": exp}), http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+
+	if n := jwksFetches.Load(); n != 1 {
+		t.Errorf("fetched the key set %d times, want 1", n)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if user, pass, _ := r.BasicAuth(); user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		active := r.PostFormValue("token") == "good"
+		json.NewEncoder(w).Encode(map[string]any{"active": active, "sub": "alice", "scope": "read"})
+	}))
+	defer srv.Close()
+
+	h := middleware.Introspection(srv.URL, "client", "secret", middleware.WithIntrospectionCacheTTL(time.Minute))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := middleware.GetJWTClaims(r.Context())
+			w.Write([]byte(claims.Subject() + ":" + claims.String("scope")))
+		}))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for range 2 {
+		if rec := serve("good"); rec.Code != http.StatusOK || rec.Body.String() != "alice:read" {
+			t.Errorf("got status code %d, body %q, want %d, %q", rec.Code, rec.Body.String(), http.StatusOK, "alice:read")
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("called the endpoint %d times, want 1 thanks to caching", n)
+	}
+	for range 2 {
+		if rec := serve("revoked"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("got status code %d for inactive token, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("called the endpoint %d times, want 3 as inactive tokens are not cached", n)
+	}
+	if rec := serve(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status code %d for missing token, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestJWKSFetch(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	jwks := middleware.NewJWKS(srv.URL, middleware.WithJWKSRefreshInterval(0), middleware.WithJWKSFetchTimeout(300*time.Millisecond))
+	token := func(kid string) *middleware.Token {
+		return &middleware.Token{Header: map[string]any{"kid": kid}}
+	}
+	if _, err := jwks.Keyfunc(token("k1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second fetch hangs: concurrent lookups of unknown keys share it until it times out,
+	// while lookups of known keys do not wait for it.
+	errs := make(chan error, 5)
+	for range cap(errs) {
+		go func() {
+			_, err := jwks.Keyfunc(token("k2"))
+			errs <- err
+		}()
+	}
+	for fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	if _, err := jwks.Keyfunc(token("k1")); err != nil {
+		t.Errorf("got error %v for a known key during a fetch", err)
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("looking up a known key took %v during a fetch", d)
+	}
+	for range cap(errs) {
+		if err := <-errs; err == nil {
+			t.Error("got no error for an unknown key after a failed fetch")
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetched the key set %d times, want 2", n)
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/amirzayi/rahjoo/middleware/otelmetric
+
+go 1.23.0
+
+require (
+	github.com/amirzayi/rahjoo v0.0.0
+	go.opentelemetry.io/otel v1.31.0
+	go.opentelemetry.io/otel/metric v1.31.0
+	go.opentelemetry.io/otel/sdk/metric v1.31.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.31.0 // indirect
+	go.opentelemetry.io/otel/trace v1.31.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
+
+// The module is developed against the rahjoo module of this repository. Require a tagged
+// release of rahjoo, and drop this replacement, when publishing it.
+replace github.com/amirzayi/rahjoo => ../..
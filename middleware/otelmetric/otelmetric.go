@@ -0,0 +1,129 @@
+// Package otelmetric provides a middleware recording HTTP server metrics with OpenTelemetry,
+// following the HTTP semantic conventions, for services on the OpenTelemetry stack.
+//
+// It lives in its own module so the router stays free of dependencies:
+//
+//	go get github.com/amirzayi/rahjoo/middleware/otelmetric
+package otelmetric
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// ScopeName is the instrumentation scope of the recorded metrics.
+const ScopeName = "github.com/amirzayi/rahjoo/middleware/otelmetric"
+
+// durationBuckets are the bucket boundaries advised by the semantic conventions, in seconds.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+type config struct {
+	attrs []attribute.KeyValue
+}
+
+// Option configures the Metrics middleware.
+type Option func(*config)
+
+// WithAttributes adds attributes to every measurement, e.g. server.address.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// Metrics returns a middleware recording the http.server.request.duration histogram and the
+// http.server.active_requests up-down counter with meters of provider, the global
+// MeterProvider if nil.
+//
+// Durations carry the http.request.method, http.route, http.response.status_code, url.scheme
+// and network.protocol.version attributes, plus error.type for server errors. http.route is the
+// matched route pattern (see rahjoo.RoutePattern), omitted if none matched. Active requests
+// carry http.request.method and url.scheme.
+func Metrics(provider metric.MeterProvider, opts ...Option) (middleware.Middleware, error) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	meter := provider.Meter(ScopeName)
+
+	duration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	active, err := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP server requests."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			activeAttrs := metric.WithAttributeSet(attribute.NewSet(append([]attribute.KeyValue{
+				attribute.String("http.request.method", methodAttr(r.Method)),
+				attribute.String("url.scheme", scheme),
+			}, c.attrs...)...))
+			active.Add(ctx, 1, activeAttrs)
+			defer active.Add(ctx, -1, activeAttrs)
+
+			r = rahjoo.TrackRoute(r)
+			rw := middleware.AcquireResponseWriter(w)
+			defer middleware.ReleaseResponseWriter(rw, w)
+			next.ServeHTTP(rw, r)
+
+			attrs := append([]attribute.KeyValue{
+				attribute.String("http.request.method", methodAttr(r.Method)),
+				attribute.Int("http.response.status_code", rw.Status()),
+				attribute.String("url.scheme", scheme),
+				attribute.String("network.protocol.version", protocolVersion(r)),
+			}, c.attrs...)
+			if route, _ := rahjoo.RoutePattern(r); route != "" {
+				attrs = append(attrs, attribute.String("http.route", string(route)))
+			}
+			if rw.Status() >= http.StatusInternalServerError {
+				attrs = append(attrs, attribute.String("error.type", strconv.Itoa(rw.Status())))
+			}
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributeSet(attribute.NewSet(attrs...)))
+		})
+	}, nil
+}
+
+// methodAttr maps non-standard methods to "_OTHER", as the semantic conventions require.
+func methodAttr(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "_OTHER"
+}
+
+// protocolVersion returns the HTTP version of r, e.g. "1.1" or "2".
+func protocolVersion(r *http.Request) string {
+	if r.ProtoMinor == 0 && r.ProtoMajor > 1 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
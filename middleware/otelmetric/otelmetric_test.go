@@ -0,0 +1,111 @@
+package otelmetric_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware/otelmetric"
+)
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	metrics, err := otelmetric.Metrics(provider, otelmetric.WithAttributes(attribute.String("server.address", "api")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var activeDuringRequest int64
+	mux := http.NewServeMux()
+	err = rahjoo.BindRoutesToMux(mux, rahjoo.Route{
+		"/users/{id}": {
+			http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+				var rm metricdata.ResourceMetrics
+				reader.Collect(r.Context(), &rm)
+				activeDuringRequest = sum(t, rm)
+			}),
+		},
+		"/boom": {
+			"": rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := metrics(mux)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", http.NoBody),
+		httptest.NewRequest(http.MethodGet, "/users/2", http.NoBody),
+		httptest.NewRequest("PURGE", "/boom", http.NoBody),
+		httptest.NewRequest(http.MethodGet, "/nope", http.NoBody),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if activeDuringRequest != 1 {
+		t.Errorf("got %d active requests while serving, want 1", activeDuringRequest)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	if got := sum(t, rm); got != 0 {
+		t.Errorf("got %d active requests after serving, want 0", got)
+	}
+
+	counts := map[string]uint64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name != "http.server.request.duration" {
+			continue
+		}
+		if m.Unit != "s" {
+			t.Errorf("got unit %q, want s", m.Unit)
+		}
+		for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+			route, _ := dp.Attributes.Value("http.route")
+			method, _ := dp.Attributes.Value("http.request.method")
+			status, _ := dp.Attributes.Value("http.response.status_code")
+			errType, _ := dp.Attributes.Value("error.type")
+			if addr, _ := dp.Attributes.Value("server.address"); addr.AsString() != "api" {
+				t.Errorf("missing server.address attribute in %v", dp.Attributes)
+			}
+			counts[method.AsString()+" "+route.AsString()+" "+status.Emit()+" "+errType.AsString()] += dp.Count
+		}
+	}
+	want := map[string]uint64{
+		"GET /users/{id} 200 ": 2,
+		"_OTHER /boom 502 502": 1,
+		"GET  404 ":            1,
+	}
+	for k, v := range want {
+		if counts[k] != v {
+			t.Errorf("got %d measurements for %q, want %d (all: %v)", counts[k], k, v, counts)
+		}
+	}
+}
+
+// sum returns the total of the http.server.active_requests data points.
+func sum(t *testing.T, rm metricdata.ResourceMetrics) int64 {
+	t.Helper()
+	var n int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "http.server.active_requests" {
+				for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+					n += dp.Value
+				}
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal, such as a JWT subject
+// or an API key ID. Authentication middlewares store the principal with it so that other
+// middlewares, e.g. RateLimit with KeyByPrincipal, can act per identity.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// GetPrincipal returns the authenticated principal stored in ctx, if any.
+func GetPrincipal(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// KeyByPrincipal returns a KeyFunc grouping requests by authenticated principal, enabling
+// per-user quotas. Anonymous requests are grouped by fallback (e.g. KeyByIP) instead.
+// Keys are prefixed so a principal can never share a key with an anonymous client.
+func KeyByPrincipal(fallback KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		if principal, ok := GetPrincipal(r.Context()); ok {
+			return "principal:" + principal
+		}
+		return "anonymous:" + fallback(r)
+	}
+}
+
+// KeyByHeader returns a KeyFunc grouping requests by the value of the given header, e.g. an API
+// key. Requests without the header are grouped by fallback (e.g. KeyByIP) instead, so they do not
+// all share a key. Keys are prefixed as by KeyByPrincipal.
+func KeyByHeader(header string, fallback KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(header); v != "" {
+			return "header:" + v
+		}
+		return "anonymous:" + fallback(r)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestKeyByPrincipal(t *testing.T) {
+	// authenticate simulates an upstream auth middleware storing the principal.
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(middleware.WithPrincipal(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := middleware.Chain(ok,
+		authenticate,
+		middleware.RateLimit(0, 1, middleware.WithRateLimitKey(middleware.KeyByPrincipal(middleware.KeyByIP))),
+	)
+
+	testCases := []struct {
+		name   string
+		user   string
+		status int
+	}{
+		{"alice", "alice", http.StatusOK},
+		{"bob_same_ip", "bob", http.StatusOK},
+		{"anonymous", "", http.StatusOK},
+		{"alice_again", "alice", http.StatusTooManyRequests},
+		{"anonymous_again", "", http.StatusTooManyRequests},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("X-User", tc.user)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestKeyByHeader(t *testing.T) {
+	key := middleware.KeyByHeader("X-Api-Key", middleware.KeyByIP)
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("X-Api-Key", "key-1")
+	if got := key(req); got != "header:key-1" {
+		t.Errorf("got key %q, want %q", got, "header:key-1")
+	}
+	// httptest requests come from 192.0.2.1.
+	if got := key(httptest.NewRequest(http.MethodGet, "/", http.NoBody)); got != "anonymous:192.0.2.1" {
+		t.Errorf("got key %q without the header, want %q", got, "anonymous:192.0.2.1")
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/amirzayi/rahjoo/middleware/prometheus
+
+go 1.23.0
+
+require (
+	github.com/amirzayi/rahjoo v0.0.0
+	github.com/prometheus/client_golang v1.20.5
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+// The module is developed against the rahjoo module of this repository. Require a tagged
+// release of rahjoo, and drop this replacement, when publishing it.
+replace github.com/amirzayi/rahjoo => ../..
@@ -0,0 +1,172 @@
+// Package prometheus provides a middleware exporting HTTP server metrics to Prometheus.
+//
+// It lives in its own module so the router stays free of dependencies:
+//
+//	go get github.com/amirzayi/rahjoo/middleware/prometheus
+package prometheus
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// unmatchedRoute labels requests no route matched, e.g. 404s for scanned paths.
+const unmatchedRoute = "unmatched"
+
+type config struct {
+	namespace, subsystem string
+	durationBuckets      []float64
+	sizeBuckets          []float64
+	constLabels          prom.Labels
+}
+
+// Option configures the Metrics middleware.
+type Option func(*config)
+
+// WithNamespace sets the namespace prefixed to the metric names.
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithSubsystem sets the subsystem prefixed to the metric names, after the namespace.
+func WithSubsystem(subsystem string) Option {
+	return func(c *config) {
+		c.subsystem = subsystem
+	}
+}
+
+// WithDurationBuckets sets the buckets of the request duration histogram, in seconds.
+// It defaults to prometheus.DefBuckets.
+func WithDurationBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.durationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets sets the buckets of the response size histogram, in bytes.
+// It defaults to exponential buckets from 100 bytes to 100 MB.
+func WithSizeBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.sizeBuckets = buckets
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, e.g. the service name.
+func WithConstLabels(labels prom.Labels) Option {
+	return func(c *config) {
+		c.constLabels = labels
+	}
+}
+
+// Metrics returns a middleware exporting the following metrics, registered with registerer
+// (prometheus.DefaultRegisterer if nil):
+//
+//   - http_requests_total: counter of served requests.
+//   - http_request_duration_seconds: histogram of request latencies.
+//   - http_response_size_bytes: histogram of response body sizes.
+//   - http_requests_in_flight: gauge of requests being served.
+//
+// All but the gauge are labeled by method, route and code, where route is the matched route
+// pattern (see rahjoo.RoutePattern), "unmatched" if none matched, and code the status class
+// (e.g. "2xx"), keeping cardinality bounded. Wrap the whole mux to also count unmatched requests.
+// It panics if the metrics can not be registered, like prometheus.MustRegister.
+func Metrics(registerer prom.Registerer, opts ...Option) middleware.Middleware {
+	c := config{
+		durationBuckets: prom.DefBuckets,
+		sizeBuckets:     prom.ExponentialBuckets(100, 10, 7),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if registerer == nil {
+		registerer = prom.DefaultRegisterer
+	}
+
+	labels := []string{"method", "route", "code"}
+	requests := prom.NewCounterVec(prom.CounterOpts{
+		Namespace:   c.namespace,
+		Subsystem:   c.subsystem,
+		Name:        "http_requests_total",
+		Help:        "Total number of HTTP requests served.",
+		ConstLabels: c.constLabels,
+	}, labels)
+	duration := prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace:   c.namespace,
+		Subsystem:   c.subsystem,
+		Name:        "http_request_duration_seconds",
+		Help:        "Duration of HTTP requests in seconds.",
+		ConstLabels: c.constLabels,
+		Buckets:     c.durationBuckets,
+	}, labels)
+	size := prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace:   c.namespace,
+		Subsystem:   c.subsystem,
+		Name:        "http_response_size_bytes",
+		Help:        "Size of HTTP response bodies in bytes.",
+		ConstLabels: c.constLabels,
+		Buckets:     c.sizeBuckets,
+	}, labels)
+	inFlight := prom.NewGauge(prom.GaugeOpts{
+		Namespace:   c.namespace,
+		Subsystem:   c.subsystem,
+		Name:        "http_requests_in_flight",
+		Help:        "Number of HTTP requests being served.",
+		ConstLabels: c.constLabels,
+	})
+	registerer.MustRegister(requests, duration, size, inFlight)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			r = rahjoo.TrackRoute(r)
+			rw := middleware.AcquireResponseWriter(w)
+			defer middleware.ReleaseResponseWriter(rw, w)
+			next.ServeHTTP(rw, r)
+
+			route, _ := rahjoo.RoutePattern(r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			values := []string{methodLabel(r.Method), string(route), strconv.Itoa(rw.Status()/100) + "xx"}
+			requests.WithLabelValues(values...).Inc()
+			duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			size.WithLabelValues(values...).Observe(float64(rw.BytesWritten()))
+		})
+	}
+}
+
+// Route returns a route serving the metrics of gatherer (prometheus.DefaultGatherer if nil)
+// on GET path, usually "/metrics".
+func Route(path rahjoo.Path, gatherer prom.Gatherer) rahjoo.Route {
+	if gatherer == nil {
+		gatherer = prom.DefaultGatherer
+	}
+	h := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	return rahjoo.Route{
+		path: {
+			http.MethodGet: rahjoo.NewHandler(h.ServeHTTP),
+		},
+	}
+}
+
+// methodLabel maps non-standard methods to "OTHER" so clients can not blow up the label set.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,72 @@
+package prometheus_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prom.NewRegistry()
+	metrics := prometheus.Metrics(registry, prometheus.WithNamespace("app"))
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux,
+		rahjoo.Route{
+			"/users/{id}": {
+				http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("user"))
+				}),
+			},
+		},
+		prometheus.Route("/metrics", registry),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := metrics(mux)
+
+	for _, target := range []string{"/users/1", "/users/2", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	}
+	req := httptest.NewRequest("PURGE", "/users/1", http.NoBody)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := `
+# HELP app_http_requests_total Total number of HTTP requests served.
+# TYPE app_http_requests_total counter
+app_http_requests_total{code="2xx",method="GET",route="/users/{id}"} 2
+app_http_requests_total{code="4xx",method="GET",route="unmatched"} 1
+app_http_requests_total{code="4xx",method="OTHER",route="unmatched"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_http_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(registry, "app_http_request_duration_seconds", "app_http_response_size_bytes"); n != 6 {
+		t.Errorf("got %d histogram series, want 6", n)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app_http_requests_in_flight 1") {
+		t.Errorf("got %d from /metrics:\n%s", rec.Code, rec.Body)
+	}
+}
+
+func TestMetricsRegisterTwice(t *testing.T) {
+	registry := prom.NewRegistry()
+	prometheus.Metrics(registry)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic registering the metrics twice")
+		}
+	}()
+	prometheus.Metrics(registry)
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QuotaPeriod is the calendar period a quota budget is granted for.
+type QuotaPeriod int
+
+const (
+	// Daily budgets are renewed every day at midnight.
+	Daily QuotaPeriod = iota
+	// Monthly budgets are renewed on the first day of every month at midnight.
+	Monthly
+)
+
+// bounds returns the start and end of the period containing t, in the location of t.
+func (p QuotaPeriod) bounds(t time.Time) (start, end time.Time) {
+	y, m, d := t.Date()
+	if p == Monthly {
+		start = time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start = time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// QuotaStore counts the requests made against quotas. Implementations backed by Redis or a
+// database allow several instances of a service to share budgets.
+type QuotaStore interface {
+	// Increment adds a request to the count of key, which identifies both the client and the
+	// period, and returns the new count. The count may be dropped after expires.
+	Increment(ctx context.Context, key string, expires time.Time) (int64, error)
+}
+
+type quotaConfig struct {
+	key      KeyFunc
+	budget   func(r *http.Request, key string) int64
+	store    QuotaStore
+	location *time.Location
+}
+
+// QuotaOption configures the Quota middleware.
+type QuotaOption func(*quotaConfig)
+
+// WithQuotaKey sets the function requests are grouped by, e.g. the tenant. It defaults to
+// KeyByPrincipal(KeyByIP), the API key or user for authenticated requests.
+func WithQuotaKey(key KeyFunc) QuotaOption {
+	return func(c *quotaConfig) {
+		c.key = key
+	}
+}
+
+// WithQuotaBudget sets the function returning the budget of key, e.g. from its subscription
+// plan, instead of the budget passed to Quota. A negative budget means unlimited.
+func WithQuotaBudget(budget func(r *http.Request, key string) int64) QuotaOption {
+	return func(c *quotaConfig) {
+		c.budget = budget
+	}
+}
+
+// WithQuotaStore sets the store counting requests. It defaults to a new in-memory store created
+// by NewMemoryQuotaStore.
+func WithQuotaStore(store QuotaStore) QuotaOption {
+	return func(c *quotaConfig) {
+		c.store = store
+	}
+}
+
+// WithQuotaLocation sets the time zone periods start at midnight in. It defaults to UTC.
+func WithQuotaLocation(loc *time.Location) QuotaOption {
+	return func(c *quotaConfig) {
+		c.location = loc
+	}
+}
+
+// Quota is a middleware granting each key (the principal by default) a budget of requests per
+// calendar period, e.g. 10000 requests a month for an API key. Unlike RateLimit, which smooths
+// traffic over seconds or minutes, it enforces long-term usage such as billing plans; both are
+// typically combined.
+//
+// Responses carry the X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset headers; requests over
+// budget are answered with 429 Too Many Requests and a Retry-After header until the period ends.
+// If the store fails the request is let through.
+func Quota(budget int64, period QuotaPeriod, opts ...QuotaOption) Middleware {
+	c := quotaConfig{
+		key:      KeyByPrincipal(KeyByIP),
+		budget:   func(*http.Request, string) int64 { return budget },
+		location: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.store == nil {
+		c.store = NewMemoryQuotaStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := c.key(r)
+			limit := c.budget(r, key)
+			if limit < 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now().In(c.location)
+			start, end := period.bounds(now)
+			count, err := c.store.Increment(r.Context(), key+"@"+start.Format(time.DateOnly), end)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-Quota-Limit", strconv.FormatInt(limit, 10))
+			h.Set("X-Quota-Remaining", strconv.FormatInt(max(0, limit-count), 10))
+			h.Set("X-Quota-Reset", strconv.FormatInt(end.Unix(), 10))
+			if count > limit {
+				h.Set("Retry-After", strconv.Itoa(int(math.Ceil(end.Sub(now).Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// quotaSweepInterval is how often expired counts are dropped from a MemoryQuotaStore.
+const quotaSweepInterval = time.Hour
+
+// MemoryQuotaStore is a QuotaStore keeping counts in memory, lost when the process restarts. It
+// is safe for concurrent use and drops expired counts periodically.
+type MemoryQuotaStore struct {
+	mu        sync.Mutex
+	entries   map[string]*quotaEntry
+	lastSweep time.Time
+}
+
+type quotaEntry struct {
+	count   int64
+	expires time.Time
+}
+
+// NewMemoryQuotaStore creates an empty MemoryQuotaStore.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{entries: map[string]*quotaEntry{}}
+}
+
+// Increment implements QuotaStore.
+func (s *MemoryQuotaStore) Increment(_ context.Context, key string, expires time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= quotaSweepInterval {
+		s.lastSweep = now
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &quotaEntry{expires: expires}
+		s.entries[key] = e
+	}
+	e.count++
+	return e.count, nil
+}
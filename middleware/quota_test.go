@@ -0,0 +1,123 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestQuota(t *testing.T) {
+	plans := map[string]int64{"principal:free": 1, "principal:pro": 3, "principal:internal": -1}
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := middleware.Quota(2, middleware.Daily, middleware.WithQuotaBudget(func(_ *http.Request, key string) int64 {
+		if budget, ok := plans[key]; ok {
+			return budget
+		}
+		return 2
+	}))(ok)
+
+	serve := func(principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		if principal != "" {
+			req = req.WithContext(middleware.WithPrincipal(req.Context(), principal))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	testCases := []struct {
+		name      string
+		principal string
+		status    int
+		remaining string
+	}{
+		{"free", "free", http.StatusOK, "0"},
+		{"free_exhausted", "free", http.StatusTooManyRequests, "0"},
+		{"pro", "pro", http.StatusOK, "2"},
+		{"pro_again", "pro", http.StatusOK, "1"},
+		{"anonymous", "", http.StatusOK, "1"},
+		{"anonymous_again", "", http.StatusOK, "0"},
+		{"anonymous_exhausted", "", http.StatusTooManyRequests, "0"},
+		{"unlimited", "internal", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.principal)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("X-Quota-Remaining"); got != tc.remaining {
+				t.Errorf("got remaining %q, want %q", got, tc.remaining)
+			}
+		})
+	}
+
+	rec := serve("free")
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if got, want := rec.Header().Get("X-Quota-Reset"), strconv.FormatInt(midnight.Unix(), 10); got != want {
+		t.Errorf("got X-Quota-Reset %s, want %s", got, want)
+	}
+	if got, _ := strconv.Atoi(rec.Header().Get("Retry-After")); got < 1 || got > 86400 {
+		t.Errorf("got Retry-After %d, want until midnight", got)
+	}
+}
+
+func TestQuotaMonthly(t *testing.T) {
+	loc := time.FixedZone("UTC+3:30", 7*30*60)
+	h := middleware.Quota(5, middleware.Monthly, middleware.WithQuotaLocation(loc))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	now := time.Now().In(loc)
+	next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, loc)
+	if got, want := rec.Header().Get("X-Quota-Reset"), strconv.FormatInt(next.Unix(), 10); got != want {
+		t.Errorf("got X-Quota-Reset %s, want %s", got, want)
+	}
+	if got := rec.Header().Get("X-Quota-Limit"); got != "5" {
+		t.Errorf("got X-Quota-Limit %q, want %q", got, "5")
+	}
+}
+
+type failingQuotaStore struct{}
+
+func (failingQuotaStore) Increment(context.Context, string, time.Time) (int64, error) {
+	return 0, errors.New("unavailable")
+}
+
+func TestQuotaStoreFailure(t *testing.T) {
+	h := middleware.Quota(0, middleware.Daily, middleware.WithQuotaStore(failingQuotaStore{}))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMemoryQuotaStore(t *testing.T) {
+	s := middleware.NewMemoryQuotaStore()
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+	for want := int64(1); want <= 3; want++ {
+		if got, _ := s.Increment(ctx, "k", future); got != want {
+			t.Errorf("got count %d, want %d", got, want)
+		}
+	}
+	if got, _ := s.Increment(ctx, "other", future); got != 1 {
+		t.Errorf("got count %d for another key, want 1", got)
+	}
+
+	past := time.Now().Add(-time.Second)
+	s.Increment(ctx, "expired", past)
+	if got, _ := s.Increment(ctx, "expired", past); got != 1 {
+		t.Errorf("got count %d after expiry, want 1", got)
+	}
+}
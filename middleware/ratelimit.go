@@ -0,0 +1,299 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a rate of events per second.
+type Limit float64
+
+// Every converts a minimum interval between events to a Limit.
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Limit(math.Inf(1))
+	}
+	return Limit(float64(time.Second) / float64(interval))
+}
+
+// RateLimitAlgorithm selects how a RateLimitStore counts requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills Burst tokens at Limit per second, allowing bursts while
+	// smoothing the sustained rate. It is the default.
+	TokenBucket RateLimitAlgorithm = iota
+	// FixedWindow allows Burst requests per window of Burst/Limit seconds starting with
+	// the first request. It is cheap, but allows up to twice Burst around window edges.
+	FixedWindow
+	// SlidingWindowLog allows Burst requests in any window of Burst/Limit seconds by
+	// remembering the time of each request. It is exact at the cost of memory per request.
+	SlidingWindowLog
+)
+
+// RateLimitPolicy describes how many requests a single key is allowed to make.
+type RateLimitPolicy struct {
+	// Limit is the sustained rate of requests.
+	Limit Limit
+	// Burst is the maximum number of requests allowed at once.
+	Burst int
+	// Algorithm is the algorithm requests are counted with.
+	Algorithm RateLimitAlgorithm
+}
+
+// window returns the window length of the windowed algorithms, or false if Limit does not
+// allow any refill.
+func (p RateLimitPolicy) window() (time.Duration, bool) {
+	if p.Limit <= 0 {
+		return 0, false
+	}
+	return secondsToDuration(float64(p.Burst) / float64(p.Limit)), true
+}
+
+// RateLimitResult is the outcome of taking a request from a RateLimitStore.
+type RateLimitResult struct {
+	// Allowed reports whether the request is within the limit.
+	Allowed bool
+	// Remaining is the number of requests still allowed right now.
+	Remaining int
+	// Reset is when the key will have its full allowance back.
+	Reset time.Time
+	// RetryAfter is how long to wait before the next request is allowed, when it is not.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the rate limiting state of every key. Implementations backed by Redis
+// or memcached allow several instances of a service to share limits.
+type RateLimitStore interface {
+	// Take records a request for key under policy and reports whether it is allowed.
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// KeyFunc derives the key requests are grouped by, e.g. for rate limiting.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP is a KeyFunc grouping requests by client IP. Combine it with RealIP when the
+// service runs behind proxies.
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+type rateLimitConfig struct {
+	key       KeyFunc
+	store     RateLimitStore
+	algorithm RateLimitAlgorithm
+}
+
+// RateLimitOption configures the RateLimit middleware.
+type RateLimitOption func(*rateLimitConfig)
+
+// WithRateLimitKey sets the function requests are grouped by. It defaults to KeyByIP.
+func WithRateLimitKey(key KeyFunc) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.key = key
+	}
+}
+
+// WithRateLimitStore sets the store keeping the rate limiting state.
+// It defaults to a new in-memory store created by NewMemoryRateLimitStore.
+func WithRateLimitStore(store RateLimitStore) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.store = store
+	}
+}
+
+// WithRateLimitAlgorithm sets the algorithm requests are counted with. It defaults to TokenBucket.
+func WithRateLimitAlgorithm(algorithm RateLimitAlgorithm) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.algorithm = algorithm
+	}
+}
+
+// RateLimit is a middleware allowing each key (the client IP by default) limit requests per second
+// with bursts of up to burst requests. Responses carry the X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset headers; requests over the limit are answered with 429 Too Many Requests
+// and a Retry-After header. If the store fails the request is let through.
+func RateLimit(limit Limit, burst int, opts ...RateLimitOption) Middleware {
+	c := rateLimitConfig{key: KeyByIP}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.store == nil {
+		c.store = NewMemoryRateLimitStore()
+	}
+	policy := RateLimitPolicy{Limit: limit, Burst: burst, Algorithm: c.algorithm}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := c.store.Take(r.Context(), c.key(r), policy)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(burst))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Reset.IsZero() {
+				h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+			}
+			if !res.Allowed {
+				if res.RetryAfter > 0 {
+					h.Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitSweepInterval is how often idle keys are dropped from a MemoryRateLimitStore.
+const rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore is a RateLimitStore keeping the state of every key in memory and
+// supporting all RateLimitAlgorithm values. It is safe for concurrent use and drops idle keys
+// periodically. Policies using different algorithms should not share keys.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+// rateLimitEntry is the state of a single key.
+type rateLimitEntry struct {
+	// tokens and last are the state of the token bucket.
+	tokens float64
+	last   time.Time
+	// start and count are the state of the fixed window.
+	start time.Time
+	count int
+	// log holds request times of the sliding window log, oldest first.
+	log []time.Time
+	// idle is when the entry no longer affects any decision and can be dropped.
+	idle time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: map[string]*rateLimitEntry{}}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(policy.Burst), last: now}
+		s.entries[key] = e
+	}
+
+	var res RateLimitResult
+	switch policy.Algorithm {
+	case FixedWindow:
+		res = e.takeFixedWindow(now, policy)
+	case SlidingWindowLog:
+		res = e.takeSlidingWindowLog(now, policy)
+	default:
+		res = e.takeTokenBucket(now, policy)
+	}
+	e.idle = res.Reset
+	return res, nil
+}
+
+func (e *rateLimitEntry) takeTokenBucket(now time.Time, policy RateLimitPolicy) RateLimitResult {
+	rate, burst := float64(policy.Limit), float64(policy.Burst)
+	if rate > 0 {
+		e.tokens = math.Min(burst, e.tokens+now.Sub(e.last).Seconds()*rate)
+	}
+	e.last = now
+
+	res := RateLimitResult{}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else if rate > 0 {
+		res.RetryAfter = secondsToDuration((1 - e.tokens) / rate)
+	}
+	res.Remaining = int(e.tokens)
+	if rate > 0 {
+		res.Reset = now.Add(secondsToDuration((burst - e.tokens) / rate))
+	}
+	return res
+}
+
+func (e *rateLimitEntry) takeFixedWindow(now time.Time, policy RateLimitPolicy) RateLimitResult {
+	window, refills := policy.window()
+	if e.start.IsZero() || (refills && !now.Before(e.start.Add(window))) {
+		e.start, e.count = now, 0
+	}
+
+	res := RateLimitResult{}
+	if e.count < policy.Burst {
+		e.count++
+		res.Allowed = true
+	}
+	res.Remaining = policy.Burst - e.count
+	if refills {
+		res.Reset = e.start.Add(window)
+		if !res.Allowed {
+			res.RetryAfter = res.Reset.Sub(now)
+		}
+	}
+	return res
+}
+
+func (e *rateLimitEntry) takeSlidingWindowLog(now time.Time, policy RateLimitPolicy) RateLimitResult {
+	window, refills := policy.window()
+	if refills {
+		i := 0
+		for i < len(e.log) && !now.Before(e.log[i].Add(window)) {
+			i++
+		}
+		e.log = e.log[i:]
+	}
+
+	res := RateLimitResult{}
+	if len(e.log) < policy.Burst {
+		e.log = append(e.log, now)
+		res.Allowed = true
+	}
+	res.Remaining = policy.Burst - len(e.log)
+	if refills && len(e.log) > 0 {
+		res.Reset = e.log[len(e.log)-1].Add(window)
+		if !res.Allowed {
+			res.RetryAfter = e.log[0].Add(window).Sub(now)
+		}
+	}
+	return res
+}
+
+// sweep drops entries that have become idle. The caller must hold s.mu.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !e.idle.IsZero() && now.After(e.idle) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if math.IsInf(s, 0) || s > math.MaxInt64/float64(time.Second) {
+		return math.MaxInt64
+	}
+	return time.Duration(s * float64(time.Second))
+}
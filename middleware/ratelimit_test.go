@@ -0,0 +1,156 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := middleware.RateLimit(middleware.Every(time.Minute), 2)(ok)
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := serve("192.0.2.1:1000")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got status code %d, want %d", i, rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != remaining {
+			t.Errorf("request %d: got remaining %q, want %q", i, got, remaining)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: got limit %q, want %q", i, got, "2")
+		}
+	}
+
+	rec := serve("192.0.2.1:2000")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status code %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("got Retry-After %q, want between 1 and 60 seconds", rec.Header().Get("Retry-After"))
+	}
+	if reset, _ := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64); reset <= time.Now().Unix() {
+		t.Errorf("got X-RateLimit-Reset %d, want a future timestamp", reset)
+	}
+
+	if rec := serve("192.0.2.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("got status code %d for another client, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, middleware.RateLimitPolicy) (middleware.RateLimitResult, error) {
+	return middleware.RateLimitResult{}, errors.New("unavailable")
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	t.Run("key", func(t *testing.T) {
+		h := middleware.RateLimit(0, 1, middleware.WithRateLimitKey(func(r *http.Request) string {
+			return r.Header.Get("X-Api-Key")
+		}))(ok)
+
+		for _, tc := range []struct {
+			key    string
+			status int
+		}{
+			{"a", http.StatusOK},
+			{"b", http.StatusOK},
+			{"a", http.StatusTooManyRequests},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("X-Api-Key", tc.key)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("key %q: got status code %d, want %d", tc.key, rec.Code, tc.status)
+			}
+		}
+	})
+
+	t.Run("failing_store", func(t *testing.T) {
+		h := middleware.RateLimit(0, 0, middleware.WithRateLimitStore(failingStore{}))(ok)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		if rec.Code != http.StatusOK {
+			t.Errorf("got status code %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestRateLimitAlgorithms(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name      string
+		algorithm middleware.RateLimitAlgorithm
+	}{
+		{"token_bucket", middleware.TokenBucket},
+		{"fixed_window", middleware.FixedWindow},
+		{"sliding_window_log", middleware.SlidingWindowLog},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := middleware.NewMemoryRateLimitStore()
+			// 3 requests per 60ms window.
+			policy := middleware.RateLimitPolicy{Limit: middleware.Every(20 * time.Millisecond), Burst: 3, Algorithm: tc.algorithm}
+
+			for i := range 3 {
+				res, err := store.Take(ctx, "k", policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !res.Allowed || res.Remaining != 2-i {
+					t.Fatalf("request %d: got allowed %t, remaining %d, want true, %d", i, res.Allowed, res.Remaining, 2-i)
+				}
+			}
+
+			res, _ := store.Take(ctx, "k", policy)
+			if res.Allowed {
+				t.Fatal("got request allowed over the limit")
+			}
+			if res.RetryAfter <= 0 || res.RetryAfter > 60*time.Millisecond {
+				t.Errorf("got retry after %s, want within the window", res.RetryAfter)
+			}
+
+			time.Sleep(res.RetryAfter + 5*time.Millisecond)
+			if res, _ := store.Take(ctx, "k", policy); !res.Allowed {
+				t.Error("got request denied after retry after elapsed")
+			}
+		})
+	}
+
+	t.Run("middleware_option", func(t *testing.T) {
+		ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		h := middleware.RateLimit(middleware.Every(time.Minute), 1, middleware.WithRateLimitAlgorithm(middleware.SlidingWindowLog))(ok)
+
+		var codes []int
+		for range 2 {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			codes = append(codes, rec.Code)
+		}
+		if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+			t.Errorf("got status codes %v, want [200 429]", codes)
+		}
+	})
+}
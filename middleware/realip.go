@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP is a middleware that resolves the client IP from the Forwarded, X-Forwarded-For or
+// X-Real-IP headers, in this order of preference, and sets r.RemoteAddr to it, so rate limiting
+// and logging further down the chain see the true client.
+//
+// The headers are only honored when the peer is a trusted proxy, given as CIDR ranges or plain
+// IPs in trustedCIDRs. Proxy chains are walked from the nearest hop, skipping trusted proxies,
+// so a client can not spoof its address by sending the headers itself.
+// It panics if a trusted CIDR is malformed.
+func RealIP(trustedCIDRs ...string) Middleware {
+	trusted := mustParsePrefixes("trusted proxy", trustedCIDRs)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddr(remoteIP(r))
+			if err == nil && trusted.contains(peer.Unmap()) {
+				if ip, ok := clientIP(r.Header, trusted.contains); ok {
+					r.RemoteAddr = ip.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the first untrusted hop, walking the forwarding chain backwards.
+func clientIP(h http.Header, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	var chain []string
+	switch {
+	case h.Get("Forwarded") != "":
+		chain = forwardedFor(h.Values("Forwarded"))
+	case h.Get("X-Forwarded-For") != "":
+		for _, v := range h.Values("X-Forwarded-For") {
+			chain = append(chain, strings.Split(v, ",")...)
+		}
+	case h.Get("X-Real-Ip") != "":
+		chain = []string{h.Get("X-Real-Ip")}
+	}
+
+	var client netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip, err := parseNodeIP(chain[i])
+		if err != nil {
+			break
+		}
+		client = ip
+		if !isTrusted(ip) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+// forwardedFor extracts the for= parameters of Forwarded header values (RFC 7239).
+func forwardedFor(values []string) []string {
+	var nodes []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					nodes = append(nodes, value)
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// parseNodeIP parses an IP optionally quoted, bracketed and followed by a port,
+// as found in forwarding headers.
+func parseNodeIP(node string) (netip.Addr, error) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	node = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	ip, err := netip.ParseAddr(node)
+	return ip.Unmap(), err
+}
+
+// parsePrefix parses a CIDR range or a single IP.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
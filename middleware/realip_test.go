@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	var got string
+	h := middleware.RealIP("10.0.0.0/8", "192.168.1.1", "fd00::/8")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+
+	testCases := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted_peer", "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.9:1234"},
+		{"no_headers", "10.0.0.1:1234", nil, "10.0.0.1:1234"},
+		{"x_forwarded_for", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "1.2.3.4"},
+		{"spoofed_chain", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 1.2.3.4, 10.0.0.2"}, "1.2.3.4"},
+		{"all_trusted", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"x_real_ip", "192.168.1.1:1234", map[string]string{"X-Real-Ip": "1.2.3.4"}, "1.2.3.4"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{
+			"Forwarded":       `for=6.6.6.6, for="[2001:db8::1]:4711";proto=https`,
+			"X-Forwarded-For": "9.9.9.9",
+		}, "2001:db8::1"},
+		{"forwarded_obfuscated", "10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden"}, "10.0.0.1:1234"},
+		{"ipv6_peer", "[fd00::1]:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "1.2.3.4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.RemoteAddr = tc.peer
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tc.want {
+				t.Errorf("got remote addr %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRealIPInvalidCIDR(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid CIDR")
+		}
+	}()
+	middleware.RealIP("10.0.0.0/33")
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+type recoveryConfig struct {
+	stack   bool
+	handler func(w http.ResponseWriter, r *http.Request, rec any)
+}
+
+// RecoveryOption configures the Recovery middleware.
+type RecoveryOption func(*recoveryConfig)
+
+// WithRecoveryStackTrace logs the stack trace of the goroutine that panicked along with the panic.
+func WithRecoveryStackTrace() RecoveryOption {
+	return func(c *recoveryConfig) {
+		c.stack = true
+	}
+}
+
+// WithRecoveryHandler sets the function writing the response after a panic, instead of the
+// default plain text 500 Internal Server Error, e.g. to render JSON or report to an error tracker.
+// rec is the value passed to panic.
+func WithRecoveryHandler(handler func(w http.ResponseWriter, r *http.Request, rec any)) RecoveryOption {
+	return func(c *recoveryConfig) {
+		c.handler = handler
+	}
+}
+
+// Recovery is a middleware that recovers from panics during HTTP request handling.
+// It logs the panic and returns a 500 Internal Server Error response to the client.
+// The logger parameter is used to log the panic details.
+//
+// Panics with http.ErrAbortHandler are not recovered, so the server aborts the response as
+// intended. If the handler already started the response, no error response is written, since
+// the status code can no longer change.
+func Recovery(logger *log.Logger, opts ...RecoveryOption) Middleware {
+	return recovery(func(r *http.Request, rec any, stack []byte) {
+		if stack != nil {
+			logger.Printf("panic recovered: %v\n%s", rec, stack)
+			return
+		}
+		logger.Printf("panic recovered: %v\n", rec)
+	}, opts)
+}
+
+// RecoveryWithSlog is like Recovery but logs recovered panics with a structured logger, at
+// error level, along with the request method, path, matched route, request ID and the stack
+// trace, which is always captured.
+func RecoveryWithSlog(logger *slog.Logger, opts ...RecoveryOption) Middleware {
+	opts = append(opts, WithRecoveryStackTrace())
+	return recovery(func(r *http.Request, rec any, stack []byte) {
+		logger.LogAttrs(context.WithoutCancel(r.Context()), slog.LevelError, "panic recovered",
+			slog.String("panic", fmt.Sprint(rec)),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routectx.From(r.Context()).Path),
+			slog.String("request_id", requestIDOf(r)),
+			slog.String("stack", string(stack)),
+		)
+	}, opts)
+}
+
+// recovery builds the Recovery middleware around report, which logs recovered panics.
+// stack is nil unless WithRecoveryStackTrace is set.
+func recovery(report func(r *http.Request, rec any, stack []byte), opts []RecoveryOption) Middleware {
+	c := recoveryConfig{
+		handler: func(w http.ResponseWriter, _ *http.Request, _ any) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, _ = routectx.With(r)
+			rw := WrapResponseWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				var stack []byte
+				if c.stack {
+					stack = debug.Stack()
+				}
+				report(r, rec, stack)
+				if !rw.Written() {
+					c.handler(w, r, rec)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRecovery(t *testing.T) {
+	jsonHandler := middleware.WithRecoveryHandler(func(w http.ResponseWriter, r *http.Request, rec any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"internal"}`))
+	})
+
+	testCases := []struct {
+		name      string
+		opts      []middleware.RecoveryOption
+		handler   http.HandlerFunc
+		status    int
+		body      string
+		wantStack bool
+	}{
+		{"default", nil, func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			http.StatusInternalServerError, "Internal Server Error\n", false},
+		{"stack_trace", []middleware.RecoveryOption{middleware.WithRecoveryStackTrace()}, func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			http.StatusInternalServerError, "Internal Server Error\n", true},
+		{"custom_handler", []middleware.RecoveryOption{jsonHandler}, func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			http.StatusInternalServerError, `{"error":"internal"}`, false},
+		{"partially_written", nil, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("partial"))
+			panic("boom")
+		}, http.StatusAccepted, "partial", false},
+		{"flushed", nil, func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+			panic("boom")
+		}, http.StatusOK, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h := middleware.Recovery(log.New(&logs, "", 0), tc.opts...)(tc.handler)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if rec.Code != tc.status || rec.Body.String() != tc.body {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body, tc.status, tc.body)
+			}
+			if !strings.HasPrefix(logs.String(), "panic recovered: boom") {
+				t.Errorf("got log %q", logs.String())
+			}
+			if got := strings.Contains(logs.String(), "goroutine "); got != tc.wantStack {
+				t.Errorf("got stack trace logged %t, want %t", got, tc.wantStack)
+			}
+		})
+	}
+}
+
+func TestRecoveryHijacked(t *testing.T) {
+	handled := make(chan bool, 1)
+	h := middleware.Recovery(log.New(io.Discard, "", 0), middleware.WithRecoveryHandler(func(w http.ResponseWriter, r *http.Request, rec any) {
+		handled <- true
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+		panic("boom")
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		handled <- false
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, http.NoBody)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if <-handled {
+		t.Error("the recovery handler must not write to a hijacked connection")
+	}
+}
+
+func TestRecoveryErrAbortHandler(t *testing.T) {
+	h := middleware.Recovery(log.New(new(bytes.Buffer), "", 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("got panic %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+}
+
+func TestRecoveryWithSlog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	h := middleware.RequestID()(middleware.RecoveryWithSlog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", http.NoBody)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status code %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log entry %q: %v", logs.String(), err)
+	}
+	for key, want := range map[string]string{
+		"level":      "ERROR",
+		"msg":        "panic recovered",
+		"panic":      "boom",
+		"method":     http.MethodPost,
+		"path":       "/orders",
+		"request_id": "req-1",
+	} {
+		if entry[key] != want {
+			t.Errorf("got %s %v, want %q", key, entry[key], want)
+		}
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "goroutine ") {
+		t.Errorf("got stack %q", stack)
+	}
+}
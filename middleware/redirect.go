@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type redirectConfig struct {
+	trusted prefixes
+}
+
+// RedirectOption configures the RedirectHTTPS and CanonicalHost middlewares.
+type RedirectOption func(*redirectConfig)
+
+// WithRedirectTrustedProxies honors the scheme of the X-Forwarded-Proto and Forwarded headers
+// when the peer is one of the TLS-terminating proxies given as CIDR ranges or plain IPs, as for
+// RealIP. Without it, the headers are ignored and only r.TLS tells HTTPS requests apart. The
+// peer is r.RemoteAddr, so the middlewares must run before RealIP. It panics if a CIDR is
+// malformed.
+func WithRedirectTrustedProxies(trustedCIDRs ...string) RedirectOption {
+	trusted := mustParsePrefixes("trusted proxy", trustedCIDRs)
+	return func(c *redirectConfig) {
+		c.trusted = trusted
+	}
+}
+
+// RedirectHTTPS is a middleware redirecting plain HTTP requests to the same URL over HTTPS.
+// Behind a TLS-terminating proxy, the original scheme is read from the X-Forwarded-Proto or
+// Forwarded headers if the proxy is trusted with WithRedirectTrustedProxies.
+//
+// Permanent redirects use 308 Permanent Redirect, temporary ones 307 Temporary Redirect, so
+// clients keep the method and body of the request.
+func RedirectHTTPS(permanent bool, opts ...RedirectOption) Middleware {
+	code := redirectCode(permanent)
+	c := newRedirectConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.scheme(r) == "https" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			host := r.Host
+			if h, port, err := net.SplitHostPort(host); err == nil && port == "80" {
+				host = h
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+		})
+	}
+}
+
+// CanonicalHost is a middleware redirecting requests addressed to another host, e.g.
+// "example.com" or an IP, to the same URL on host (e.g. "www.example.com"), keeping the scheme.
+// The comparison is case-insensitive; host should include the port if it is not the default one.
+// Redirect codes and options are those of RedirectHTTPS.
+func CanonicalHost(host string, permanent bool, opts ...RedirectOption) Middleware {
+	code := redirectCode(permanent)
+	c := newRedirectConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Host, host) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Redirect(w, r, c.scheme(r)+"://"+host+r.URL.RequestURI(), code)
+		})
+	}
+}
+
+func newRedirectConfig(opts []RedirectOption) redirectConfig {
+	var c redirectConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func redirectCode(permanent bool) int {
+	if permanent {
+		return http.StatusPermanentRedirect
+	}
+	return http.StatusTemporaryRedirect
+}
+
+// scheme returns the scheme the client used, "http" or "https", honoring the X-Forwarded-Proto
+// and Forwarded headers set by trusted proxies. Other values of the headers are ignored, as they
+// end up in the Location header of redirects.
+func (c redirectConfig) scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	peer, err := netip.ParseAddr(remoteIP(r))
+	if err != nil || !c.trusted.contains(peer.Unmap()) {
+		return "http"
+	}
+	if proto := forwardedProto(r.Header); proto == "https" {
+		return proto
+	}
+	return "http"
+}
+
+// forwardedProto returns the scheme of the X-Forwarded-Proto or Forwarded headers, lowercased.
+func forwardedProto(h http.Header) string {
+	if proto := h.Get("X-Forwarded-Proto"); proto != "" {
+		proto, _, _ = strings.Cut(proto, ",")
+		return strings.ToLower(strings.TrimSpace(proto))
+	}
+	for _, v := range h.Values("Forwarded") {
+		element, _, _ := strings.Cut(v, ",")
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "proto") {
+				return strings.ToLower(strings.Trim(value, `"`))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	testCases := []struct {
+		name      string
+		permanent bool
+		target    string
+		headers   map[string]string
+		tls       bool
+		status    int
+		location  string
+	}{
+		{"http", true, "http://example.com/a?b=1", nil, false, http.StatusPermanentRedirect, "https://example.com/a?b=1"},
+		{"temporary", false, "http://example.com/", nil, false, http.StatusTemporaryRedirect, "https://example.com/"},
+		{"default_port_dropped", true, "http://example.com:80/", nil, false, http.StatusPermanentRedirect, "https://example.com/"},
+		{"tls", true, "https://example.com/", nil, true, http.StatusOK, ""},
+		{"forwarded_proto", true, "http://example.com/", map[string]string{"X-Forwarded-Proto": "https"}, false, http.StatusOK, ""},
+		{"forwarded_proto_http", true, "http://example.com/", map[string]string{"X-Forwarded-Proto": "http"}, false, http.StatusPermanentRedirect, "https://example.com/"},
+		{"forwarded", true, "http://example.com/", map[string]string{"Forwarded": `for=1.2.3.4;proto=https`}, false, http.StatusOK, ""},
+		{"forwarded_proto_invalid", true, "http://example.com/", map[string]string{"X-Forwarded-Proto": "https://evil.com/#"}, false, http.StatusPermanentRedirect, "https://example.com/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, http.NoBody)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rec := httptest.NewRecorder()
+			// httptest requests come from 192.0.2.1.
+			middleware.RedirectHTTPS(tc.permanent, middleware.WithRedirectTrustedProxies("192.0.2.0/24"))(ok).ServeHTTP(rec, req)
+			if rec.Code != tc.status || rec.Header().Get("Location") != tc.location {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Header().Get("Location"), tc.status, tc.location)
+			}
+		})
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	h := middleware.CanonicalHost("www.example.com", true, middleware.WithRedirectTrustedProxies("192.0.2.1"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	testCases := []struct {
+		name     string
+		target   string
+		proto    string
+		status   int
+		location string
+	}{
+		{"canonical", "http://www.example.com/a", "", http.StatusOK, ""},
+		{"case_insensitive", "http://WWW.Example.com/a", "", http.StatusOK, ""},
+		{"apex", "http://example.com/a?b=1", "", http.StatusPermanentRedirect, "http://www.example.com/a?b=1"},
+		{"apex_behind_tls_proxy", "http://example.com/a", "https", http.StatusPermanentRedirect, "https://www.example.com/a"},
+		{"invalid_proto", "http://example.com/x", "https://evil.com/#", http.StatusPermanentRedirect, "http://www.example.com/x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			if tc.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status || rec.Header().Get("Location") != tc.location {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Header().Get("Location"), tc.status, tc.location)
+			}
+		})
+	}
+}
+
+func TestRedirectUntrustedProxy(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/a", http.NoBody)
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	rec := httptest.NewRecorder()
+	middleware.RedirectHTTPS(true, middleware.WithRedirectTrustedProxies("10.0.0.0/8"))(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Errorf("got %d, want the forwarded scheme of an untrusted peer ignored", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.CanonicalHost("www.example.com", true)(ok).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Location"); got != "http://www.example.com/a" {
+		t.Errorf("got Location %q, want %q", got, "http://www.example.com/a")
+	}
+}
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware/cors"
+)
+
+// ConfigArg is the config key holding the argument of a middleware reference such as
+// "ratelimit:100rps", "100rps" here, when it is resolved by Resolve.
+const ConfigArg = "arg"
+
+// ErrUnknownMiddleware is returned by Resolve for middleware names no factory is registered for.
+var ErrUnknownMiddleware = errors.New("middleware: unknown middleware")
+
+// Factory builds a middleware from its configuration, typically decoded from YAML or JSON. It
+// panics if the configuration is invalid.
+type Factory func(config map[string]any) Middleware
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a middleware available by name to Resolve, so route configurations can refer
+// to it. It panics if name is empty or contains a colon, if factory is nil or if a factory is
+// already registered for name. The following middlewares are registered by default:
+//
+//	recovery      RecoveryWithSlog with slog.Default()
+//	requestid     RequestID
+//	enforcejson   EnforceJSON
+//	nocache       NoCache
+//	responsetime  ResponseTime
+//	servertiming  ServerTiming
+//	compress      Compress, with the "level" config (default gzip.DefaultCompression)
+//	ratelimit     RateLimit, with the rate as argument or "rate" config ("100rps", "60rpm",
+//	              "1000rph") and the "burst" config (default the rate per second, at least 1)
+//	cors          cors.CORSHandler, with the "origins", "methods", "headers" and
+//	              "exposed_headers" string list configs, the "credentials" boolean config
+//	              and the "max_age" duration config ("10m")
+//	scopes        RequireScopes, with the comma-separated scopes as argument
+//	              ("scopes:users:read,users:write") or the "scopes" string list config
+//	roles         RequireRoles, with the comma-separated roles as argument or the "roles"
+//	              string list config
+func Register(name string, factory Factory) {
+	if name == "" || strings.Contains(name, ":") {
+		panic(fmt.Sprintf("middleware: invalid middleware name %q", name))
+	}
+	if factory == nil {
+		panic("middleware: Register factory is nil for " + name)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("middleware: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the sorted names of the registered middlewares.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Sorted(maps.Keys(registry))
+}
+
+// Resolve builds the middleware ref refers to with config, which may be nil. ref is the name of a
+// registered middleware, optionally followed by a colon and an argument passed to the factory
+// under the ConfigArg key, e.g. "ratelimit:100rps". config is not modified.
+// It returns an error wrapping ErrUnknownMiddleware if no middleware is registered under the
+// name, and an error if the factory rejects config.
+func Resolve(ref string, config map[string]any) (m Middleware, err error) {
+	name, arg, hasArg := strings.Cut(ref, ":")
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMiddleware, name)
+	}
+
+	if hasArg {
+		config = maps.Clone(config)
+		if config == nil {
+			config = map[string]any{}
+		}
+		config[ConfigArg] = arg
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			m, err = nil, fmt.Errorf("middleware: resolving %q: %v", ref, rec)
+		}
+	}()
+	return factory(config), nil
+}
+
+func init() {
+	Register("recovery", func(map[string]any) Middleware { return RecoveryWithSlog(slog.Default()) })
+	Register("requestid", func(map[string]any) Middleware { return RequestID() })
+	Register("enforcejson", func(map[string]any) Middleware { return EnforceJSON })
+	Register("nocache", func(map[string]any) Middleware { return NoCache() })
+	Register("responsetime", func(map[string]any) Middleware { return ResponseTime() })
+	Register("servertiming", func(map[string]any) Middleware { return ServerTiming() })
+	Register("compress", func(config map[string]any) Middleware {
+		return Compress(configInt(config, "level", -1))
+	})
+	Register("ratelimit", func(config map[string]any) Middleware {
+		rate := configString(config, ConfigArg, configString(config, "rate", ""))
+		limit := parseRate(rate)
+		return RateLimit(limit, configInt(config, "burst", max(1, int(limit))))
+	})
+	Register("cors", func(config map[string]any) Middleware {
+		var opts []cors.Option
+		if v, ok := config["origins"]; ok {
+			opts = append(opts, cors.WithOrigins(toStrings("origins", v)))
+		}
+		if v, ok := config["methods"]; ok {
+			opts = append(opts, cors.WithMethods(toStrings("methods", v)))
+		}
+		if v, ok := config["headers"]; ok {
+			opts = append(opts, cors.WithHeaders(toStrings("headers", v)))
+		}
+		if v, ok := config["exposed_headers"]; ok {
+			opts = append(opts, cors.WithExposedHeaders(toStrings("exposed_headers", v)))
+		}
+		if v, ok := config["credentials"]; ok {
+			allow, isBool := v.(bool)
+			if !isBool {
+				panic(fmt.Sprintf("credentials must be a boolean, got %T", v))
+			}
+			opts = append(opts, cors.WithCredentials(allow))
+		}
+		if v := configString(config, "max_age", ""); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				panic(fmt.Sprintf("max_age: %v", err))
+			}
+			opts = append(opts, cors.WithMaxAge(d))
+		}
+		return cors.CORSHandler(opts...)
+	})
+	Register("scopes", func(config map[string]any) Middleware {
+		return RequireScopes(configList(config, "scopes")...)
+	})
+	Register("roles", func(config map[string]any) Middleware {
+		return RequireRoles(configList(config, "roles")...)
+	})
+}
+
+// configList returns the comma-separated ConfigArg or, if it is not set, the string list
+// config[key]. It panics if the list is empty.
+func configList(config map[string]any, key string) []string {
+	var list []string
+	if arg := configString(config, ConfigArg, ""); arg != "" {
+		list = strings.Split(arg, ",")
+	} else if v, ok := config[key]; ok {
+		list = toStrings(key, v)
+	}
+	if len(list) == 0 {
+		panic(key + " must not be empty")
+	}
+	return list
+}
+
+// parseRate parses a rate such as "100rps", "60rpm" or "1000rph"; a bare number is per second.
+func parseRate(rate string) Limit {
+	per := time.Second
+	for suffix, d := range map[string]time.Duration{"rps": time.Second, "rpm": time.Minute, "rph": time.Hour} {
+		if n, ok := strings.CutSuffix(rate, suffix); ok {
+			rate, per = n, d
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(rate, 64)
+	if err != nil || n <= 0 {
+		panic(fmt.Sprintf("invalid rate %q", rate))
+	}
+	return Limit(n * float64(time.Second) / float64(per))
+}
+
+// configString returns the string config[key], or def if it is not set.
+func configString(config map[string]any, key, def string) string {
+	v, ok := config[key]
+	if !ok {
+		return def
+	}
+	s, ok := v.(string)
+	if !ok {
+		panic(fmt.Sprintf("%s must be a string, got %T", key, v))
+	}
+	return s
+}
+
+// configInt returns the integer config[key], which may have been decoded as a float64 from
+// JSON or as a string from a reference argument, or def if it is not set.
+func configInt(config map[string]any, key string, def int) int {
+	v, ok := config[key]
+	if !ok {
+		return def
+	}
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		if n == float64(int(n)) {
+			return int(n)
+		}
+	case string:
+		if i, err := strconv.Atoi(n); err == nil {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("%s must be an integer, got %v", key, v))
+}
+
+// toStrings converts a list decoded from YAML or JSON to strings.
+func toStrings(key string, v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		s := make([]string, len(list))
+		for i, item := range list {
+			str, ok := item.(string)
+			if !ok {
+				panic(fmt.Sprintf("%s must be a list of strings, got %T item", key, item))
+			}
+			s[i] = str
+		}
+		return s
+	}
+	panic(fmt.Sprintf("%s must be a list of strings, got %T", key, v))
+}
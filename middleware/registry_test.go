@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRegisterAndResolve(t *testing.T) {
+	var got map[string]any
+	middleware.Register("test-header", func(config map[string]any) middleware.Middleware {
+		got = config
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Test", config[middleware.ConfigArg].(string))
+				next.ServeHTTP(w, r)
+			})
+		}
+	})
+	if !slices.Contains(middleware.Registered(), "test-header") {
+		t.Errorf("got registered %v, want test-header among them", middleware.Registered())
+	}
+
+	config := map[string]any{"other": 1}
+	m, err := middleware.Resolve("test-header:hello", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["other"] != 1 || got[middleware.ConfigArg] != "hello" {
+		t.Errorf("got config %v", got)
+	}
+	if _, ok := config[middleware.ConfigArg]; ok {
+		t.Error("Resolve modified the config")
+	}
+	rec := httptest.NewRecorder()
+	m(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Header().Get("X-Test") != "hello" {
+		t.Errorf("got X-Test %q, want hello", rec.Header().Get("X-Test"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice must panic")
+		}
+	}()
+	middleware.Register("test-header", func(map[string]any) middleware.Middleware { return nil })
+}
+
+func TestResolveBuiltins(t *testing.T) {
+	testCases := []struct {
+		ref    string
+		config map[string]any
+		err    string
+	}{
+		{"recovery", nil, ""},
+		{"requestid", nil, ""},
+		{"enforcejson", nil, ""},
+		{"compress", map[string]any{"level": float64(5)}, ""},
+		{"compress", map[string]any{"level": 42}, "invalid compression level"},
+		{"compress", map[string]any{"level": "fast"}, "level must be an integer"},
+		{"ratelimit:100rps", nil, ""},
+		{"ratelimit", map[string]any{"rate": "60rpm", "burst": 5}, ""},
+		{"ratelimit:fast", nil, `invalid rate "fast"`},
+		{"cors", map[string]any{"origins": []any{"https://example.com"}, "credentials": true, "max_age": "10m"}, ""},
+		{"cors", map[string]any{"origins": "https://example.com"}, "origins must be a list of strings"},
+		{"scopes:users:read,users:write", nil, ""},
+		{"roles", map[string]any{"roles": []any{"admin"}}, ""},
+		{"roles", nil, "roles must not be empty"},
+		{"unknown", nil, "unknown middleware"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			m, err := middleware.Resolve(tc.ref, tc.config)
+			if tc.err == "" {
+				if err != nil || m == nil {
+					t.Errorf("got error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want one containing %q", err, tc.err)
+			}
+		})
+	}
+
+	if _, err := middleware.Resolve("unknown", nil); !errors.Is(err, middleware.ErrUnknownMiddleware) {
+		t.Errorf("got error %v, want ErrUnknownMiddleware", err)
+	}
+}
+
+func TestResolveRateLimit(t *testing.T) {
+	m, err := middleware.Resolve("ratelimit:1rps", map[string]any{"burst": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	var codes []int
+	for range 2 {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		codes = append(codes, rec.Code)
+	}
+	if !slices.Equal(codes, []int{http.StatusOK, http.StatusTooManyRequests}) {
+		t.Errorf("got status codes %v", codes)
+	}
+}
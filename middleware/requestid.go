@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the default header the request ID is read from and echoed in.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds the length of an accepted incoming request ID.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+	trust    bool
+}
+
+// RequestIDOption configures the RequestID middleware.
+type RequestIDOption func(*requestIDConfig)
+
+// WithRequestIDHeader sets the header the request ID is read from and echoed in.
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.header = header
+	}
+}
+
+// WithRequestIDGenerator sets the function generating new request IDs.
+// By default a random 128-bit hex string is generated.
+func WithRequestIDGenerator(generate func() string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.generate = generate
+	}
+}
+
+// WithTrustedRequestID sets whether an incoming request ID is reused. It is true by default;
+// disable it on edge services facing untrusted clients to always generate a new ID.
+func WithTrustedRequestID(trust bool) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.trust = trust
+	}
+}
+
+// RequestID is a middleware that reads the request ID from the X-Request-Id header, or generates
+// one if the header is missing or malformed. The ID is stored in the request context, set on the
+// request header for middlewares running before it and echoed in the response header.
+// Use GetRequestID to read it.
+func RequestID(opts ...RequestIDOption) Middleware {
+	c := requestIDConfig{header: RequestIDHeader, generate: newRequestID, trust: true}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(c.header)
+			if !c.trust || !validRequestID(id) {
+				id = c.generate()
+			}
+			r.Header.Set(c.header, id)
+			w.Header().Set(c.header, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// GetRequestID returns the request ID stored in ctx by the RequestID middleware, or an empty string.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id is short and made of visible ASCII characters only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
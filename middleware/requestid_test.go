@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetRequestID(r.Context())
+	})
+
+	testCases := []struct {
+		name     string
+		incoming string
+		opts     []middleware.RequestIDOption
+		want     string
+	}{
+		{"reuse", "abc-123", nil, "abc-123"},
+		{"generate", "", nil, ""},
+		{"malformed", "bad id\n", nil, ""},
+		{"too_long", strings.Repeat("a", 200), nil, ""},
+		{"untrusted", "abc-123", []middleware.RequestIDOption{middleware.WithTrustedRequestID(false)}, ""},
+		{"generator", "", []middleware.RequestIDOption{middleware.WithRequestIDGenerator(func() string { return "fixed" })}, "fixed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			middleware.RequestID(tc.opts...)(h).ServeHTTP(rec, req)
+
+			if tc.want != "" && got != tc.want {
+				t.Errorf("got request id %q, want %q", got, tc.want)
+			}
+			if tc.want == "" && (len(got) != 32 || got == tc.incoming) {
+				t.Errorf("got request id %q, want a generated one", got)
+			}
+			if echoed := rec.Header().Get(middleware.RequestIDHeader); echoed != got {
+				t.Errorf("got response header %q, want %q", echoed, got)
+			}
+		})
+	}
+
+	t.Run("custom_header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.Header.Set("X-Correlation-Id", "corr")
+		rec := httptest.NewRecorder()
+		middleware.RequestID(middleware.WithRequestIDHeader("X-Correlation-Id"))(h).ServeHTTP(rec, req)
+		if got != "corr" || rec.Header().Get("X-Correlation-Id") != "corr" {
+			t.Errorf("got request id %q, response header %q, want %q", got, rec.Header().Get("X-Correlation-Id"), "corr")
+		}
+	})
+}
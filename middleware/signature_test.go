@@ -0,0 +1,114 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+type memoryReplayCache struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (c *memoryReplayCache) Seen(_ context.Context, sig string, _ time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := c.seen[sig]
+	c.seen[sig] = true
+	return seen, nil
+}
+
+func TestVerifySignature(t *testing.T) {
+	secrets := map[string][]byte{"partner": []byte("s3cret")}
+	lookup := func(_ context.Context, keyID string) ([]byte, error) {
+		secret, ok := secrets[keyID]
+		if !ok {
+			return nil, errors.New("unknown key")
+		}
+		return secret, nil
+	}
+
+	h := middleware.VerifySignature(lookup,
+		middleware.WithSignatureMaxBody(64),
+		middleware.WithSignatureReplayCache(&memoryReplayCache{seen: map[string]bool{}}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := middleware.GetPrincipal(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(principal + ":" + string(body)))
+	}))
+
+	signed := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhook?x=1", strings.NewReader(body))
+		if err := middleware.SignRequest(req, "partner", secrets["partner"]); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	testCases := []struct {
+		name   string
+		req    func() *http.Request
+		status int
+	}{
+		{"valid", func() *http.Request { return signed(`{"event":"paid"}`) }, http.StatusOK},
+		{"tampered_body", func() *http.Request {
+			req := signed(`{"event":"paid"}`)
+			req.Body = io.NopCloser(strings.NewReader(`{"event":"refunded"}`))
+			return req
+		}, http.StatusUnauthorized},
+		{"tampered_path", func() *http.Request {
+			req := signed("a")
+			req.URL.RawQuery = "x=2"
+			return req
+		}, http.StatusUnauthorized},
+		{"stale", func() *http.Request {
+			req := signed("b")
+			req.Header.Set(middleware.SignatureTimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+			return req
+		}, http.StatusUnauthorized},
+		{"unknown_key", func() *http.Request {
+			req := signed("c")
+			req.Header.Set(middleware.SignatureKeyIDHeader, "other")
+			return req
+		}, http.StatusUnauthorized},
+		{"missing", func() *http.Request { return httptest.NewRequest(http.MethodPost, "/webhook", http.NoBody) }, http.StatusUnauthorized},
+		{"too_large", func() *http.Request { return signed(strings.Repeat("x", 100)) }, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tc.req())
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.status == http.StatusOK && rec.Body.String() != `partner:{"event":"paid"}` {
+				t.Errorf("got body %q", rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		req := signed("replayed")
+		replay := req.Clone(context.Background())
+		replay.Body = io.NopCloser(strings.NewReader("replayed"))
+
+		for i, want := range []int{http.StatusOK, http.StatusConflict} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, []*http.Request{req, replay}[i])
+			if rec.Code != want {
+				t.Errorf("attempt %d: got status code %d, want %d", i, rec.Code, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/links"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestVerifySignedURL(t *testing.T) {
+	signer := middleware.NewURLSigner([]byte("0123456789abcdef0123456789abcdef"))
+	download := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file " + r.PathValue("id")))
+	}
+	routes := rahjoo.Route{
+		"/files/{id}": {http.MethodGet: rahjoo.NewHandler(download).WithName("file.download")},
+	}.SetMiddleware(middleware.VerifySignedURL(signer))
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, routes); err != nil {
+		t.Fatal(err)
+	}
+	urls, err := links.New(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := urls.URL("file.download", "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(rawURL string, ttl time.Duration) string {
+		signed, err := signer.Sign(rawURL, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := sign(u+"?disposition=inline", time.Minute)
+	otherKey, _ := middleware.NewURLSigner([]byte("another key")).Sign(u, time.Minute)
+
+	testCases := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"valid", valid, http.StatusOK},
+		{"absolute", strings.TrimPrefix(sign("https://files.example.com"+u, time.Minute), "https://files.example.com"), http.StatusOK},
+		{"other_path", strings.Replace(valid, "/files/42", "/files/43", 1), http.StatusForbidden},
+		{"changed_query", strings.Replace(valid, "disposition=inline", "disposition=attachment", 1), http.StatusForbidden},
+		{"added_query", valid + "&admin=1", http.StatusForbidden},
+		{"extended_expiry", strings.Replace(valid, "expires=", "expires=9", 1), http.StatusForbidden},
+		{"unsigned", u, http.StatusForbidden},
+		{"other_key", otherKey, http.StatusForbidden},
+		{"expired", sign(u, -time.Minute), http.StatusGone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d for %s, want %d", rec.Code, tc.target, tc.status)
+			}
+		})
+	}
+}
+
+func TestURLSignerSign(t *testing.T) {
+	signer := middleware.NewURLSigner([]byte("key"))
+	signed, err := signer.Sign("/files/42?signature=forged&a=1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(signed, "signature=") != 1 || !strings.Contains(signed, "a=1&expires=") {
+		t.Errorf("got signed URL %q, want the existing query kept and one signature", signed)
+	}
+	if _, err := signer.Sign("%zz", time.Hour); err == nil {
+		t.Error("got no error for an invalid URL")
+	}
+}
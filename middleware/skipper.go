@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Skipper reports whether a middleware should be bypassed for the given request.
+type Skipper func(r *http.Request) bool
+
+// Skip wraps middleware so that requests for which skipper returns true bypass it and are
+// passed straight to the next handler, e.g.
+//
+//	middleware.Skip(middleware.EnforceJSON, middleware.SkipPaths("/healthz"))
+func Skip(middleware Middleware, skipper Skipper) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SkipPaths returns a Skipper matching requests whose URL path is one of paths.
+func SkipPaths(paths ...string) Skipper {
+	return func(r *http.Request) bool {
+		return slices.Contains(paths, r.URL.Path)
+	}
+}
+
+// SkipPathPrefixes returns a Skipper matching requests whose URL path starts with one of prefixes.
+func SkipPathPrefixes(prefixes ...string) Skipper {
+	return func(r *http.Request) bool {
+		return slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return strings.HasPrefix(r.URL.Path, prefix)
+		})
+	}
+}
+
+// SkipMethods returns a Skipper matching requests made with one of methods.
+func SkipMethods(methods ...string) Skipper {
+	return func(r *http.Request) bool {
+		return slices.Contains(methods, r.Method)
+	}
+}
+
+// SkipMultipart is a Skipper matching requests with a multipart body.
+func SkipMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/")
+}
+
+// SkipAny returns a Skipper matching requests matched by any of skippers.
+func SkipAny(skippers ...Skipper) Skipper {
+	return func(r *http.Request) bool {
+		return slices.ContainsFunc(skippers, func(skip Skipper) bool {
+			return skip(r)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestSkip(t *testing.T) {
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	panics := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") })
+
+	enforceJSON := middleware.Skip(middleware.EnforceJSON, middleware.SkipAny(
+		middleware.SkipPaths("/healthz"),
+		middleware.SkipPathPrefixes("/public/"),
+		middleware.SkipMethods(http.MethodGet),
+		middleware.SkipMultipart,
+	))(ok)
+
+	testCases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		status      int
+	}{
+		{"enforced", http.MethodPost, "/users", "", http.StatusBadRequest},
+		{"path", http.MethodPost, "/healthz", "", http.StatusOK},
+		{"prefix", http.MethodPost, "/public/file", "", http.StatusOK},
+		{"method", http.MethodGet, "/users", "", http.StatusOK},
+		{"multipart", http.MethodPost, "/users", "multipart/form-data; boundary=x", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, http.NoBody)
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			enforceJSON.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+
+	t.Run("recovery", func(t *testing.T) {
+		recovery := middleware.Skip(middleware.Recovery(log.Default()), middleware.SkipPaths("/raw"))(panics)
+
+		rec := httptest.NewRecorder()
+		recovery.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/recovered", http.NoBody))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("got status code %d, want %d", rec.Code, http.StatusInternalServerError)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected skipped recovery to let the panic through")
+			}
+		}()
+		recovery.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/raw", http.NoBody))
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+type slowRequestConfig struct {
+	observe func(r *http.Request, route string, latency time.Duration)
+}
+
+// SlowRequestOption configures the SlowRequestLog middleware.
+type SlowRequestOption func(*slowRequestConfig)
+
+// WithSlowRequestObserver calls observe for every slow request along with the log entry, e.g.
+// to increment a metrics counter labeled by route. route is the matched route pattern.
+func WithSlowRequestObserver(observe func(r *http.Request, route string, latency time.Duration)) SlowRequestOption {
+	return func(c *slowRequestConfig) {
+		c.observe = observe
+	}
+}
+
+// SlowRequestLog is a middleware logging, at warning level, the requests taking longer than
+// threshold to be served, with the method, the matched route pattern, the path, the status code,
+// the latency and the request ID, to spot problem endpoints without full tracing.
+func SlowRequestLog(threshold time.Duration, logger *slog.Logger, opts ...SlowRequestOption) Middleware {
+	var c slowRequestConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, route := routectx.With(r)
+			rw := AcquireResponseWriter(w)
+			defer ReleaseResponseWriter(rw, w)
+
+			next.ServeHTTP(rw, r)
+
+			latency := time.Since(start)
+			if latency <= threshold {
+				return
+			}
+			logger.LogAttrs(context.WithoutCancel(r.Context()), slog.LevelWarn, "slow request",
+				slog.String("method", r.Method),
+				slog.String("route", route.Path),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.Status()),
+				slog.Duration("latency", latency),
+				slog.Duration("threshold", threshold),
+				slog.String("request_id", requestIDOf(r)),
+			)
+			if c.observe != nil {
+				c.observe(r, route.Path, latency)
+			}
+		})
+	}
+}
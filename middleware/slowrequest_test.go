@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestSlowRequestLog(t *testing.T) {
+	var logs bytes.Buffer
+	slow := map[string]int{}
+	mw := middleware.SlowRequestLog(20*time.Millisecond, slog.New(slog.NewJSONHandler(&logs, nil)),
+		middleware.WithSlowRequestObserver(func(r *http.Request, route string, latency time.Duration) {
+			slow[route]++
+		}),
+	)
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux, rahjoo.Route{
+		"/reports/{id}": {http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(30 * time.Millisecond)
+		})},
+		"/fast": {http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(mux)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", http.NoBody))
+	if logs.Len() != 0 {
+		t.Errorf("fast request logged: %s", logs.String())
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reports/7", http.NoBody))
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log entry %q: %v", logs.String(), err)
+	}
+	if entry["level"] != "WARN" || entry["route"] != "/reports/{id}" || entry["path"] != "/reports/7" || entry["status"] != 200.0 {
+		t.Errorf("got log entry %v", entry)
+	}
+	if slow["/reports/{id}"] != 1 || len(slow) != 1 {
+		t.Errorf("got observed slow requests %v", slow)
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseTimeHeader is the header ResponseTime reports the handler duration in.
+const ResponseTimeHeader = "X-Response-Time"
+
+// ResponseTime is a middleware reporting how long the handler took until the response header
+// was written in the X-Response-Time header, in milliseconds (e.g. "12.345ms").
+func ResponseTime() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			hw := &headerHookWriter{ResponseWriter: w, hook: func(h http.Header) {
+				h.Set(ResponseTimeHeader, formatMillis(time.Since(start))+"ms")
+			}}
+			next.ServeHTTP(hw, r)
+			hw.fire()
+		})
+	}
+}
+
+type serverTimingKey struct{}
+
+type serverTiming struct {
+	name string
+	dur  time.Duration
+	desc string
+}
+
+type serverTimings struct {
+	mu      sync.Mutex
+	entries []serverTiming
+}
+
+// ServerTiming is a middleware reporting the handler duration as the "total" metric of the
+// Server-Timing header, along with the sub-timings recorded by handlers with AddServerTiming or
+// StartServerTiming until the response header is written, so browser developer tools can
+// break down where the time went.
+func ServerTiming() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			timings := &serverTimings{}
+			hw := &headerHookWriter{ResponseWriter: w, hook: func(h http.Header) {
+				h.Add("Server-Timing", timings.header(time.Since(start)))
+			}}
+			next.ServeHTTP(hw, r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, timings)))
+			hw.fire()
+		})
+	}
+}
+
+// AddServerTiming records a sub-timing named name, with an optional description, to be
+// reported by the ServerTiming middleware. It does nothing if the middleware is not in use.
+// name must be a valid HTTP token, such as "db" or "cache".
+func AddServerTiming(ctx context.Context, name string, d time.Duration, desc string) {
+	timings, ok := ctx.Value(serverTimingKey{}).(*serverTimings)
+	if !ok {
+		return
+	}
+	timings.mu.Lock()
+	timings.entries = append(timings.entries, serverTiming{name: name, dur: d, desc: desc})
+	timings.mu.Unlock()
+}
+
+// StartServerTiming starts timing name and returns the function recording it, for use with defer:
+//
+//	defer middleware.StartServerTiming(r.Context(), "db")()
+func StartServerTiming(ctx context.Context, name string) func() {
+	start := time.Now()
+	return func() {
+		AddServerTiming(ctx, name, time.Since(start), "")
+	}
+}
+
+func (t *serverTimings) header(total time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	for _, e := range t.entries {
+		b.WriteString(e.name)
+		b.WriteString(";dur=")
+		b.WriteString(formatMillis(e.dur))
+		if e.desc != "" {
+			b.WriteString(";desc=")
+			b.WriteString(strconv.Quote(e.desc))
+		}
+		b.WriteString(", ")
+	}
+	b.WriteString("total;dur=")
+	b.WriteString(formatMillis(total))
+	return b.String()
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// headerHookWriter calls hook with the response header right before it is written, or when
+// fire is called if the handler wrote nothing. Like ResponseWriter, it passes flushing,
+// hijacking and server push through; the hook is not called on hijacked connections.
+type headerHookWriter struct {
+	http.ResponseWriter
+	hook  func(http.Header)
+	fired bool
+}
+
+func (w *headerHookWriter) fire() {
+	if w.fired {
+		return
+	}
+	w.fired = true
+	w.hook(w.Header())
+}
+
+func (w *headerHookWriter) WriteHeader(code int) {
+	// Informational responses precede the final one, which gets the header.
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		w.fire()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerHookWriter) Write(b []byte) (int, error) {
+	w.fire()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerHookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headerHookWriter) Flush() {
+	w.fire()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *headerHookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		// The handler now writes the response on its own.
+		w.fired = true
+	}
+	return conn, buf, err
+}
+
+func (w *headerHookWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
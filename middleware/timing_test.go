@@ -0,0 +1,81 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestResponseTime(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"write", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }},
+		{"write_header", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }},
+		{"empty", func(w http.ResponseWriter, r *http.Request) {}},
+		{"flush", func(w http.ResponseWriter, r *http.Request) { w.(http.Flusher).Flush() }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			middleware.ResponseTime()(tc.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			if got := rec.Header().Get(middleware.ResponseTimeHeader); !regexp.MustCompile(`^\d+\.\d{3}ms$`).MatchString(got) {
+				t.Errorf("got X-Response-Time %q", got)
+			}
+		})
+	}
+}
+
+func TestServerTiming(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name:    "total_only",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			want:    `^total;dur=\d+\.\d{3}$`,
+		},
+		{
+			name: "sub_timings",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				middleware.AddServerTiming(r.Context(), "cache", 2*time.Millisecond, "miss")
+				stop := middleware.StartServerTiming(r.Context(), "db")
+				stop()
+				w.Write([]byte("ok"))
+			},
+			want: `^cache;dur=2\.000;desc="miss", db;dur=\d+\.\d{3}, total;dur=\d+\.\d{3}$`,
+		},
+		{
+			name: "after_header_ignored",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				middleware.AddServerTiming(r.Context(), "late", time.Millisecond, "")
+			},
+			want: `^total;dur=\d+\.\d{3}$`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			middleware.ServerTiming()(tc.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			if got := rec.Header().Get("Server-Timing"); !regexp.MustCompile(tc.want).MatchString(got) {
+				t.Errorf("got Server-Timing %q, want match for %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddServerTimingWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	middleware.AddServerTiming(req.Context(), "db", time.Millisecond, "")
+	middleware.StartServerTiming(req.Context(), "db")()
+}
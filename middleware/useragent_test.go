@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestUserAgentFilter(t *testing.T) {
+	blocked := map[string]int{}
+	h := middleware.UserAgentFilter([]string{"sqlmap", "^curl/", `bot\b`},
+		middleware.WithUserAgentBlockEmpty(),
+		middleware.WithUserAgentObserver(func(r *http.Request, pattern string) { blocked[pattern]++ }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	testCases := []struct {
+		name   string
+		ua     string
+		status int
+	}{
+		{"browser", "Mozilla/5.0 (X11; Linux x86_64)", http.StatusOK},
+		{"scanner", "sqlmap/1.7", http.StatusForbidden},
+		{"case_insensitive", "SQLMap/1.7", http.StatusForbidden},
+		{"anchored", "curl/8.0", http.StatusForbidden},
+		{"anchored_elsewhere", "libcurl/8.0", http.StatusOK},
+		{"word_boundary", "EvilBot bot", http.StatusForbidden},
+		{"empty", "", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("User-Agent", tc.ua)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+	if blocked["sqlmap"] != 2 || blocked["^curl/"] != 1 || blocked[""] != 1 {
+		t.Errorf("got blocked counts %v", blocked)
+	}
+}
+
+func TestUserAgentFilterTarpit(t *testing.T) {
+	h := middleware.UserAgentFilter([]string{"sqlmap"}, middleware.WithUserAgentTarpit(50*time.Millisecond))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("User-Agent", "sqlmap")
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || rec.Code != http.StatusForbidden {
+		t.Errorf("got %d after %s, want 403 after the tarpit delay", rec.Code, elapsed)
+	}
+}
+
+func TestUserAgentFilterInvalidPattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid pattern")
+		}
+	}()
+	middleware.UserAgentFilter([]string{"("})
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+// Default body size decoded by the Validate middleware.
+const defaultValidateMaxBody = 1 << 20
+
+// StructValidator validates decoded values. *validator.Validate of
+// github.com/go-playground/validator satisfies it, so it can be passed to WithValidator as is.
+type StructValidator interface {
+	Struct(v any) error
+}
+
+// Validatable is implemented by types validating themselves, used when no StructValidator is set.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldViolation is a field failing validation, as listed in 422 responses.
+type FieldViolation struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type validateConfig struct {
+	validator      StructValidator
+	maxBody        int64
+	disallowFields bool
+}
+
+// ValidateOption configures the Validate middleware.
+type ValidateOption func(*validateConfig)
+
+// WithValidator sets the validator run on decoded bodies, e.g. validator.New() of
+// github.com/go-playground/validator.
+func WithValidator(v StructValidator) ValidateOption {
+	return func(c *validateConfig) {
+		c.validator = v
+	}
+}
+
+// WithValidateMaxBody caps the size of decoded bodies. It defaults to 1 MiB.
+func WithValidateMaxBody(n int64) ValidateOption {
+	return func(c *validateConfig) {
+		c.maxBody = n
+	}
+}
+
+// WithDisallowUnknownFields rejects bodies with fields T does not have.
+func WithDisallowUnknownFields() ValidateOption {
+	return func(c *validateConfig) {
+		c.disallowFields = true
+	}
+}
+
+type validatedKey[T any] struct{}
+
+// Validate is a middleware decoding the JSON request body into a T and validating it, with the
+// StructValidator set by WithValidator or, without one, the Validate method of T if it
+// implements Validatable. The validated value is stored in the request context for the handler,
+// read with GetValidated.
+//
+// Malformed bodies are answered with 400 Bad Request and invalid ones with 422 Unprocessable
+// Entity, both as problem details; the latter lists the violations in an "errors" member.
+// Errors are split into field violations when they are a slice of errors with a Field method,
+// such as validator.ValidationErrors, or joined with errors.Join.
+func Validate[T any](opts ...ValidateOption) Middleware {
+	c := validateConfig{maxBody: defaultValidateMaxBody}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var v T
+			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, c.maxBody))
+			if c.disallowFields {
+				dec.DisallowUnknownFields()
+			}
+			if err := dec.Decode(&v); err != nil {
+				status := http.StatusBadRequest
+				if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+					status = http.StatusRequestEntityTooLarge
+				} else if errors.Is(err, io.EOF) {
+					err = errors.New("request body is empty")
+				}
+				problem.Write(w, status, problem.WithDetail(err.Error()))
+				return
+			}
+
+			var err error
+			if c.validator != nil {
+				err = c.validator.Struct(v)
+			} else if val, ok := any(&v).(Validatable); ok {
+				err = val.Validate()
+			} else if val, ok := any(v).(Validatable); ok {
+				err = val.Validate()
+			}
+			if err != nil {
+				problem.Write(w, http.StatusUnprocessableEntity,
+					problem.WithDetail("request body is invalid"),
+					problem.WithExtension("errors", fieldViolations(err)),
+				)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), validatedKey[T]{}, v)))
+		})
+	}
+}
+
+// GetValidated returns the value decoded and validated by the Validate middleware of the same T.
+func GetValidated[T any](ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(validatedKey[T]{}).(T)
+	return v, ok
+}
+
+// fieldViolations splits a validation error into the violations it is made of.
+func fieldViolations(err error) []FieldViolation {
+	type fieldError interface {
+		error
+		Field() string
+	}
+
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else if rv := reflect.ValueOf(err); rv.Kind() == reflect.Slice {
+		for i := range rv.Len() {
+			if e, ok := rv.Index(i).Interface().(error); ok {
+				errs = append(errs, e)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		errs = []error{err}
+	}
+
+	violations := make([]FieldViolation, 0, len(errs))
+	for _, e := range errs {
+		var violation FieldViolation
+		if fe, ok := e.(fieldError); ok {
+			violation.Field = fe.Field()
+		}
+		violation.Message = e.Error()
+		violations = append(violations, violation)
+	}
+	return violations
+}
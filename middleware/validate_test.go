@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+type signup struct {
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func (s signup) Validate() error {
+	var errs []error
+	if !strings.Contains(s.Email, "@") {
+		errs = append(errs, errors.New("email is invalid"))
+	}
+	if s.Age < 18 {
+		errs = append(errs, errors.New("age must be at least 18"))
+	}
+	return errors.Join(errs...)
+}
+
+// fieldErr and fieldErrs mimic validator.FieldError and validator.ValidationErrors.
+type fieldErr struct{ field, tag string }
+
+func (e fieldErr) Field() string { return e.field }
+func (e fieldErr) Error() string {
+	return fmt.Sprintf("Field validation for '%s' failed on the '%s' tag", e.field, e.tag)
+}
+
+type fieldErrs []fieldErr
+
+func (e fieldErrs) Error() string { return "validation failed" }
+
+type structValidator func(any) error
+
+func (f structValidator) Struct(v any) error { return f(v) }
+
+func TestValidate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := middleware.GetValidated[signup](r.Context())
+		if !ok {
+			t.Error("validated value missing from context")
+		}
+		fmt.Fprint(w, v.Email)
+	})
+	playground := middleware.WithValidator(structValidator(func(v any) error {
+		if v.(signup).Email == "" {
+			return fieldErrs{{"Email", "required"}}
+		}
+		return nil
+	}))
+
+	testCases := []struct {
+		name       string
+		opts       []middleware.ValidateOption
+		body       string
+		status     int
+		violations []middleware.FieldViolation
+	}{
+		{"valid", nil, `{"email":"a@b.c","age":20}`, http.StatusOK, nil},
+		{"self_validation", nil, `{"email":"nope","age":3}`, http.StatusUnprocessableEntity, []middleware.FieldViolation{
+			{Message: "email is invalid"}, {Message: "age must be at least 18"},
+		}},
+		{"struct_validator", []middleware.ValidateOption{playground}, `{"age":3}`, http.StatusUnprocessableEntity, []middleware.FieldViolation{
+			{Field: "Email", Message: "Field validation for 'Email' failed on the 'required' tag"},
+		}},
+		{"struct_validator_replaces_self_validation", []middleware.ValidateOption{playground}, `{"email":"nope","age":3}`, http.StatusOK, nil},
+		{"malformed", nil, `{"email":`, http.StatusBadRequest, nil},
+		{"empty", nil, ``, http.StatusBadRequest, nil},
+		{"unknown_field", []middleware.ValidateOption{middleware.WithDisallowUnknownFields()}, `{"email":"a@b.c","age":20,"admin":true}`, http.StatusBadRequest, nil},
+		{"too_large", []middleware.ValidateOption{middleware.WithValidateMaxBody(8)}, `{"email":"a@b.c","age":20}`, http.StatusRequestEntityTooLarge, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			middleware.Validate[signup](tc.opts...)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tc.body)))
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.violations == nil {
+				return
+			}
+			var body struct {
+				Errors []middleware.FieldViolation `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(body.Errors, tc.violations) {
+				t.Errorf("got violations %+v, want %+v", body.Errors, tc.violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ResponseWriter is an http.ResponseWriter recording the status code and the number of body bytes
+// written through it. Middlewares needing either, such as loggers and metrics, share it instead
+// of each wrapping the writer on their own.
+//
+// It passes flushing, hijacking and HTTP/2 server push through to the wrapped writer, so
+// streaming responses and WebSocket upgrades keep working behind it. When the wrapped writer
+// does not support them, Flush does nothing and Hijack and Push return http.ErrNotSupported.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status returns the status code of the response, http.StatusOK if none was written explicitly.
+	Status() int
+	// BytesWritten returns the number of body bytes written.
+	BytesWritten() int64
+	// Written reports whether the response was started, i.e. its header or body was written or
+	// flushed, or the connection hijacked. Informational (1xx) responses do not start it.
+	Written() bool
+	// Unwrap returns the wrapped http.ResponseWriter, for use by http.ResponseController.
+	Unwrap() http.ResponseWriter
+}
+
+// WrapResponseWriter wraps w in a ResponseWriter. If w already is one, it is returned as is.
+func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	return newResponseRecorder(w)
+}
+
+// responseRecorderPool recycles the writers of AcquireResponseWriter.
+var responseRecorderPool = sync.Pool{New: func() any { return new(responseRecorder) }}
+
+// AcquireResponseWriter is like WrapResponseWriter, taking the wrapper from a pool instead of
+// allocating it. Middlewares on the hot path use it along with ReleaseResponseWriter:
+//
+//	rw := middleware.AcquireResponseWriter(w)
+//	defer middleware.ReleaseResponseWriter(rw, w)
+//	next.ServeHTTP(rw, r)
+func AcquireResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	rw := responseRecorderPool.Get().(*responseRecorder)
+	*rw = responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	return rw
+}
+
+// ReleaseResponseWriter returns rw, acquired with AcquireResponseWriter(w), to the pool. It does
+// nothing if rw was not allocated for w, i.e. if w already was a ResponseWriter. Neither the
+// middleware nor the handlers it called may use rw afterwards, so it must not be released while
+// a goroutine started by a handler may still write to it.
+func ReleaseResponseWriter(rw ResponseWriter, w http.ResponseWriter) {
+	if _, ok := w.(ResponseWriter); ok {
+		return
+	}
+	rr, ok := rw.(*responseRecorder)
+	if !ok {
+		return
+	}
+	*rr = responseRecorder{}
+	responseRecorderPool.Put(rr)
+}
+
+// responseRecorder is the ResponseWriter implementation returned by WrapResponseWriter.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rw *responseRecorder) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	// Informational responses, such as 103 Early Hints, precede the final one.
+	if code >= 100 && code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	rw.status = code
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+func (rw *responseRecorder) Status() int {
+	return rw.status
+}
+
+func (rw *responseRecorder) BytesWritten() int64 {
+	return rw.bytes
+}
+
+func (rw *responseRecorder) Written() bool {
+	return rw.wroteHeader
+}
+
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *responseRecorder) Flush() {
+	if err := http.NewResponseController(rw.ResponseWriter).Flush(); err == nil {
+		rw.wroteHeader = true
+	}
+}
+
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && !rw.wroteHeader {
+		// The connection now belongs to the handler, e.g. for a WebSocket upgrade.
+		rw.status = http.StatusSwitchingProtocols
+		rw.wroteHeader = true
+	}
+	return conn, buf, err
+}
+
+func (rw *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,178 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func TestWrapResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := middleware.WrapResponseWriter(rec)
+	if middleware.WrapResponseWriter(rw) != rw {
+		t.Error("wrapping a ResponseWriter again must return it as is")
+	}
+
+	if rw.Written() {
+		t.Error("the response must not be started before writing")
+	}
+	rw.WriteHeader(http.StatusAccepted)
+	rw.WriteHeader(http.StatusInternalServerError)
+	io.WriteString(rw, "hello")
+
+	if rw.Status() != http.StatusAccepted || rec.Code != http.StatusAccepted {
+		t.Errorf("got status %d, recorded %d, want %d", rw.Status(), rec.Code, http.StatusAccepted)
+	}
+	if !rw.Written() {
+		t.Error("expected the response to be started")
+	}
+	if rw.BytesWritten() != 5 {
+		t.Errorf("got %d bytes written, want 5", rw.BytesWritten())
+	}
+	if rw.Unwrap() != rec {
+		t.Error("Unwrap must return the wrapped writer")
+	}
+}
+
+// plainWriter is an http.ResponseWriter supporting none of the optional interfaces.
+type plainWriter struct{ http.ResponseWriter }
+
+func TestWrapResponseWriterPassthrough(t *testing.T) {
+	t.Run("flush", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		var w http.ResponseWriter = middleware.WrapResponseWriter(rec)
+		w.(http.Flusher).Flush()
+		if !rec.Flushed {
+			t.Error("expected the wrapped writer to be flushed")
+		}
+	})
+
+	t.Run("flush_through_wrappers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		// A writer wrapping rec with an Unwrap method, as other middlewares' writers do.
+		inner := middleware.WrapResponseWriter(rec)
+		middleware.WrapResponseWriter(struct {
+			http.ResponseWriter
+			unwrapper
+		}{inner, unwrapper{inner}}).Flush()
+		if !rec.Flushed {
+			t.Error("expected the innermost writer to be flushed")
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		rw := middleware.WrapResponseWriter(plainWriter{httptest.NewRecorder()})
+		rw.Flush()
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("got hijack error %v, want %v", err, http.ErrNotSupported)
+		}
+		if err := rw.Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("got push error %v, want %v", err, http.ErrNotSupported)
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		// statusc hands the recorded status over from the handler goroutine.
+		statusc := make(chan int, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := middleware.WrapResponseWriter(w)
+			conn, buf, err := rw.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				statusc <- 0
+				return
+			}
+			defer conn.Close()
+			if !rw.Written() {
+				t.Error("a hijacked connection must count as started")
+			}
+			statusc <- rw.Status()
+			buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			buf.Flush()
+		}))
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, http.NoBody)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if status := <-statusc; resp.StatusCode != http.StatusSwitchingProtocols || status != http.StatusSwitchingProtocols {
+			t.Errorf("got response status %d, recorded %d, want %d", resp.StatusCode, status, http.StatusSwitchingProtocols)
+		}
+	})
+}
+
+// unwrapper exposes a wrapped writer to http.ResponseController.
+type unwrapper struct{ w http.ResponseWriter }
+
+func (u unwrapper) Unwrap() http.ResponseWriter { return u.w }
+
+func TestAcquireResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := middleware.AcquireResponseWriter(rec)
+	rw.WriteHeader(http.StatusCreated)
+	rw.Write([]byte("hi"))
+
+	// A nested middleware gets the same writer, and releasing it must not reset it.
+	inner := middleware.AcquireResponseWriter(rw)
+	if inner != rw {
+		t.Fatal("acquiring a ResponseWriter again must return it as is")
+	}
+	middleware.ReleaseResponseWriter(inner, rw)
+	if rw.Status() != http.StatusCreated || rw.BytesWritten() != 2 {
+		t.Errorf("releasing a nested writer reset it: got status %d and %d bytes", rw.Status(), rw.BytesWritten())
+	}
+	middleware.ReleaseResponseWriter(rw, rec)
+
+	rw = middleware.AcquireResponseWriter(httptest.NewRecorder())
+	if rw.Status() != http.StatusOK || rw.BytesWritten() != 0 {
+		t.Errorf("got recycled writer with status %d and %d bytes", rw.Status(), rw.BytesWritten())
+	}
+}
+
+func BenchmarkInstrumentedChain(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := bytes.Repeat([]byte("rahjoo "), 512)
+	handler := middleware.Chain(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(body)
+		}),
+		middleware.Logger(logger),
+		middleware.AccessLog(io.Discard, middleware.CommonLogFormat),
+		middleware.SlowRequestLog(time.Minute, logger),
+		middleware.Compress(gzip.BestSpeed),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	w := discardResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		clear(w.header)
+		handler.ServeHTTP(w, req)
+	}
+}
+
+// discardResponseWriter is a response writer discarding what is written, so benchmarks only
+// measure the middlewares.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardResponseWriter) WriteHeader(int)             {}
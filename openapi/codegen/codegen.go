@@ -0,0 +1,428 @@
+// Package codegen generates rahjoo route wiring from OpenAPI 3 documents: a typed server
+// interface with a method per operation, and a Routes function binding its methods to the
+// operations, with path parameters parsed into typed arguments and JSON request bodies
+// validated against their schemas by the jsonschema middleware.
+//
+// For the document
+//
+//	{"openapi": "3.1.0", "paths": {"/users/{id}": {"get": {"operationId": "getUser",
+//	  "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}]}}}}
+//
+// it generates, among others:
+//
+//	type ServerInterface interface {
+//		// GetUser handles GET /users/{id}.
+//		GetUser(w http.ResponseWriter, r *http.Request, id int64)
+//	}
+//
+//	func Routes(si ServerInterface) rahjoo.Route
+//
+// Integer path parameters are passed as int64, string ones with the uuid or date-time format as
+// [16]byte and time.Time, and others as strings. Requests with malformed path parameters are
+// answered with 400 Bad Request as problem details. Query parameters, headers and responses are
+// left to the handlers.
+//
+// The cmd/rahjoo-gen command wraps Generate.
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// methods lists the operations of a path item in the order they are generated in.
+var methods = []string{"get", "head", "post", "put", "patch", "delete", "options", "trace"}
+
+type document struct {
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components map[string]any                        `json:"components"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema map[string]any `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref    string `json:"$ref"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+	Schema struct {
+		Type   string `json:"type"`
+		Format string `json:"format"`
+	} `json:"schema"`
+}
+
+type options struct {
+	unmarshal func(data []byte, v any) error
+}
+
+// Option configures Generate.
+type Option func(*options)
+
+// WithUnmarshaler parses the OpenAPI document with unmarshal instead of json.Unmarshal, e.g. with
+// yaml.Unmarshal of gopkg.in/yaml.v3. unmarshal must decode mappings into map[string]any.
+func WithUnmarshaler(unmarshal func(data []byte, v any) error) Option {
+	return func(o *options) {
+		o.unmarshal = unmarshal
+	}
+}
+
+// Generate returns the gofmt-ed source of package pkg wiring the operations of the OpenAPI
+// document spec.
+func Generate(spec []byte, pkg string, opts ...Option) ([]byte, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.unmarshal != nil {
+		var raw any
+		if err := o.unmarshal(spec, &raw); err != nil {
+			return nil, fmt.Errorf("codegen: %w", err)
+		}
+		var err error
+		if spec, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("codegen: %w", err)
+		}
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("codegen: invalid package name %q", pkg)
+	}
+
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("codegen: %w", err)
+	}
+	f, err := doc.file(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, fmt.Errorf("codegen: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("codegen: formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// file is the data of the generated file.
+type file struct {
+	Package    string
+	BasePath   string
+	Operations []op
+	Paths      []pathOps
+	HasTime    bool
+	HasParsers bool
+	HasSchemas bool
+}
+
+type pathOps struct {
+	Path       string
+	Operations []op
+}
+
+type op struct {
+	Name       string
+	ID         string
+	HTTPMethod string
+	Method     string
+	Path       string
+	Summary    string
+	Params     []param
+	SchemaVar  string
+	SchemaJSON string
+}
+
+type param struct {
+	Name   string
+	Var    string
+	Type   string
+	Parser string
+}
+
+func (d *document) file(pkg string) (*file, error) {
+	f := &file{Package: pkg}
+	if len(d.Servers) > 0 {
+		if u, err := url.Parse(d.Servers[0].URL); err == nil && strings.Trim(u.Path, "/") != "" {
+			f.BasePath = "/" + strings.Trim(u.Path, "/")
+		}
+	}
+
+	names := map[string]string{}
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		item := d.Paths[path]
+		var shared []parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("codegen: parameters of %s: %w", path, err)
+			}
+		}
+
+		po := pathOps{Path: path}
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var o operation
+			if err := json.Unmarshal(raw, &o); err != nil {
+				return nil, fmt.Errorf("codegen: %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			generated, err := d.operation(method, path, o, shared)
+			if err != nil {
+				return nil, err
+			}
+			if other, dup := names[generated.Name]; dup {
+				return nil, fmt.Errorf("codegen: %s %s and %s both generate method %s", strings.ToUpper(method), path, other, generated.Name)
+			}
+			names[generated.Name] = strings.ToUpper(method) + " " + path
+
+			for _, p := range generated.Params {
+				f.HasTime = f.HasTime || p.Type == "time.Time"
+				f.HasParsers = f.HasParsers || p.Parser != ""
+			}
+			f.HasSchemas = f.HasSchemas || generated.SchemaVar != ""
+			po.Operations = append(po.Operations, generated)
+			f.Operations = append(f.Operations, generated)
+		}
+		if len(po.Operations) > 0 {
+			f.Paths = append(f.Paths, po)
+		}
+	}
+	return f, nil
+}
+
+func (d *document) operation(method, path string, o operation, shared []parameter) (op, error) {
+	name := o.OperationID
+	if name == "" {
+		name = method + " " + path
+	}
+	generated := op{
+		Name:       exportedIdent(name),
+		ID:         o.OperationID,
+		HTTPMethod: strings.ToUpper(method),
+		Method:     "http.Method" + exportedIdent(method),
+		Path:       path,
+		Summary:    strings.Join(strings.Fields(o.Summary), " "),
+	}
+	if generated.Name == "" {
+		return op{}, fmt.Errorf("codegen: %s %s: can not derive a method name", strings.ToUpper(method), path)
+	}
+
+	// Operation parameters override the path item ones with the same name and location.
+	params := map[string]parameter{}
+	var order []string
+	for _, p := range slices.Concat(shared, o.Parameters) {
+		p, err := d.resolveParameter(p)
+		if err != nil {
+			return op{}, fmt.Errorf("codegen: %s %s: %w", strings.ToUpper(method), path, err)
+		}
+		if p.In != "path" {
+			continue
+		}
+		if _, ok := params[p.Name]; !ok {
+			order = append(order, p.Name)
+		}
+		params[p.Name] = p
+	}
+	for _, name := range order {
+		p := params[name]
+		gp := param{Name: p.Name, Var: paramIdent(p.Name), Type: "string"}
+		switch {
+		case p.Schema.Type == "integer":
+			gp.Type, gp.Parser = "int64", "rahjoo.ParamInt64"
+		case p.Schema.Type == "string" && p.Schema.Format == "uuid":
+			gp.Type, gp.Parser = "[16]byte", "rahjoo.ParamUUID"
+		case p.Schema.Type == "string" && p.Schema.Format == "date-time":
+			gp.Type, gp.Parser = "time.Time", "rahjoo.ParamTime"
+		}
+		generated.Params = append(generated.Params, gp)
+	}
+
+	if o.RequestBody != nil {
+		if content, ok := o.RequestBody.Content["application/json"]; ok && content.Schema != nil {
+			schema := content.Schema
+			if schemas, ok := d.Components["schemas"]; ok {
+				// Embed the component schemas so "#/components/schemas/..." references resolve.
+				schema = map[string]any{
+					"allOf":      []any{content.Schema},
+					"components": map[string]any{"schemas": schemas},
+				}
+			}
+			data, err := json.Marshal(schema)
+			if err != nil {
+				return op{}, fmt.Errorf("codegen: %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			generated.SchemaVar = unexportedIdent(generated.Name) + "Schema"
+			generated.SchemaJSON = goString(string(data))
+		}
+	}
+	return generated, nil
+}
+
+// resolveParameter follows the reference of p to the components of the document, if any.
+func (d *document) resolveParameter(p parameter) (parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if !ok {
+		return p, fmt.Errorf("unsupported parameter reference %q", p.Ref)
+	}
+	params, _ := d.Components["parameters"].(map[string]any)
+	raw, ok := params[name]
+	if !ok {
+		return p, fmt.Errorf("unresolvable parameter reference %q", p.Ref)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return p, err
+	}
+	var resolved parameter
+	err = json.Unmarshal(data, &resolved)
+	return resolved, err
+}
+
+// exportedIdent turns s, such as "getUser", "get_user" or "get /users/{id}", into an exported
+// Go identifier, "GetUser", "GetUser" and "GetUsersId" here.
+func exportedIdent(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(c) {
+			b.WriteString("Op")
+		}
+		if upper {
+			c = unicode.ToUpper(c)
+			upper = false
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// unexportedIdent lowercases the first letter of the exported identifier s.
+func unexportedIdent(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// paramIdent turns the parameter name s into a Go variable name.
+func paramIdent(s string) string {
+	name := unexportedIdent(exportedIdent(s))
+	if token.IsKeyword(name) || name == "w" || name == "r" || name == "err" || name == "si" {
+		name += "Param"
+	}
+	return name
+}
+
+// goString returns s as a Go string literal, raw if possible.
+func goString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by rahjoo-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"net/http"
+	{{- if .HasTime}}
+	"time"
+	{{- end}}
+
+	"github.com/amirzayi/rahjoo"
+	{{- if .HasSchemas}}
+	"github.com/amirzayi/rahjoo/middleware/jsonschema"
+	{{- end}}
+	{{- if .HasParsers}}
+	"github.com/amirzayi/rahjoo/problem"
+	{{- end}}
+)
+{{if .BasePath}}
+// BasePath is the path of the first server of the API, for mounting Routes with
+// rahjoo.NewGroupRoute(BasePath, Routes(si)).
+const BasePath = {{printf "%q" .BasePath}}
+{{end}}
+// ServerInterface is implemented by the handlers of the API operations.
+type ServerInterface interface {
+{{- range .Operations}}
+	// {{.Name}} handles {{.HTTPMethod}} {{.Path}}.{{if .Summary}}
+	// {{.Summary}}{{end}}
+	{{.Name}}(w http.ResponseWriter, r *http.Request{{range .Params}}, {{.Var}} {{.Type}}{{end}})
+{{- end}}
+}
+{{range .Operations}}{{if .SchemaVar}}
+// {{.SchemaVar}} is the schema of the {{.Name}} request body.
+var {{.SchemaVar}} = jsonschema.MustCompile([]byte({{.SchemaJSON}}))
+{{end}}{{end}}
+// Routes wires the methods of si to the API operations.
+func Routes(si ServerInterface) rahjoo.Route {
+	return rahjoo.Route{
+{{- range .Paths}}
+		{{printf "%q" .Path}}: {
+{{- range .Operations}}
+			{{.Method}}: rahjoo.NewHandler(
+{{- if .Params -}}
+			func(w http.ResponseWriter, r *http.Request) {
+{{- range .Params}}
+{{- if .Parser}}
+				{{.Var}}, err := {{.Parser}}(r, {{printf "%q" .Name}})
+				if err != nil {
+					problem.Write(w, http.StatusBadRequest, problem.WithDetail(err.Error()))
+					return
+				}
+{{- else}}
+				{{.Var}} := r.PathValue({{printf "%q" .Name}})
+{{- end}}
+{{- end}}
+				si.{{.Name}}(w, r{{range .Params}}, {{.Var}}{{end}})
+			}
+{{- else -}}
+			si.{{.Name}}
+{{- end}}
+{{- if .SchemaVar}}, jsonschema.Validate({{.SchemaVar}}){{end}})
+{{- if .ID}}.WithName({{printf "%q" .ID}}){{end}},
+{{- end}}
+		},
+{{- end}}
+	}
+}
+`))
@@ -0,0 +1,105 @@
+package codegen_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/openapi/codegen"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	spec, err := os.ReadFile(filepath.Join("testdata", "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := codegen.Generate(spec, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "users.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "users.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s (run go test -update to update it):\n%s", golden, src)
+	}
+}
+
+func TestGenerateMinimal(t *testing.T) {
+	src, err := codegen.Generate([]byte(`{"paths": {"/ping": {"get": {}}}}`), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GetPing(w http.ResponseWriter, r *http.Request)",
+		"http.MethodGet: rahjoo.NewHandler(si.GetPing),",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"BasePath", "jsonschema", "problem", `"time"`} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated code contains %q:\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec string
+		pkg  string
+		err  string
+	}{
+		{"bad_package", `{"paths": {}}`, "my-api", "invalid package name"},
+		{"bad_json", `{"paths": [`, "api", "unexpected end"},
+		{"duplicate_names", `{"paths": {"/a": {"get": {"operationId": "get_a"}}, "/b": {"get": {"operationId": "getA"}}}}`, "api", "both generate method GetA"},
+		{"bad_parameter_ref", `{"paths": {"/a/{x}": {"get": {"parameters": [{"$ref": "#/components/parameters/X"}]}}}}`, "api", "unresolvable parameter reference"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := codegen.Generate([]byte(tc.spec), tc.pkg)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want one containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestWithUnmarshaler(t *testing.T) {
+	fakeYAML := func(data []byte, v any) error {
+		if string(data) != "paths: ..." {
+			return errors.New("bad yaml")
+		}
+		*v.(*any) = map[string]any{"paths": map[string]any{"/ping": map[string]any{"get": map[string]any{"operationId": "ping"}}}}
+		return nil
+	}
+	src, err := codegen.Generate([]byte("paths: ..."), "api", codegen.WithUnmarshaler(fakeYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "Ping(w http.ResponseWriter, r *http.Request)") {
+		t.Errorf("unexpected generated code:\n%s", src)
+	}
+}
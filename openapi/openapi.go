@@ -0,0 +1,139 @@
+// Package openapi serves an OpenAPI document along with a Swagger UI or Redoc page to browse it:
+//
+//	//go:embed openapi.json
+//	var spec []byte
+//
+//	docs := openapi.UIRoutes(openapi.SpecHandler(spec), openapi.WithTitle("Users API")).
+//		SetMiddleware(middleware.BasicAuth("docs", middleware.BasicAuthUsers(staff)))
+//	rahjoo.BindRoutesToMux(mux, api, docs)
+//
+// The page itself is embedded in the package; the UI scripts and styles are loaded from a CDN
+// unless WithAssetsURL points at a self-hosted copy.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/serve"
+)
+
+// DefaultPath is the path the documentation is served under unless set by WithPath.
+const DefaultPath = "/docs"
+
+// SpecPath is the path of the OpenAPI document, relative to the documentation path.
+const SpecPath = "/openapi.json"
+
+// UI is a documentation viewer.
+type UI string
+
+const (
+	// SwaggerUI renders the document with Swagger UI, which can also send requests.
+	SwaggerUI UI = "swagger"
+	// Redoc renders the document with Redoc as a three-panel reference.
+	Redoc UI = "redoc"
+)
+
+var defaultAssetsURL = map[UI]string{
+	SwaggerUI: "https://unpkg.com/swagger-ui-dist@5",
+	Redoc:     "https://cdn.jsdelivr.net/npm/redoc@2/bundles",
+}
+
+//go:embed ui.html
+var page string
+
+var pageTemplate = template.Must(template.New("ui").Parse(page))
+
+type config struct {
+	path      string
+	ui        UI
+	title     string
+	assetsURL string
+}
+
+// Option configures the documentation routes.
+type Option func(*config)
+
+// WithPath serves the documentation page at path+"/" and the document at path+SpecPath. It
+// defaults to DefaultPath.
+func WithPath(path string) Option {
+	return func(c *config) {
+		c.path = path
+	}
+}
+
+// WithUI selects the documentation viewer. It defaults to SwaggerUI.
+func WithUI(ui UI) Option {
+	return func(c *config) {
+		c.ui = ui
+	}
+}
+
+// WithTitle sets the title of the documentation page. It defaults to "API documentation".
+func WithTitle(title string) Option {
+	return func(c *config) {
+		c.title = title
+	}
+}
+
+// WithAssetsURL loads the viewer from url instead of the public CDN, e.g. a directory serving a
+// copy of the swagger-ui-dist package for SwaggerUI or of the redoc bundles for Redoc.
+func WithAssetsURL(url string) Option {
+	return func(c *config) {
+		c.assetsURL = url
+	}
+}
+
+// UIRoutes returns a Route serving the documentation page at the documentation path and spec,
+// typically from SpecHandler, next to it at SpecPath. The page refers to the document by a
+// relative URL, so the routes keep working when mounted under a prefix. Being a regular Route,
+// it can be protected with the router's own middlewares.
+//
+// UIRoutes panics if the UI is unknown.
+func UIRoutes(spec http.Handler, opts ...Option) rahjoo.Route {
+	cfg := config{
+		path:  DefaultPath,
+		ui:    SwaggerUI,
+		title: "API documentation",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if _, ok := defaultAssetsURL[cfg.ui]; !ok {
+		panic("openapi: unknown UI " + string(cfg.ui))
+	}
+	if cfg.assetsURL == "" {
+		cfg.assetsURL = defaultAssetsURL[cfg.ui]
+	}
+
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, map[string]string{
+		"UI":        string(cfg.ui),
+		"Title":     cfg.title,
+		"AssetsURL": cfg.assetsURL,
+		"SpecURL":   "." + SpecPath,
+	})
+	if err != nil {
+		panic("openapi: " + err.Error())
+	}
+	html := buf.Bytes()
+
+	return rahjoo.NewGroupRoute(cfg.path, rahjoo.Route{
+		"/{$}": {http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+			serve.Content(w, r, "index.html", time.Time{}, bytes.NewReader(html))
+		})},
+		SpecPath: {http.MethodGet: rahjoo.NewHandler(spec.ServeHTTP)},
+	})
+}
+
+// SpecHandler returns a handler serving spec, an OpenAPI document in JSON, with an ETag so
+// browsers revalidate it instead of downloading it again.
+func SpecHandler(spec []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve.Content(w, r, "openapi.json", time.Time{}, bytes.NewReader(spec), serve.WithContentType("application/json"))
+	})
+}
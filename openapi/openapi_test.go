@@ -0,0 +1,88 @@
+package openapi_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/openapi"
+	"github.com/amirzayi/rahjoo/rahjootest"
+)
+
+const spec = `{"openapi":"3.1.0","info":{"title":"Users","version":"1"},"paths":{}}`
+
+func TestUIRoutes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []openapi.Option
+		path     string
+		contains []string
+	}{
+		{
+			name:     "swagger_ui",
+			path:     "/docs/",
+			contains: []string{"<title>API documentation</title>", "https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js", `url: "./openapi.json"`},
+		},
+		{
+			name:     "redoc",
+			opts:     []openapi.Option{openapi.WithUI(openapi.Redoc), openapi.WithTitle("Users <API>")},
+			path:     "/docs/",
+			contains: []string{"<title>Users &lt;API&gt;</title>", `<redoc spec-url="./openapi.json">`, "redoc.standalone.js"},
+		},
+		{
+			name:     "custom_path_and_assets",
+			opts:     []openapi.Option{openapi.WithPath("/api/reference"), openapi.WithAssetsURL("/static/swagger")},
+			path:     "/api/reference/",
+			contains: []string{`href="/static/swagger/swagger-ui.css"`, `src="/static/swagger/swagger-ui-bundle.js"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := rahjootest.New(t, openapi.UIRoutes(openapi.SpecHandler([]byte(spec)), tc.opts...))
+			req := c.Get(tc.path).ExpectStatus(http.StatusOK).ExpectHeader("Content-Type", "text/html; charset=utf-8")
+			body := req.Body()
+			for _, s := range tc.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("page does not contain %q:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecHandler(t *testing.T) {
+	c := rahjootest.New(t, openapi.UIRoutes(openapi.SpecHandler([]byte(spec))))
+	req := c.Get("/docs/openapi.json").ExpectStatus(http.StatusOK).ExpectHeader("Content-Type", "application/json").ExpectBody(spec)
+
+	etag := req.Response().Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	c.Get("/docs/openapi.json").WithHeader("If-None-Match", etag).ExpectStatus(http.StatusNotModified)
+}
+
+func TestUIRoutesMiddleware(t *testing.T) {
+	docs := openapi.UIRoutes(openapi.SpecHandler([]byte(spec))).
+		SetMiddleware(middleware.BasicAuth("docs", middleware.BasicAuthUsers(map[string]string{"dev": "secret"})))
+	c := rahjootest.New(t, docs, rahjoo.Route{"/users": {http.MethodGet: rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})}})
+
+	c.Get("/docs/").ExpectStatus(http.StatusUnauthorized)
+	c.Get("/docs/openapi.json").ExpectStatus(http.StatusUnauthorized)
+	c.Get("/users").ExpectStatus(http.StatusOK)
+
+	c.SetHeader("Authorization", "Basic ZGV2OnNlY3JldA==")
+	c.Get("/docs/").ExpectStatus(http.StatusOK)
+	c.Get("/docs/openapi.json").ExpectStatus(http.StatusOK)
+}
+
+func TestUIRoutesUnknownUI(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	openapi.UIRoutes(openapi.SpecHandler([]byte(spec)), openapi.WithUI("rapidoc"))
+}
@@ -0,0 +1,120 @@
+package rahjoo
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PaginationDefaults configures Pagination.
+type PaginationDefaults struct {
+	// Limit is the page size used when the request does not set one. It defaults to 20.
+	Limit int
+	// MaxLimit caps the page size a request can ask for. It defaults to 100.
+	MaxLimit int
+}
+
+// Page is the slice of a list requested with limit/offset or page/per_page query parameters.
+type Page struct {
+	// Limit is the maximum number of items of the page.
+	Limit int
+	// Offset is the number of items skipped before the page.
+	Offset int
+
+	// numbered records the request used page/per_page, so links use them too.
+	numbered bool
+}
+
+// Number returns the 1-based number of the page.
+func (p Page) Number() int {
+	return p.Offset/p.Limit + 1
+}
+
+// Pagination parses the page requested by the query parameters of r, either limit and offset
+// ("?limit=20&offset=40") or page and per_page ("?page=3&per_page=20"), the latter taking
+// precedence. Missing parameters get the defaults and page sizes above defaults.MaxLimit are
+// capped. Invalid values, including pages too far for their offset to fit an int, are returned
+// as BindErrors, answered with 400 Bad Request by middleware.ErrorHandler.
+func Pagination(r *http.Request, defaults PaginationDefaults) (Page, error) {
+	if defaults.Limit <= 0 {
+		defaults.Limit = 20
+	}
+	if defaults.MaxLimit <= 0 {
+		defaults.MaxLimit = 100
+	}
+	query := r.URL.Query()
+	var errs BindErrors
+	param := func(name string, fallback, min int) int {
+		v := query.Get(name)
+		if v == "" {
+			return fallback
+		}
+		n, err := strconv.Atoi(v)
+		switch {
+		case err != nil:
+			err = errors.New("invalid integer")
+		case n < min:
+			err = errors.New("must be at least " + strconv.Itoa(min))
+		}
+		if err != nil {
+			errs = append(errs, &BindError{Source: "query", Name: name, Value: v, Err: err})
+		}
+		return n
+	}
+
+	var p Page
+	if query.Has("page") {
+		number := param("page", 1, 1)
+		p.Limit = min(param("per_page", defaults.Limit, 1), defaults.MaxLimit)
+		if p.Limit > 0 && number-1 > math.MaxInt/p.Limit {
+			errs = append(errs, &BindError{Source: "query", Name: "page", Value: query.Get("page"), Err: errors.New("too large")})
+		}
+		p.Offset = (number - 1) * p.Limit
+		p.numbered = true
+	} else {
+		p.Limit = min(param("limit", defaults.Limit, 1), defaults.MaxLimit)
+		p.Offset = param("offset", 0, 0)
+	}
+	if len(errs) > 0 {
+		return Page{}, errs
+	}
+	return p, nil
+}
+
+// SetPaginationHeaders sets the X-Total-Count header to total and the Link header to the
+// first, prev, next and last pages around p, built from the URL of r with the same style of
+// query parameters, so clients can walk a list without computing URLs.
+func SetPaginationHeaders(w http.ResponseWriter, r *http.Request, p Page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = (total - 1) / p.Limit * p.Limit
+	}
+	links := []string{pageLink(r, p, 0, "first")}
+	if p.Offset > 0 {
+		links = append(links, pageLink(r, p, max(p.Offset-p.Limit, 0), "prev"))
+	}
+	if p.Offset+p.Limit < total {
+		links = append(links, pageLink(r, p, p.Offset+p.Limit, "next"))
+	}
+	links = append(links, pageLink(r, p, lastOffset, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// pageLink returns the Link header element of the page at offset with relation rel.
+func pageLink(r *http.Request, p Page, offset int, rel string) string {
+	query := r.URL.Query()
+	if p.numbered {
+		query.Set("page", strconv.Itoa(offset/p.Limit+1))
+		query.Set("per_page", strconv.Itoa(p.Limit))
+	} else {
+		query.Set("limit", strconv.Itoa(p.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
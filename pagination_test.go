@@ -0,0 +1,109 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestPagination(t *testing.T) {
+	defaults := rahjoo.PaginationDefaults{Limit: 10, MaxLimit: 50}
+	testCases := []struct {
+		name    string
+		target  string
+		limit   int
+		offset  int
+		number  int
+		wantErr []string
+	}{
+		{"defaults", "/items", 10, 0, 1, nil},
+		{"limit_offset", "/items?limit=25&offset=50", 25, 50, 3, nil},
+		{"page_per_page", "/items?page=3&per_page=20", 20, 40, 3, nil},
+		{"page_default_size", "/items?page=2", 10, 10, 2, nil},
+		{"page_precedence", "/items?page=2&limit=5&offset=0", 10, 10, 2, nil},
+		{"capped", "/items?limit=1000", 50, 0, 1, nil},
+		{"invalid", "/items?limit=ten&offset=-1", 0, 0, 0, []string{"limit", "offset"}},
+		{"page_zero", "/items?page=0&per_page=0", 0, 0, 0, []string{"page", "per_page"}},
+		{"page_overflow", "/items?page=9223372036854775807&per_page=50", 0, 0, 0, []string{"page"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := rahjoo.Pagination(httptest.NewRequest(http.MethodGet, tc.target, http.NoBody), defaults)
+			if tc.wantErr != nil {
+				var errs rahjoo.BindErrors
+				if !errors.As(err, &errs) || len(errs) != len(tc.wantErr) {
+					t.Fatalf("got error %v, want errors for %v", err, tc.wantErr)
+				}
+				for i, name := range tc.wantErr {
+					if errs[i].Name != name {
+						t.Errorf("got error for %q, want %q", errs[i].Name, name)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Limit != tc.limit || p.Offset != tc.offset || p.Number() != tc.number {
+				t.Errorf("got limit %d offset %d number %d, want %d %d %d", p.Limit, p.Offset, p.Number(), tc.limit, tc.offset, tc.number)
+			}
+		})
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	testCases := []struct {
+		name   string
+		target string
+		total  int
+		link   string
+	}{
+		{
+			name:   "middle_numbered",
+			target: "/items?page=2&per_page=10&q=go",
+			total:  35,
+			link: `</items?page=1&per_page=10&q=go>; rel="first", </items?page=1&per_page=10&q=go>; rel="prev", ` +
+				`</items?page=3&per_page=10&q=go>; rel="next", </items?page=4&per_page=10&q=go>; rel="last"`,
+		},
+		{
+			name:   "first_offset",
+			target: "/items?limit=10",
+			total:  20,
+			link:   `</items?limit=10&offset=0>; rel="first", </items?limit=10&offset=10>; rel="next", </items?limit=10&offset=10>; rel="last"`,
+		},
+		{
+			name:   "last_offset",
+			target: "/items?limit=10&offset=15",
+			total:  20,
+			link:   `</items?limit=10&offset=0>; rel="first", </items?limit=10&offset=5>; rel="prev", </items?limit=10&offset=10>; rel="last"`,
+		},
+		{
+			name:   "empty",
+			target: "/items",
+			total:  0,
+			link:   `</items?limit=20&offset=0>; rel="first", </items?limit=20&offset=0>; rel="last"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			p, err := rahjoo.Pagination(req, rahjoo.PaginationDefaults{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			rahjoo.SetPaginationHeaders(rec, req, p, tc.total)
+			if got := rec.Header().Get("Link"); got != tc.link {
+				t.Errorf("got Link\n%s\nwant\n%s", got, tc.link)
+			}
+			if got := rec.Header().Get("X-Total-Count"); got == "" {
+				t.Error("expected X-Total-Count header")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package rahjoo
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrMissingParam is the error of a BindError for a path parameter without value.
+var ErrMissingParam = errors.New("missing value")
+
+// ParamInt returns the path parameter name of r as an int.
+// The returned error is a *BindError, answered with 400 Bad Request by middleware.ErrorHandler.
+func ParamInt(r *http.Request, name string) (int, error) {
+	return param(r, name, func(v string) (int, error) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, errors.New("invalid integer")
+		}
+		return n, nil
+	})
+}
+
+// ParamInt64 returns the path parameter name of r as an int64, as ParamInt does.
+func ParamInt64(r *http.Request, name string) (int64, error) {
+	return param(r, name, func(v string) (int64, error) {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.New("invalid integer")
+		}
+		return n, nil
+	})
+}
+
+// ParamUUID returns the path parameter name of r as a UUID in its canonical textual form
+// (e.g. "6ba7b810-9dad-11d1-80b4-00c04fd430c8"), as ParamInt does. The returned array
+// converts to the UUID types of common packages, such as uuid.UUID(id) of github.com/google/uuid.
+func ParamUUID(r *http.Request, name string) ([16]byte, error) {
+	return param(r, name, parseUUID)
+}
+
+// ParamTime returns the path parameter name of r as a time in RFC 3339 format, as ParamInt does.
+func ParamTime(r *http.Request, name string) (time.Time, error) {
+	return param(r, name, func(v string) (time.Time, error) {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, errors.New("invalid RFC 3339 time")
+		}
+		return t, nil
+	})
+}
+
+func param[T any](r *http.Request, name string, parse func(string) (T, error)) (T, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		var zero T
+		return zero, &BindError{Source: "path", Name: name, Err: ErrMissingParam}
+	}
+	t, err := parse(v)
+	if err != nil {
+		return t, &BindError{Source: "path", Name: name, Value: v, Err: err}
+	}
+	return t, nil
+}
+
+func parseUUID(v string) ([16]byte, error) {
+	var id [16]byte
+	if len(v) != 36 || v[8] != '-' || v[13] != '-' || v[18] != '-' || v[23] != '-' {
+		return id, errors.New("invalid UUID")
+	}
+	b := id[:0]
+	for _, part := range []string{v[:8], v[9:13], v[14:18], v[19:23], v[24:]} {
+		var err error
+		if b, err = hex.AppendDecode(b, []byte(part)); err != nil {
+			return [16]byte{}, errors.New("invalid UUID")
+		}
+	}
+	return id, nil
+}
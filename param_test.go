@@ -0,0 +1,71 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+// serveParam serves target through a mux route with a {v} wildcard and returns the value and
+// error fn extracted from the request.
+func serveParam[T any](target string, fn func(*http.Request, string) (T, error)) (got T, err error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{v}", func(w http.ResponseWriter, r *http.Request) {
+		got, err = fn(r, "v")
+	})
+	mux.HandleFunc("/items/", func(w http.ResponseWriter, r *http.Request) {
+		got, err = fn(r, "v")
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	return got, err
+}
+
+func TestParams(t *testing.T) {
+	testCases := []struct {
+		name    string
+		extract func(target string) (any, error)
+		target  string
+		want    any
+		wantErr bool
+	}{
+		{"int", func(p string) (any, error) { return serveParam(p, rahjoo.ParamInt) }, "/items/42", 42, false},
+		{"int_negative", func(p string) (any, error) { return serveParam(p, rahjoo.ParamInt) }, "/items/-7", -7, false},
+		{"int_invalid", func(p string) (any, error) { return serveParam(p, rahjoo.ParamInt) }, "/items/abc", 0, true},
+		{"int_missing", func(p string) (any, error) { return serveParam(p, rahjoo.ParamInt) }, "/items/", 0, true},
+		{"int64", func(p string) (any, error) { return serveParam(p, rahjoo.ParamInt64) }, "/items/9000000000", int64(9000000000), false},
+		{
+			"uuid", func(p string) (any, error) { return serveParam(p, rahjoo.ParamUUID) }, "/items/6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			[16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}, false,
+		},
+		{"uuid_invalid", func(p string) (any, error) { return serveParam(p, rahjoo.ParamUUID) }, "/items/6ba7b810-9dad-11d1-80b4-00c04fd430cz", [16]byte{}, true},
+		{"uuid_no_dashes", func(p string) (any, error) { return serveParam(p, rahjoo.ParamUUID) }, "/items/6ba7b8109dad11d180b400c04fd430c8", [16]byte{}, true},
+		{"time", func(p string) (any, error) { return serveParam(p, rahjoo.ParamTime) }, "/items/2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"time_invalid", func(p string) (any, error) { return serveParam(p, rahjoo.ParamTime) }, "/items/yesterday", time.Time{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.extract(tc.target)
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var bindErr *rahjoo.BindError
+			if !errors.As(err, &bindErr) || bindErr.Source != "path" || bindErr.Name != "v" || bindErr.StatusCode() != http.StatusBadRequest {
+				t.Fatalf("got error %#v, want a path *BindError", err)
+			}
+			if tc.target == "/items/" && !errors.Is(err, rahjoo.ErrMissingParam) {
+				t.Errorf("got error %v, want %v", err, rahjoo.ErrMissingParam)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package rahjoo
+
+import (
+	"net/http"
+
+	"github.com/amirzayi/rahjoo/internal/routectx"
+)
+
+// RoutePattern returns the Path pattern and Method of the route that matched r, e.g.
+// "/users/{id}" instead of the raw "/users/42", so metrics and logging can be labeled
+// without cardinality explosions. The returned Method is empty for routes handling all methods.
+//
+// Within handlers and route middlewares the pattern is always available. Middlewares wrapping
+// the whole mux must pass the request returned by TrackRoute down the chain to read it afterwards.
+// If no route matched, RoutePattern returns empty values.
+func RoutePattern(r *http.Request) (Path, Method) {
+	info := routectx.From(r.Context())
+	if info == nil {
+		return "", ""
+	}
+	return Path(info.Path), Method(info.Method)
+}
+
+// TrackRoute returns a request that, once served by a mux bound with BindRoutesToMux, lets
+// RoutePattern report the matched route to the middleware that called TrackRoute:
+//
+//	r = rahjoo.TrackRoute(r)
+//	next.ServeHTTP(w, r)
+//	path, method := rahjoo.RoutePattern(r)
+func TrackRoute(r *http.Request) *http.Request {
+	r, _ = routectx.With(r)
+	return r
+}
+
+// withRoute records path and method of the matched route in the request context before handler runs.
+func withRoute(path Path, method Method, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, info := routectx.With(r)
+		info.Path, info.Method = string(path), string(method)
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package rahjoo_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestRoutePattern(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		path, method := rahjoo.RoutePattern(r)
+		fmt.Fprintf(w, "%s %s", method, path)
+	}
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMux(mux, rahjoo.NewGroupRoute("/api", rahjoo.Route{
+		"/users/{id}": {http.MethodGet: rahjoo.NewHandler(h)},
+		"/files/":     {"": rahjoo.NewHandler(h)},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outerPath rahjoo.Path
+	var outerMethod rahjoo.Method
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = rahjoo.TrackRoute(r)
+		mux.ServeHTTP(w, r)
+		outerPath, outerMethod = rahjoo.RoutePattern(r)
+	})
+
+	testCases := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/api/users/42", "GET /api/users/{id}"},
+		{http.MethodPost, "/api/files/a/b", " /api/files/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			outer.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, http.NoBody))
+
+			if body := rec.Body.String(); body != tc.body {
+				t.Errorf("got handler pattern %q, want %q", body, tc.body)
+			}
+			if got := fmt.Sprintf("%s %s", outerMethod, outerPath); got != tc.body {
+				t.Errorf("got outer pattern %q, want %q", got, tc.body)
+			}
+		})
+	}
+
+	t.Run("unmatched", func(t *testing.T) {
+		outer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", http.NoBody))
+		if outerPath != "" || outerMethod != "" {
+			t.Errorf("got pattern %q %q, want empty", outerMethod, outerPath)
+		}
+	})
+}
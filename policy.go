@@ -0,0 +1,111 @@
+package rahjoo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/internal/funcname"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// RouteInfo describes a route of a route table to a Policy.
+type RouteInfo struct {
+	Path        Path
+	Method      Method
+	Name        string
+	Middlewares []middleware.Middleware
+}
+
+// Policy checks a route against a rule, returning an error describing the violation if the
+// route breaks it.
+type Policy func(RouteInfo) error
+
+// CheckPolicy checks every route of the route table against policies and returns the
+// violations joined as *RouteError values, or nil. It lets teams enforce security baselines in
+// a unit test:
+//
+//	err := rahjoo.CheckPolicy(routes,
+//	    rahjoo.RequireMiddleware(middleware.Recovery(logger)),
+//	    rahjoo.Unless(rahjoo.RequireMiddleware(auth.Required), rahjoo.PathPrefix("/public/")),
+//	)
+//
+// Only the middlewares of the routes are checked, not the ones given to
+// BindRoutesToMuxWithOptions with WithGlobalMiddleware.
+func CheckPolicy(routes Route, policies ...Policy) error {
+	var errs []error
+	for _, path := range sortedPaths(routes) {
+		for _, method := range sortedMethods(routes[path]) {
+			action := routes[path][method]
+			info := RouteInfo{Path: path, Method: method, Name: action.name, Middlewares: action.middlewares}
+			for _, policy := range policies {
+				if err := policy(info); err != nil {
+					errs = append(errs, &RouteError{Path: path, Method: method, Err: err})
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RequireMiddleware returns a Policy requiring every route to include m.
+// Middlewares are compared by the function implementing them, so a middleware built by a
+// constructor matches any other middleware built by the same constructor, whatever its settings:
+// middleware.RequireScopes("orders:write") is satisfied by middleware.RequireScopes("profile"),
+// though not by middleware.RequireRoles or middleware.Authorize.
+func RequireMiddleware(m middleware.Middleware) Policy {
+	return func(ri RouteInfo) error {
+		if middlewareIndex(ri.Middlewares, m) < 0 {
+			return fmt.Errorf("missing required middleware %s", funcname.Of(m))
+		}
+		return nil
+	}
+}
+
+// RequireMiddlewareOrder returns a Policy requiring first to run before then on every route
+// including then, e.g. recovery before authentication.
+// Middlewares are compared as by RequireMiddleware.
+func RequireMiddlewareOrder(first, then middleware.Middleware) Policy {
+	return func(ri RouteInfo) error {
+		j := middlewareIndex(ri.Middlewares, then)
+		if j < 0 {
+			return nil
+		}
+		if i := middlewareIndex(ri.Middlewares, first); i < 0 || i > j {
+			return fmt.Errorf("middleware %s must run before %s", funcname.Of(first), funcname.Of(then))
+		}
+		return nil
+	}
+}
+
+// Unless returns a Policy applying policy only to the routes for which exempt returns false,
+// e.g. to leave public routes out of an authentication requirement.
+func Unless(policy Policy, exempt func(RouteInfo) bool) Policy {
+	return func(ri RouteInfo) error {
+		if exempt(ri) {
+			return nil
+		}
+		return policy(ri)
+	}
+}
+
+// PathPrefix returns a function for Unless matching the routes whose path begins with any of
+// prefixes.
+func PathPrefix(prefixes ...string) func(RouteInfo) bool {
+	return func(ri RouteInfo) bool {
+		return slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return strings.HasPrefix(string(ri.Path), prefix)
+		})
+	}
+}
+
+// middlewareIndex returns the index of the first middleware of middlewares implemented by the
+// same function as m, or -1.
+func middlewareIndex(middlewares []middleware.Middleware, m middleware.Middleware) int {
+	pc := reflect.ValueOf(m).Pointer()
+	return slices.IndexFunc(middlewares, func(mw middleware.Middleware) bool {
+		return mw != nil && reflect.ValueOf(mw).Pointer() == pc
+	})
+}
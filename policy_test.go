@@ -0,0 +1,94 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+func requireAuth(next http.Handler) http.Handler { return next }
+
+func TestCheckPolicy(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	recovery := middleware.Recovery(log.Default())
+
+	routes := rahjoo.Route{
+		"/public/status": {http.MethodGet: rahjoo.NewHandler(h, recovery)},
+		"/users":         {http.MethodGet: rahjoo.NewHandler(h, recovery, requireAuth)},
+		"/orders":        {http.MethodGet: rahjoo.NewHandler(h, requireAuth, recovery)},
+		"/admin":         {http.MethodPost: rahjoo.NewHandler(h)},
+	}
+
+	err := rahjoo.CheckPolicy(routes,
+		// A distinct Recovery middleware must match as well.
+		rahjoo.RequireMiddleware(middleware.Recovery(nil)),
+		rahjoo.Unless(rahjoo.RequireMiddleware(requireAuth), rahjoo.PathPrefix("/public/")),
+		rahjoo.RequireMiddlewareOrder(recovery, requireAuth),
+	)
+
+	want := []string{
+		`route POST "/admin": missing required middleware middleware.recovery`,
+		`route POST "/admin": missing required middleware rahjoo_test.requireAuth`,
+		`route GET "/orders": middleware middleware.recovery must run before rahjoo_test.requireAuth`,
+	}
+	if got := strings.Split(err.Error(), "\n"); len(got) != len(want) {
+		t.Fatalf("got violations %q, want %q", got, want)
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("violations %q do not contain %q", err, w)
+		}
+	}
+	var routeErr *rahjoo.RouteError
+	if !errors.As(err, &routeErr) {
+		t.Errorf("got error %T, want *rahjoo.RouteError values", err)
+	}
+
+	if err := rahjoo.CheckPolicy(routes, rahjoo.Unless(rahjoo.RequireMiddleware(recovery), rahjoo.PathPrefix("/admin"))); err != nil {
+		t.Errorf("unexpected violations: %v", err)
+	}
+}
+
+func TestCheckPolicyConstructors(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	allow := middleware.AuthorizerFunc(func(*http.Request, string) (bool, error) { return true, nil })
+	authz := map[string]middleware.Middleware{
+		"middleware.Authorize":     middleware.Authorize(allow),
+		"middleware.RequireScopes": middleware.RequireScopes("orders:write"),
+		"middleware.RequireRoles":  middleware.RequireRoles("admin"),
+		"middleware.Casbin":        middleware.Casbin(nil),
+	}
+
+	for name, m := range authz {
+		t.Run(name, func(t *testing.T) {
+			for other, mw := range authz {
+				routes := rahjoo.Route{"/orders": {http.MethodPost: rahjoo.NewHandler(h, mw)}}
+				err := rahjoo.CheckPolicy(routes, rahjoo.RequireMiddleware(m))
+				if other == name && err != nil {
+					t.Errorf("unexpected violations: %v", err)
+				}
+				if other != name && (err == nil || !strings.Contains(err.Error(), "missing required middleware "+name)) {
+					t.Errorf("got violations %v with %s, want missing %s", err, other, name)
+				}
+			}
+		})
+	}
+}
+
+type authenticator struct{}
+
+func (authenticator) Required(next http.Handler) http.Handler { return next }
+
+func TestCheckPolicyMethodValue(t *testing.T) {
+	routes := rahjoo.Route{"/users": {http.MethodGet: rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})}}
+	err := rahjoo.CheckPolicy(routes, rahjoo.RequireMiddleware(authenticator{}.Required))
+	// Named as in route snapshots, without the suffix of method values.
+	if want := "missing required middleware rahjoo_test.authenticator.Required"; err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("got violations %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,161 @@
+// Package problem implements RFC 7807 (RFC 9457) Problem Details, the machine-readable shape
+// shared by the error responses of the router and its middlewares:
+//
+//	problem.Write(w, http.StatusNotFound, problem.WithDetail("user 42 does not exist"))
+//
+// Details is also an error, so handlers adapted with rahjoo.HandlerE can return it directly to
+// the middleware.ErrorHandler.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// DefaultType is the problem type used when none is given, meaning the problem has no
+// semantics beyond its status code.
+const DefaultType = "about:blank"
+
+// Details describes a problem.
+type Details struct {
+	// Type is a URI reference identifying the problem type.
+	Type string `json:"type"`
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members serialized alongside the standard ones,
+	// e.g. the invalid fields of a validation problem.
+	Extensions map[string]any `json:"-"`
+}
+
+// Option configures Details.
+type Option func(*Details)
+
+// WithType sets the problem type URI and its title.
+func WithType(uri, title string) Option {
+	return func(d *Details) {
+		d.Type, d.Title = uri, title
+	}
+}
+
+// WithDetail sets the explanation of the problem occurrence.
+func WithDetail(detail string) Option {
+	return func(d *Details) {
+		d.Detail = detail
+	}
+}
+
+// WithInstance sets the URI of the problem occurrence, usually the request path.
+func WithInstance(instance string) Option {
+	return func(d *Details) {
+		d.Instance = instance
+	}
+}
+
+// WithExtension adds an extension member. Keys of standard members are ignored.
+func WithExtension(key string, value any) Option {
+	return func(d *Details) {
+		if d.Extensions == nil {
+			d.Extensions = map[string]any{}
+		}
+		d.Extensions[key] = value
+	}
+}
+
+// New returns the Details of a problem answered with status. Its type defaults to DefaultType
+// and its title to the status text.
+func New(status int, opts ...Option) *Details {
+	d := &Details{Type: DefaultType, Title: http.StatusText(status), Status: status}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Write writes the problem answered with status to w.
+func Write(w http.ResponseWriter, status int, opts ...Option) error {
+	return New(status, opts...).Write(w)
+}
+
+// Write writes d to w with its status code.
+func (d *Details) Write(w http.ResponseWriter) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(d.Status)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// Error returns the detail of the problem, or its title if it has none.
+func (d *Details) Error() string {
+	if d.Detail != "" {
+		return d.Detail
+	}
+	return d.Title
+}
+
+// StatusCode returns the status code of the problem.
+func (d *Details) StatusCode() int {
+	return d.Status
+}
+
+// MarshalJSON serializes the standard members along with the extensions.
+func (d *Details) MarshalJSON() ([]byte, error) {
+	type details Details
+	standard, err := json.Marshal((*details)(d))
+	if err != nil || len(d.Extensions) == 0 {
+		return standard, err
+	}
+
+	members := make(map[string]any, len(d.Extensions))
+	for k, v := range d.Extensions {
+		members[k] = v
+	}
+	var std map[string]any
+	if err := json.Unmarshal(standard, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON parses the standard members and collects the others into Extensions.
+func (d *Details) UnmarshalJSON(data []byte) error {
+	type details Details
+	if err := json.Unmarshal(data, (*details)(d)); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, k)
+	}
+	d.Extensions = nil
+	for k, raw := range members {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if d.Extensions == nil {
+			d.Extensions = map[string]any{}
+		}
+		d.Extensions[k] = v
+	}
+	return nil
+}
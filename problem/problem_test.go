@@ -0,0 +1,76 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/problem"
+)
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		opts   []problem.Option
+		want   map[string]any
+	}{
+		{"defaults", http.StatusNotFound, nil, map[string]any{
+			"type": "about:blank", "title": "Not Found", "status": 404.0,
+		}},
+		{"detail_and_instance", http.StatusConflict, []problem.Option{
+			problem.WithDetail("email already registered"),
+			problem.WithInstance("/users"),
+		}, map[string]any{
+			"type": "about:blank", "title": "Conflict", "status": 409.0,
+			"detail": "email already registered", "instance": "/users",
+		}},
+		{"custom_type_and_extensions", http.StatusUnprocessableEntity, []problem.Option{
+			problem.WithType("https://example.com/probs/validation", "Validation failed"),
+			problem.WithExtension("errors", []string{"name is required"}),
+			problem.WithExtension("status", "ignored"),
+		}, map[string]any{
+			"type": "https://example.com/probs/validation", "title": "Validation failed", "status": 422.0,
+			"errors": []any{"name is required"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := problem.Write(rec, tc.status, tc.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tc.status || rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			var got map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetailsRoundTrip(t *testing.T) {
+	in := problem.New(http.StatusTooManyRequests, problem.WithDetail("slow down"), problem.WithExtension("retry_after", 30.0))
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out problem.Details
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, &out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+	if in.Error() != "slow down" || in.StatusCode() != http.StatusTooManyRequests {
+		t.Errorf("got error %q with status code %d", in.Error(), in.StatusCode())
+	}
+}
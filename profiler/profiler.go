@@ -0,0 +1,35 @@
+// Package profiler exposes the runtime profiles and expvar variables of a service as a rahjoo
+// Route. It lives apart from the rahjoo package because importing net/http/pprof and expvar
+// registers /debug/pprof/ and /debug/vars on http.DefaultServeMux as a side effect, which only
+// programs opting in to profiling should pay for.
+package profiler
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+// Routes returns a Route exposing the net/http/pprof profiles under prefix+"/pprof/" and the
+// expvar variables at prefix+"/vars", e.g. "/debug/pprof/heap" and "/debug/vars" for the prefix
+// "/debug". Being a regular Route, it can be protected with the router's own middlewares:
+//
+//	debug := profiler.Routes("/debug").SetMiddleware(middleware.IPFilter(office, nil))
+func Routes(prefix string) rahjoo.Route {
+	return rahjoo.NewGroupRoute(prefix, rahjoo.Route{
+		"/pprof/{$}":       {http.MethodGet: rahjoo.NewHandler(pprof.Index)},
+		"/pprof/cmdline":   {http.MethodGet: rahjoo.NewHandler(pprof.Cmdline)},
+		"/pprof/profile":   {http.MethodGet: rahjoo.NewHandler(pprof.Profile)},
+		"/pprof/symbol":    {http.MethodGet: rahjoo.NewHandler(pprof.Symbol), http.MethodPost: rahjoo.NewHandler(pprof.Symbol)},
+		"/pprof/trace":     {http.MethodGet: rahjoo.NewHandler(pprof.Trace)},
+		"/pprof/{profile}": {http.MethodGet: rahjoo.NewHandler(profileHandler)},
+		"/vars":            {http.MethodGet: rahjoo.NewHandler(expvar.Handler().ServeHTTP)},
+	})
+}
+
+// profileHandler serves the runtime profile named by the path, e.g. heap or goroutine.
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+}
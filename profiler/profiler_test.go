@@ -0,0 +1,52 @@
+package profiler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/profiler"
+)
+
+func TestProfiler(t *testing.T) {
+	mux := http.NewServeMux()
+	routes := profiler.Routes("/admin/debug").SetMiddleware(middleware.BasicAuth("debug", middleware.BasicAuthUsers(map[string]string{"ops": "secret"})))
+	if err := rahjoo.BindRoutesToMux(mux, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		auth     bool
+		status   int
+		contains string
+	}{
+		{"index", "/admin/debug/pprof/", true, http.StatusOK, "goroutine"},
+		{"named_profile", "/admin/debug/pprof/goroutine?debug=1", true, http.StatusOK, "goroutine profile:"},
+		{"cmdline", "/admin/debug/pprof/cmdline", true, http.StatusOK, ""},
+		{"unknown_profile", "/admin/debug/pprof/nope", true, http.StatusNotFound, ""},
+		{"expvar", "/admin/debug/vars", true, http.StatusOK, `"memstats"`},
+		{"protected", "/admin/debug/pprof/", false, http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, http.NoBody)
+			if tc.auth {
+				req.SetBasicAuth("ops", "secret")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Errorf("body does not contain %q", tc.contains)
+			}
+		})
+	}
+}
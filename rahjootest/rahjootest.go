@@ -0,0 +1,193 @@
+// Package rahjootest provides utilities for testing rahjoo routes.
+//
+// Client serves requests straight through a route table, with a fluent API replacing the
+// httptest boilerplate of route tests:
+//
+//	c := rahjootest.New(t, userRoutes)
+//	c.Post("/users").WithJSON(newUser).ExpectStatus(http.StatusCreated)
+//	c.Get("/users/1").ExpectStatus(http.StatusOK).ExpectJSON(map[string]any{"id": 1, "name": "sara"})
+package rahjootest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+// Client serves requests through a route table bound to its own http.ServeMux.
+type Client struct {
+	t      testing.TB
+	mux    *http.ServeMux
+	header http.Header
+}
+
+// New returns a Client serving routes. It fails the test if the routes cannot be bound.
+func New(t testing.TB, routes ...rahjoo.Route) *Client {
+	t.Helper()
+	return NewWithOptions(t, routes)
+}
+
+// NewWithOptions is like New, binding routes with opts as rahjoo.BindRoutesToMuxWithOptions does.
+func NewWithOptions(t testing.TB, routes []rahjoo.Route, opts ...rahjoo.BindOption) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMuxWithOptions(mux, routes, opts...); err != nil {
+		t.Fatalf("rahjootest: binding routes: %v", err)
+	}
+	return &Client{t: t, mux: mux, header: http.Header{}}
+}
+
+// Handler returns the handler serving the routes, e.g. for an httptest.Server.
+func (c *Client) Handler() http.Handler {
+	return c.mux
+}
+
+// SetHeader sets a header sent with every request of c, e.g. Authorization.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Get starts a GET request to target, a path with an optional query.
+func (c *Client) Get(target string) *Request { return c.Request(http.MethodGet, target) }
+
+// Head starts a HEAD request to target.
+func (c *Client) Head(target string) *Request { return c.Request(http.MethodHead, target) }
+
+// Post starts a POST request to target.
+func (c *Client) Post(target string) *Request { return c.Request(http.MethodPost, target) }
+
+// Put starts a PUT request to target.
+func (c *Client) Put(target string) *Request { return c.Request(http.MethodPut, target) }
+
+// Patch starts a PATCH request to target.
+func (c *Client) Patch(target string) *Request { return c.Request(http.MethodPatch, target) }
+
+// Delete starts a DELETE request to target.
+func (c *Client) Delete(target string) *Request { return c.Request(http.MethodDelete, target) }
+
+// Request starts a request with method to target. It is sent by the first Expect or
+// Response call.
+func (c *Client) Request(method, target string) *Request {
+	return &Request{c: c, method: method, target: target, header: c.header.Clone()}
+}
+
+// Request is a request being built, then the response it got once sent.
+type Request struct {
+	c      *Client
+	method string
+	target string
+	header http.Header
+	body   []byte
+
+	rec  *httptest.ResponseRecorder
+	resp *http.Response
+}
+
+// WithHeader sets a header of the request.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithBody sets the body of the request and its Content-Type.
+func (r *Request) WithBody(contentType string, body string) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = []byte(body)
+	return r
+}
+
+// WithJSON sets the body of the request to v encoded as JSON.
+func (r *Request) WithJSON(v any) *Request {
+	r.c.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.c.t.Fatalf("rahjootest: encoding request body: %v", err)
+	}
+	r.header.Set("Content-Type", "application/json")
+	r.body = body
+	return r
+}
+
+// Response sends the request if it was not sent yet and returns its response, whose body can
+// be read again.
+func (r *Request) Response() *http.Response {
+	if r.resp == nil {
+		req := httptest.NewRequest(r.method, r.target, bytes.NewReader(r.body))
+		req.Header = r.header
+		r.rec = httptest.NewRecorder()
+		r.c.mux.ServeHTTP(r.rec, req)
+		r.resp = r.rec.Result()
+	}
+	r.resp.Body = io.NopCloser(bytes.NewReader(r.rec.Body.Bytes()))
+	return r.resp
+}
+
+// Body returns the body of the response.
+func (r *Request) Body() string {
+	r.Response()
+	return r.rec.Body.String()
+}
+
+// ExpectStatus fails the test if the response status code is not code.
+func (r *Request) ExpectStatus(code int) *Request {
+	r.c.t.Helper()
+	if got := r.Response().StatusCode; got != code {
+		r.c.t.Errorf("%s %s: got status code %d, want %d; body: %s", r.method, r.target, got, code, r.Body())
+	}
+	return r
+}
+
+// ExpectHeader fails the test if the response header key is not value.
+func (r *Request) ExpectHeader(key, value string) *Request {
+	r.c.t.Helper()
+	if got := r.Response().Header.Get(key); got != value {
+		r.c.t.Errorf("%s %s: got header %s %q, want %q", r.method, r.target, key, got, value)
+	}
+	return r
+}
+
+// ExpectBody fails the test if the response body is not body, ignoring surrounding whitespace.
+func (r *Request) ExpectBody(body string) *Request {
+	r.c.t.Helper()
+	if got := r.Body(); strings.TrimSpace(got) != strings.TrimSpace(body) {
+		r.c.t.Errorf("%s %s: got body %q, want %q", r.method, r.target, got, body)
+	}
+	return r
+}
+
+// ExpectJSON fails the test if the response body is not the JSON encoding of want. Both are
+// compared as decoded JSON, so formatting and object key order do not matter.
+func (r *Request) ExpectJSON(want any) *Request {
+	r.c.t.Helper()
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		r.c.t.Fatalf("rahjootest: encoding expected JSON: %v", err)
+	}
+	var got, expected any
+	if err := json.Unmarshal([]byte(r.Body()), &got); err != nil {
+		r.c.t.Errorf("%s %s: got invalid JSON body %q: %v", r.method, r.target, r.Body(), err)
+		return r
+	}
+	json.Unmarshal(wantJSON, &expected)
+	if !reflect.DeepEqual(got, expected) {
+		r.c.t.Errorf("%s %s: got JSON %s, want %s", r.method, r.target, strings.TrimSpace(r.Body()), wantJSON)
+	}
+	return r
+}
+
+// DecodeJSON decodes the response body into v, failing the test on error.
+func (r *Request) DecodeJSON(v any) *Request {
+	r.c.t.Helper()
+	if err := json.Unmarshal([]byte(r.Body()), v); err != nil {
+		r.c.t.Fatalf("%s %s: decoding JSON body %q: %v", r.method, r.target, r.Body(), err)
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+package rahjootest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/rahjootest"
+)
+
+// recordingT records the failures of a test instead of failing it.
+type recordingT struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	t.fatal = true
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func userRoutes() rahjoo.Route {
+	return rahjoo.Route{
+		"/users/{id}": {
+			http.MethodGet: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"name": "sara", "id": %s}`, r.PathValue("id"))
+			}),
+		},
+		"/users": {
+			http.MethodPost: rahjoo.NewHandler(func(w http.ResponseWriter, r *http.Request) {
+				var u user
+				if err := json.NewDecoder(r.Body).Decode(&u); err != nil || r.Header.Get("Authorization") != "Bearer token" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, u.Name)
+			}, middleware.EnforceJSON),
+		},
+	}
+}
+
+func TestClient(t *testing.T) {
+	c := rahjootest.New(t, userRoutes()).SetHeader("Authorization", "Bearer token")
+
+	c.Get("/users/1").
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON(user{ID: 1, Name: "sara"})
+	c.Post("/users").WithJSON(user{Name: "amir"}).ExpectStatus(http.StatusCreated).ExpectBody("amir")
+	c.Post("/users").WithBody("text/plain", "amir").ExpectStatus(http.StatusUnsupportedMediaType)
+	c.Delete("/users/1").ExpectStatus(http.StatusMethodNotAllowed)
+
+	var u user
+	c.Get("/users/7").DecodeJSON(&u)
+	if u.ID != 7 {
+		t.Errorf("got user %+v", u)
+	}
+}
+
+func TestClientFailures(t *testing.T) {
+	testCases := []struct {
+		name   string
+		expect func(*rahjootest.Client)
+		want   string
+	}{
+		{"status", func(c *rahjootest.Client) { c.Get("/users/1").ExpectStatus(http.StatusNotFound) }, "got status code 200, want 404"},
+		{"header", func(c *rahjootest.Client) { c.Get("/users/1").ExpectHeader("Content-Type", "text/plain") }, `got header Content-Type "application/json"`},
+		{"json", func(c *rahjootest.Client) { c.Get("/users/1").ExpectJSON(user{ID: 2, Name: "sara"}) }, "got JSON"},
+		{"body", func(c *rahjootest.Client) { c.Get("/users/1").ExpectBody("nope") }, "got body"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := &recordingT{}
+			tc.expect(rahjootest.New(rt, userRoutes()))
+			if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], tc.want) {
+				t.Errorf("got failures %q, want one containing %q", rt.errors, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewBindError(t *testing.T) {
+	rt := &recordingT{}
+	h := rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})
+	rahjootest.New(rt, rahjoo.Route{"/a/{x}/{x}": {http.MethodGet: h}})
+	if !rt.fatal {
+		t.Error("expected a fatal failure for invalid routes")
+	}
+}
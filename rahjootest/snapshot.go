@@ -0,0 +1,108 @@
+package rahjootest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/internal/funcname"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// UpdateGoldenEnv is the environment variable which, set to a non-empty value, makes
+// SnapshotRoutes write the golden files instead of comparing against them:
+//
+//	RAHJOOTEST_UPDATE=1 go test ./...
+const UpdateGoldenEnv = "RAHJOOTEST_UPDATE"
+
+// SnapshotRoutes compares the route table to the golden file at path, failing the test with
+// the routes added and removed if they differ. Each route is written on its own line, sorted,
+// with its handler, its middlewares in execution order and its name, so accidental route
+// removals and dropped middlewares show up in review and in CI:
+//
+//	GET /users/{id} handler=users.Get middlewares=[auth.Required middleware.EnforceJSON] name=users.get
+//
+// Functions are identified by their name, dropping the import path and closure suffixes, so a
+// middleware returned by a constructor is named after the function declaring the closure, and
+// middlewares built by the same constructor with distinct settings are indistinguishable.
+// Set UpdateGoldenEnv to create or update the golden file.
+func SnapshotRoutes(t testing.TB, routes rahjoo.Route, path string) {
+	t.Helper()
+	got := FormatRoutes(routes)
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("rahjootest: creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("rahjootest: writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("rahjootest: reading golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if diff := diffLines(string(bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n"))), got); diff != "" {
+		t.Errorf("routes differ from %s (set %s=1 to update it):\n%s", path, UpdateGoldenEnv, diff)
+	}
+}
+
+// FormatRoutes serializes routes deterministically in the format of SnapshotRoutes.
+func FormatRoutes(routes rahjoo.Route) string {
+	var lines []string
+	for path, methods := range routes {
+		for method, action := range methods {
+			if method == "" {
+				method = "*"
+			}
+			line := fmt.Sprintf("%s %s handler=%s middlewares=[%s]", method, path, funcname.Of(action.Handler()), middlewareNames(action.Middlewares()))
+			if name := action.Name(); name != "" {
+				line += " name=" + name
+			}
+			lines = append(lines, line)
+		}
+	}
+	slices.Sort(lines)
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func middlewareNames(middlewares []middleware.Middleware) string {
+	names := make([]string, len(middlewares))
+	for i, m := range middlewares {
+		names[i] = funcname.Of(m)
+	}
+	return strings.Join(names, " ")
+}
+
+// diffLines returns the lines only in want prefixed with "-" and the lines only in got prefixed
+// with "+", or an empty string if both hold the same lines.
+func diffLines(want, got string) string {
+	wantLines := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	var b strings.Builder
+	for _, line := range wantLines {
+		if !slices.Contains(gotLines, line) {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+	for _, line := range gotLines {
+		if !slices.Contains(wantLines, line) {
+			fmt.Fprintf(&b, "+ %s\n", line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package rahjootest_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/rahjootest"
+)
+
+func getUser(http.ResponseWriter, *http.Request) {}
+
+func audit(next http.Handler) http.Handler { return next }
+
+func snapshotRoutes() rahjoo.Route {
+	return rahjoo.Route{
+		"/users/{id}": {
+			http.MethodGet:    rahjoo.NewHandler(getUser, audit, middleware.EnforceJSON).WithName("users.get"),
+			http.MethodDelete: rahjoo.NewHandler(getUser, audit),
+		},
+		"/health": {
+			"": rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {}),
+		},
+	}
+}
+
+func TestFormatRoutes(t *testing.T) {
+	want := `* /health handler=rahjootest_test.snapshotRoutes middlewares=[]
+DELETE /users/{id} handler=rahjootest_test.getUser middlewares=[rahjootest_test.audit]
+GET /users/{id} handler=rahjootest_test.getUser middlewares=[rahjootest_test.audit middleware.EnforceJSON] name=users.get
+`
+	if got := rahjootest.FormatRoutes(snapshotRoutes()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSnapshotRoutes(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "routes.golden")
+
+	rt := &recordingT{}
+	rahjootest.SnapshotRoutes(rt, snapshotRoutes(), golden)
+	if !rt.fatal {
+		t.Fatal("expected a fatal failure for a missing golden file")
+	}
+
+	t.Setenv(rahjootest.UpdateGoldenEnv, "1")
+	rahjootest.SnapshotRoutes(t, snapshotRoutes(), golden)
+	if _, err := os.Stat(golden); err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	os.Unsetenv(rahjootest.UpdateGoldenEnv)
+
+	rahjootest.SnapshotRoutes(t, snapshotRoutes(), golden)
+
+	routes := snapshotRoutes()
+	delete(routes, "/health")
+	routes["/users/{id}"][http.MethodDelete] = rahjoo.NewHandler(getUser)
+	rt = &recordingT{}
+	rahjootest.SnapshotRoutes(rt, routes, golden)
+	if len(rt.errors) != 1 {
+		t.Fatalf("got failures %q, want one", rt.errors)
+	}
+	for _, want := range []string{
+		"- * /health",
+		"- DELETE /users/{id} handler=rahjootest_test.getUser middlewares=[rahjootest_test.audit]",
+		"+ DELETE /users/{id} handler=rahjootest_test.getUser middlewares=[]",
+	} {
+		if !strings.Contains(rt.errors[0], want) {
+			t.Errorf("failure %q does not contain %q", rt.errors[0], want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package rahjootest
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/internal/funcname"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// TraceHandler is the name Trace records when the handler of a traced chain runs.
+const TraceHandler = "handler"
+
+// Trace records the execution order of the middlewares of a chain built by TraceChain.
+type Trace struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// TraceChain chains middlewares around handler like middleware.Chain, instrumenting each of
+// them to record its name in the returned Trace when it runs, and TraceHandler when handler
+// runs. Middlewares are named like in SnapshotRoutes. It makes middleware ordering bugs, such
+// as authentication running before recovery, testable:
+//
+//	h, trace := rahjootest.TraceChain(handler, action.Middlewares()...)
+//	h.ServeHTTP(httptest.NewRecorder(), req)
+//	trace.ExpectOrder(t, "middleware.recovery", "auth.Required", rahjootest.TraceHandler)
+//
+// A middleware answering the request without calling the next handler ends the trace.
+func TraceChain(handler http.Handler, middlewares ...middleware.Middleware) (http.Handler, *Trace) {
+	trace := &Trace{}
+	traced := make([]middleware.Middleware, 0, len(middlewares))
+	for _, m := range middlewares {
+		traced = append(traced, trace.wrap(funcname.Of(m), m))
+	}
+	handler = trace.wrap(TraceHandler, func(next http.Handler) http.Handler { return next })(handler)
+	return middleware.Chain(handler, traced...), trace
+}
+
+// wrap instruments m to record name before it runs.
+func (tr *Trace) wrap(name string, m middleware.Middleware) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		h := m(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tr.record(name)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (tr *Trace) record(name string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.calls = append(tr.calls, name)
+}
+
+// Calls returns the names recorded so far, in execution order.
+func (tr *Trace) Calls() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return slices.Clone(tr.calls)
+}
+
+// Reset forgets the names recorded so far, e.g. between requests.
+func (tr *Trace) Reset() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.calls = nil
+}
+
+// ExpectOrder fails the test unless names were all recorded in the given order. Other names
+// may be recorded in between, so only the relative order of names is asserted.
+func (tr *Trace) ExpectOrder(t testing.TB, names ...string) {
+	t.Helper()
+	calls := tr.Calls()
+	i := 0
+	for _, call := range calls {
+		if i < len(names) && call == names[i] {
+			i++
+		}
+	}
+	if i < len(names) {
+		t.Errorf("got calls [%s], want [%s] in this order", strings.Join(calls, " "), strings.Join(names, " "))
+	}
+}
+
+// ExpectNotCalled fails the test if any of names was recorded, e.g. to assert that a
+// middleware rejecting the request kept the handler from running.
+func (tr *Trace) ExpectNotCalled(t testing.TB, names ...string) {
+	t.Helper()
+	calls := tr.Calls()
+	for _, name := range names {
+		if slices.Contains(calls, name) {
+			t.Errorf("got calls [%s], want no %s", strings.Join(calls, " "), name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package rahjootest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/rahjootest"
+)
+
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestTraceChain(t *testing.T) {
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h, trace := rahjootest.TraceChain(handler, audit, authenticate, middleware.EnforceJSON)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := []string{"rahjootest_test.audit", "rahjootest_test.authenticate", "middleware.EnforceJSON", rahjootest.TraceHandler}
+	if got := trace.Calls(); !slices.Equal(got, want) {
+		t.Errorf("got calls %q, want %q", got, want)
+	}
+	trace.ExpectOrder(t, "rahjootest_test.audit", rahjootest.TraceHandler)
+
+	rt := &recordingT{}
+	trace.ExpectOrder(rt, "rahjootest_test.authenticate", "rahjootest_test.audit")
+	if len(rt.errors) != 1 {
+		t.Errorf("got failures %q, want one for the wrong order", rt.errors)
+	}
+
+	trace.Reset()
+	req.Header.Del("Authorization")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	trace.ExpectOrder(t, "rahjootest_test.audit", "rahjootest_test.authenticate")
+	trace.ExpectNotCalled(t, "middleware.EnforceJSON", rahjootest.TraceHandler)
+
+	rt = &recordingT{}
+	trace.ExpectNotCalled(rt, "rahjootest_test.audit")
+	if len(rt.errors) != 1 {
+		t.Errorf("got failures %q, want one for the recorded call", rt.errors)
+	}
+}
@@ -0,0 +1,62 @@
+package rahjoo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RouteLoader builds a complete route table from an external source such as a configuration file.
+type RouteLoader func() (Route, error)
+
+// ReplaceRoutes replaces the whole route table of the DynamicRouter with routes.
+// If routes can not be registered, the route table is left untouched and the error is returned.
+func (d *DynamicRouter) ReplaceRoutes(routes ...Route) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.swap(MergeRoutes(routes...).Clone())
+}
+
+// Reload rebuilds the route table from load and atomically swaps it in.
+// In-flight requests finish on the previous mux. If load fails or the loaded routes can not be
+// registered, the current route table is kept and the error is returned.
+func (d *DynamicRouter) Reload(load RouteLoader) error {
+	routes, err := load()
+	if err != nil {
+		return fmt.Errorf("load routes: %w", err)
+	}
+	return d.ReplaceRoutes(routes)
+}
+
+// WatchFile polls the named file every interval and reloads the route table with load whenever
+// the file's size or modification time changes. It blocks until ctx is done and returns ctx.Err().
+// Reload errors are passed to onError, if it is not nil, and the previous routes stay active.
+func (d *DynamicRouter) WatchFile(ctx context.Context, name string, interval time.Duration, load RouteLoader, onError func(error)) error {
+	var lastMod time.Time
+	var lastSize int64 = -1
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(name)
+		switch {
+		case err != nil:
+			if onError != nil {
+				onError(err)
+			}
+		case info.ModTime() != lastMod || info.Size() != lastSize:
+			lastMod, lastSize = info.ModTime(), info.Size()
+			if err := d.Reload(load); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
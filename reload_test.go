@@ -0,0 +1,117 @@
+package rahjoo_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestDynamicRouterReload(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	d, err := rahjoo.NewDynamicRouter(rahjoo.Route{"/old": {http.MethodGet: rahjoo.NewHandler(ok)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return rec.Code
+	}
+
+	err = d.Reload(func() (rahjoo.Route, error) {
+		return nil, errors.New("broken config")
+	})
+	if err == nil {
+		t.Fatal("expected load error")
+	}
+	if got := status("/old"); got != http.StatusOK {
+		t.Fatalf("got status code %d after failed reload, want %d", got, http.StatusOK)
+	}
+
+	err = d.Reload(func() (rahjoo.Route, error) {
+		return rahjoo.Route{"/a/{x}": {"": rahjoo.NewHandler(ok)}, "/a/{y}": {"": rahjoo.NewHandler(ok)}}, nil
+	})
+	if err == nil {
+		t.Fatal("expected bind error for conflicting patterns")
+	}
+	if got := status("/old"); got != http.StatusOK {
+		t.Fatalf("got status code %d after failed bind, want %d", got, http.StatusOK)
+	}
+
+	err = d.Reload(func() (rahjoo.Route, error) {
+		return rahjoo.Route{"/new": {http.MethodGet: rahjoo.NewHandler(ok)}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status("/old"); got != http.StatusNotFound {
+		t.Errorf("got status code %d for replaced route, want %d", got, http.StatusNotFound)
+	}
+	if got := status("/new"); got != http.StatusOK {
+		t.Errorf("got status code %d for reloaded route, want %d", got, http.StatusOK)
+	}
+}
+
+func TestDynamicRouterWatchFile(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	name := filepath.Join(t.TempDir(), "routes.txt")
+	if err := os.WriteFile(name, []byte("/first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func() (rahjoo.Route, error) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		route := rahjoo.Route{}
+		for _, p := range strings.Fields(string(b)) {
+			route = rahjoo.MergeRoutes(route, rahjoo.Route{rahjoo.Path(p): {http.MethodGet: rahjoo.NewHandler(ok)}})
+		}
+		return route, nil
+	}
+
+	d, err := rahjoo.NewDynamicRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- d.WatchFile(ctx, name, 5*time.Millisecond, load, func(err error) { t.Error(err) })
+	}()
+
+	waitFor := func(path string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			rec := httptest.NewRecorder()
+			d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+			if rec.Code == http.StatusOK {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("route %s was not loaded", path)
+	}
+
+	waitFor("/first")
+	if err := os.WriteFile(name, []byte("/first /second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("/second")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
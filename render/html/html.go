@@ -0,0 +1,202 @@
+// Package html renders server-side HTML pages from html/template sets loaded from a file system,
+// typically an embed.FS, with shared layouts and partials:
+//
+//	//go:embed templates
+//	var templates embed.FS
+//
+//	sub, _ := fs.Sub(templates, "templates")
+//	pages, err := html.New(sub, html.WithDevMode(os.Getenv("DEV") != ""))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	render.Templates = pages
+//
+//	func home(w http.ResponseWriter, r *http.Request) {
+//		render.HTML(w, http.StatusOK, "home", data)
+//	}
+//
+// Every page, e.g. pages/home.html, is parsed in its own set along with all layouts and
+// partials, so pages can define the same blocks, such as "title" and "content", filled in by
+// the layout.
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+type config struct {
+	layouts, partials, pages string
+	entry                    string
+	funcs                    template.FuncMap
+	dev                      bool
+}
+
+// Option configures a Renderer.
+type Option func(*config)
+
+// WithLayouts sets the glob pattern of the layout templates, "layouts/*.html" by default.
+func WithLayouts(pattern string) Option {
+	return func(c *config) {
+		c.layouts = pattern
+	}
+}
+
+// WithPartials sets the glob pattern of the partial templates, "partials/*.html" by default.
+func WithPartials(pattern string) Option {
+	return func(c *config) {
+		c.partials = pattern
+	}
+}
+
+// WithPages sets the glob pattern of the page templates, "pages/*.html" by default. Pages are
+// named after their file name without extension.
+func WithPages(pattern string) Option {
+	return func(c *config) {
+		c.pages = pattern
+	}
+}
+
+// WithEntry sets the name of the template executed to render a page, "layout" by default,
+// typically defined by a layout. Pages whose set has no such template are executed as is.
+func WithEntry(name string) Option {
+	return func(c *config) {
+		c.entry = name
+	}
+}
+
+// WithFuncs adds functions available to every template.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(c *config) {
+		for name, fn := range funcs {
+			c.funcs[name] = fn
+		}
+	}
+}
+
+// WithDevMode reloads the templates on every render, so edits show up without a restart when
+// the file system is the working directory (os.DirFS) rather than an embed.FS.
+func WithDevMode(dev bool) Option {
+	return func(c *config) {
+		c.dev = dev
+	}
+}
+
+// page is the template set of a page and the name of the template rendering it.
+type page struct {
+	tmpl  *template.Template
+	entry string
+}
+
+// Renderer renders the pages loaded from a file system. It implements render.TemplateExecutor.
+type Renderer struct {
+	fsys fs.FS
+	c    config
+
+	mu    sync.RWMutex
+	pages map[string]page
+}
+
+// New loads the templates of fsys. It returns an error if a template fails to parse or two
+// pages have the same name.
+func New(fsys fs.FS, opts ...Option) (*Renderer, error) {
+	c := config{
+		layouts:  "layouts/*.html",
+		partials: "partials/*.html",
+		pages:    "pages/*.html",
+		entry:    "layout",
+		funcs:    template.FuncMap{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	r := &Renderer{fsys: fsys, c: c}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load parses the templates and replaces the pages of r.
+func (r *Renderer) load() error {
+	shared := template.New("").Funcs(r.c.funcs)
+	for _, pattern := range []string{r.c.layouts, r.c.partials} {
+		files, err := fs.Glob(r.fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("html: %w", err)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		if _, err := shared.ParseFS(r.fsys, files...); err != nil {
+			return fmt.Errorf("html: %w", err)
+		}
+	}
+
+	files, err := fs.Glob(r.fsys, r.c.pages)
+	if err != nil {
+		return fmt.Errorf("html: %w", err)
+	}
+	pages := make(map[string]page, len(files))
+	for _, file := range files {
+		base := path.Base(file)
+		name := strings.TrimSuffix(base, path.Ext(base))
+		if _, ok := pages[name]; ok {
+			return fmt.Errorf("html: duplicate page %q", name)
+		}
+		tmpl, err := shared.Clone()
+		if err == nil {
+			_, err = tmpl.ParseFS(r.fsys, file)
+		}
+		if err != nil {
+			return fmt.Errorf("html: %w", err)
+		}
+		entry := r.c.entry
+		if tmpl.Lookup(entry) == nil {
+			entry = base
+		}
+		pages[name] = page{tmpl: tmpl, entry: entry}
+	}
+
+	r.mu.Lock()
+	r.pages = pages
+	r.mu.Unlock()
+	return nil
+}
+
+// ExecuteTemplate renders the page name with data to w.
+func (r *Renderer) ExecuteTemplate(w io.Writer, name string, data any) error {
+	if r.c.dev {
+		if err := r.load(); err != nil {
+			return err
+		}
+	}
+	r.mu.RLock()
+	p, ok := r.pages[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("html: no page %q", name)
+	}
+	return p.tmpl.ExecuteTemplate(w, p.entry, data)
+}
+
+// HTML writes the page name rendered with data with status code. The page is rendered before
+// anything is written, so on error the caller can still answer with an error.
+func (r *Renderer) HTML(w http.ResponseWriter, code int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := r.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	_, err := buf.WriteTo(w)
+	return err
+}
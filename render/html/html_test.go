@@ -0,0 +1,115 @@
+package html_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/amirzayi/rahjoo/render/html"
+)
+
+func templates() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`{{define "layout"}}<title>{{template "title" .}}</title>{{template "nav" .}}<main>{{template "content" .}}</main>{{end}}`)},
+		"partials/nav.html":  {Data: []byte(`{{define "nav"}}<nav>{{upper .User}}</nav>{{end}}`)},
+		"pages/home.html":    {Data: []byte(`{{define "title"}}Home{{end}}{{define "content"}}Hello {{.User}}{{end}}`)},
+		"pages/profile.html": {Data: []byte(`{{define "title"}}Profile{{end}}{{define "content"}}<b>{{.User}}</b>{{end}}`)},
+	}
+}
+
+var funcs = template.FuncMap{"upper": strings.ToUpper}
+
+func TestRenderer(t *testing.T) {
+	r, err := html.New(templates(), html.WithFuncs(funcs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		page    string
+		data    any
+		want    string
+		wantErr bool
+	}{
+		{"home", "home", map[string]string{"User": "sara"}, "<title>Home</title><nav>SARA</nav><main>Hello sara</main>", false},
+		{"shared_block_names", "profile", map[string]string{"User": "<script>"}, "<title>Profile</title><nav>&lt;SCRIPT&gt;</nav><main><b>&lt;script&gt;</b></main>", false},
+		{"missing_page", "admin", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			err := r.HTML(rec, http.StatusOK, tc.page, tc.data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				if rec.Body.Len() != 0 {
+					t.Errorf("got body %q written on error", rec.Body)
+				}
+				return
+			}
+			if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" || rec.Body.String() != tc.want {
+				t.Errorf("got %q %q, want %q", rec.Header().Get("Content-Type"), rec.Body, tc.want)
+			}
+		})
+	}
+}
+
+func TestRendererWithoutLayout(t *testing.T) {
+	fsys := fstest.MapFS{"views/plain.tmpl": {Data: []byte(`plain {{.}}`)}}
+	r, err := html.New(fsys, html.WithPages("views/*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := r.ExecuteTemplate(&b, "plain", "page"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "plain page" {
+		t.Errorf("got %q, want %q", b.String(), "plain page")
+	}
+}
+
+func TestRendererDevMode(t *testing.T) {
+	for _, dev := range []bool{false, true} {
+		fsys := templates()
+		r, err := html.New(fsys, html.WithFuncs(funcs), html.WithDevMode(dev))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["pages/home.html"] = &fstest.MapFile{Data: []byte(`{{define "title"}}Edited{{end}}{{define "content"}}{{end}}`)}
+
+		var b strings.Builder
+		if err := r.ExecuteTemplate(&b, "home", map[string]string{"User": "sara"}); err != nil {
+			t.Fatal(err)
+		}
+		if reloaded := strings.Contains(b.String(), "Edited"); reloaded != dev {
+			t.Errorf("dev mode %t: got %q", dev, b.String())
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		fsys fstest.MapFS
+		opts []html.Option
+	}{
+		{"parse_error", fstest.MapFS{"pages/bad.html": {Data: []byte(`{{if}}`)}}, nil},
+		{"duplicate_page", fstest.MapFS{"pages/a/home.html": {Data: []byte(`a`)}, "pages/b/home.html": {Data: []byte(`b`)}}, []html.Option{html.WithPages("pages/*/*.html")}},
+		{"unknown_function", fstest.MapFS{"pages/home.html": {Data: []byte(`{{upper .}}`)}}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := html.New(tc.fsys, tc.opts...); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
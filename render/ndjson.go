@@ -0,0 +1,49 @@
+package render
+
+import (
+	"encoding/json"
+	"iter"
+	"net/http"
+	"time"
+)
+
+// ndjsonFlushInterval is the minimum time between the flushes of NDJSON.
+const ndjsonFlushInterval = 250 * time.Millisecond
+
+// NDJSON streams the values of seq as newline-delimited JSON (application/x-ndjson) with status
+// 200 OK, one value per line, without buffering the whole result set:
+//
+//	render.NDJSON(w, r, store.AllOrders(ctx))
+//
+// Lines are flushed to the client when a value arrives at least 250 milliseconds after the
+// previous flush, and at the end: while seq blocks, the lines written since the last flush may
+// stay buffered. Iteration stops when the request context is canceled, e.g. when the client goes
+// away, returning the context error. As the response has started, errors encoding a value stop
+// the stream and are returned for logging only.
+func NDJSON[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq[T]) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	ctx := r.Context()
+	enc := json.NewEncoder(w)
+	lastFlush := time.Now()
+	for v := range seq {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if time.Since(lastFlush) >= ndjsonFlushInterval {
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+			lastFlush = time.Now()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
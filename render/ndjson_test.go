@@ -0,0 +1,75 @@
+package render_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/render"
+)
+
+func TestNDJSON(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancelAfter yields users, canceling ctx once n of them were yielded.
+	cancelAfter := func(n int) iter.Seq[user] {
+		return func(yield func(user) bool) {
+			for i := 1; ; i++ {
+				if !yield(user{ID: i, Name: "u"}) {
+					return
+				}
+				if i == n {
+					cancel()
+				}
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		seq     iter.Seq[user]
+		body    string
+		wantErr error
+	}{
+		{"values", context.Background(), slices.Values([]user{{1, "amir"}, {2, "sara"}}), `{"id":1,"name":"amir"}` + "\n" + `{"id":2,"name":"sara"}` + "\n", nil},
+		{"empty", context.Background(), slices.Values([]user(nil)), "", nil},
+		{"canceled", ctx, cancelAfter(2), `{"id":1,"name":"u"}` + "\n" + `{"id":2,"name":"u"}` + "\n", context.Canceled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequestWithContext(tc.ctx, http.MethodGet, "/", http.NoBody)
+			err := render.NDJSON(rec, req, tc.seq)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body, tc.body)
+			}
+			if tc.wantErr == nil && !rec.Flushed {
+				t.Error("expected the response to be flushed")
+			}
+		})
+	}
+}
+
+func TestNDJSONEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := render.NDJSON(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody), slices.Values([]any{1, func() {}, 3}))
+	if err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if rec.Body.String() != "1\n" {
+		t.Errorf("got body %q, want the values before the error", rec.Body)
+	}
+}
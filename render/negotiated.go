@@ -0,0 +1,126 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sync"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// Media types of the built-in encoders.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+)
+
+// MediaTypeMsgPack is the media type of MessagePack, which has no built-in encoder:
+//
+//	render.RegisterEncoder(render.MediaTypeMsgPack, msgpack.Marshal)
+//
+// with msgpack of github.com/vmihailenco/msgpack/v5.
+const MediaTypeMsgPack = "application/msgpack"
+
+// Encoder encodes v into a response body.
+type Encoder func(v any) ([]byte, error)
+
+type encoderRegistry struct {
+	mu       sync.RWMutex
+	types    []string
+	encoders map[string]Encoder
+	fallback string
+}
+
+var encoders = &encoderRegistry{
+	types: []string{MediaTypeJSON, MediaTypeXML},
+	encoders: map[string]Encoder{
+		MediaTypeJSON: json.Marshal,
+		MediaTypeXML: func(v any) ([]byte, error) {
+			body, err := xml.Marshal(v)
+			return append([]byte(xml.Header), body...), err
+		},
+	},
+	fallback: MediaTypeJSON,
+}
+
+// RegisterEncoder registers enc for mediaType, for Negotiated and Format. It replaces the
+// encoder already registered for mediaType, if any.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	encoders.mu.Lock()
+	defer encoders.mu.Unlock()
+	if _, ok := encoders.encoders[mediaType]; !ok {
+		encoders.types = append(encoders.types, mediaType)
+	}
+	encoders.encoders[mediaType] = enc
+}
+
+// SetDefaultMediaType sets the media type Negotiated writes to clients without preference or
+// accepting no registered media type, application/json by default. It must be registered.
+func SetDefaultMediaType(mediaType string) {
+	encoders.mu.Lock()
+	defer encoders.mu.Unlock()
+	encoders.fallback = mediaType
+}
+
+// lookup returns the encoder registered for mediaType.
+func (e *encoderRegistry) lookup(mediaType string) (Encoder, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	enc, ok := e.encoders[mediaType]
+	return enc, ok
+}
+
+// negotiate returns the registered media type preferred by the Accept header values, the
+// default one first on ties, or the default one if none is acceptable.
+func (e *encoderRegistry) negotiate(accept []string) (string, Encoder) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	offered := make([]string, 0, len(e.types))
+	offered = append(offered, e.fallback)
+	for _, t := range e.types {
+		if t != e.fallback {
+			offered = append(offered, t)
+		}
+	}
+	mediaType, ok := middleware.NegotiateMediaType(accept, offered)
+	if !ok {
+		mediaType = e.fallback
+	}
+	return mediaType, e.encoders[mediaType]
+}
+
+// Negotiated writes v with status code in the registered media type the client prefers
+// according to its Accept header, without requiring the middleware.Negotiate middleware:
+// JSON and XML are built in, and other media types such as MessagePack can be added with
+// RegisterEncoder. Clients without preference or accepting none of them get the default
+// media type, see SetDefaultMediaType. JSON and XML are indented on request, see Pretty.
+func Negotiated(w http.ResponseWriter, r *http.Request, code int, v any) error {
+	w.Header().Add("Vary", "Accept")
+	mediaType, enc := encoders.negotiate(r.Header.Values("Accept"))
+	if Pretty(r) {
+		switch mediaType {
+		case MediaTypeJSON:
+			return writeJSON(w, code, v, true)
+		case MediaTypeXML:
+			return writeXML(w, code, v, true)
+		}
+	}
+	body, err := enc(v)
+	if err != nil {
+		return err
+	}
+	if mediaType == MediaTypeJSON {
+		body = append(body, '\n')
+	}
+	return write(w, code, contentType(mediaType), body)
+}
+
+// contentType returns the Content-Type of mediaType, with the UTF-8 charset for textual types.
+func contentType(mediaType string) string {
+	switch mediaType {
+	case MediaTypeJSON, MediaTypeXML:
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
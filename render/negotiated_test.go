@@ -0,0 +1,80 @@
+package render_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/render"
+)
+
+// fakeMsgPack stands in for a MessagePack encoder.
+func fakeMsgPack(v any) ([]byte, error) {
+	return []byte(fmt.Sprintf("msgpack:%v", v)), nil
+}
+
+func TestNegotiated(t *testing.T) {
+	render.RegisterEncoder(render.MediaTypeMsgPack, fakeMsgPack)
+
+	testCases := []struct {
+		name        string
+		fallback    string
+		accept      string
+		target      string
+		contentType string
+		body        string
+	}{
+		{"no_accept", "", "", "/", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n"},
+		{"wildcard", "", "*/*", "/", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n"},
+		{"xml", "", "application/xml", "/", "application/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user id="1"><name>amir</name></user>`},
+		{"msgpack", "", "application/msgpack", "/", "application/msgpack", "msgpack:amir"},
+		{"quality", "", "application/json;q=0.5, application/msgpack", "/", "application/msgpack", "msgpack:amir"},
+		{"unacceptable", "", "image/png", "/", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n"},
+		{"pretty", "", "application/json", "/?pretty", "application/json; charset=utf-8", "{\n  \"id\": 1,\n  \"name\": \"amir\"\n}\n"},
+		{"custom_default", render.MediaTypeMsgPack, "", "/", "application/msgpack", "msgpack:amir"},
+		{"custom_default_wildcard", render.MediaTypeMsgPack, "*/*", "/", "application/msgpack", "msgpack:amir"},
+		{"custom_default_explicit", render.MediaTypeMsgPack, "application/xml", "/", "application/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user id="1"><name>amir</name></user>`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.fallback != "" {
+				render.SetDefaultMediaType(tc.fallback)
+				defer render.SetDefaultMediaType(render.MediaTypeJSON)
+			}
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			if err := render.Negotiated(rec, req, http.StatusOK, user{ID: 1, Name: "amir"}); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Header().Get("Content-Type") != tc.contentType || rec.Body.String() != tc.body {
+				t.Errorf("got %q %q, want %q %q", rec.Header().Get("Content-Type"), rec.Body, tc.contentType, tc.body)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Errorf("got Vary %q, want Accept", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestFormatRegisteredEncoder(t *testing.T) {
+	render.RegisterEncoder(render.MediaTypeMsgPack, fakeMsgPack)
+
+	h := middleware.Negotiate(render.MediaTypeJSON, render.MediaTypeMsgPack)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := render.Format(w, r, http.StatusOK, user{ID: 1, Name: "amir"}); err != nil {
+			t.Error(err)
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept", render.MediaTypeMsgPack)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Type") != render.MediaTypeMsgPack || rec.Body.String() != "msgpack:amir" {
+		t.Errorf("got %q %q", rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
@@ -0,0 +1,163 @@
+// Package render writes response bodies in the common formats of HTTP APIs.
+//
+// Format serializes to the media type chosen by the middleware.Negotiate middleware, so a
+// handler can serve JSON and XML clients alike:
+//
+//	rahjoo.NewHandler(getUser, middleware.Negotiate("application/json", "application/xml"))
+//
+//	func getUser(w http.ResponseWriter, r *http.Request) {
+//		render.Format(w, r, http.StatusOK, user)
+//	}
+//
+// Values are encoded before anything is written, so on an encoding error the response is left
+// untouched and the caller can still answer with an error. The errors of writing the response
+// itself are returned as well, mostly for logging since the response has started then.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// PrettyParam is the query parameter asking Format to indent JSON and XML for human readers,
+// e.g. "?pretty" or "?pretty=true".
+const PrettyParam = "pretty"
+
+// JSON writes v encoded as JSON with status code.
+func JSON(w http.ResponseWriter, code int, v any) error {
+	return writeJSON(w, code, v, false)
+}
+
+// XML writes v encoded as XML with status code, preceded by the XML header.
+func XML(w http.ResponseWriter, code int, v any) error {
+	return writeXML(w, code, v, false)
+}
+
+// Blob writes data as is with status code and contentType.
+func Blob(w http.ResponseWriter, code int, contentType string, data []byte) error {
+	return write(w, code, contentType, data)
+}
+
+// Stream copies r to the response with status code and contentType, without buffering it,
+// e.g. to proxy a file from object storage.
+func Stream(w http.ResponseWriter, code int, contentType string, r io.Reader) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// Pretty reports whether r asks for indented output with the PrettyParam query parameter.
+func Pretty(r *http.Request) bool {
+	v, ok := r.URL.Query()[PrettyParam]
+	if !ok {
+		return false
+	}
+	if v[0] == "" {
+		return true
+	}
+	pretty, _ := strconv.ParseBool(v[0])
+	return pretty
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any, pretty bool) error {
+	var body []byte
+	var err error
+	if pretty {
+		body, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	return write(w, code, "application/json; charset=utf-8", append(body, '\n'))
+}
+
+func writeXML(w http.ResponseWriter, code int, v any, pretty bool) error {
+	var body []byte
+	var err error
+	if pretty {
+		body, err = xml.MarshalIndent(v, "", "  ")
+	} else {
+		body, err = xml.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	return write(w, code, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// TemplateExecutor renders named templates, as *template.Template of html/template and
+// *html.Renderer of the render/html package do.
+type TemplateExecutor interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// Templates is the TemplateExecutor HTML renders with. Set it once at startup.
+var Templates TemplateExecutor
+
+// HTML writes the template name of Templates, rendered with data, as HTML with status code.
+// It returns an error if Templates is not set.
+func HTML(w http.ResponseWriter, code int, name string, data any) error {
+	if Templates == nil {
+		return errors.New("render: Templates is not set")
+	}
+	var buf bytes.Buffer
+	if err := Templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	return write(w, code, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Text writes v formatted with fmt.Sprint as plain text with status code.
+func Text(w http.ResponseWriter, code int, v any) error {
+	return write(w, code, "text/plain; charset=utf-8", []byte(fmt.Sprint(v)))
+}
+
+// Format writes v in the media type negotiated by the middleware.Negotiate middleware: JSON for
+// application/json and "+json" types, XML for application/xml, text/xml and "+xml" types, and
+// plain text for text/plain, and the encoders added with RegisterEncoder for their media types.
+// Without negotiation, it writes JSON. It returns an error for other negotiated media types.
+// JSON and XML are indented if the request asks for it, see Pretty.
+func Format(w http.ResponseWriter, r *http.Request, code int, v any) error {
+	pretty := Pretty(r)
+	mediaType := middleware.GetMediaType(r.Context())
+	if mediaType == "" {
+		return writeJSON(w, code, v, pretty)
+	}
+	mt, _, _ := mime.ParseMediaType(mediaType)
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return writeJSON(w, code, v, pretty)
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		return writeXML(w, code, v, pretty)
+	case mt == "text/plain":
+		return Text(w, code, v)
+	}
+	if enc, ok := encoders.lookup(mt); ok {
+		body, err := enc(v)
+		if err != nil {
+			return err
+		}
+		return write(w, code, mt, body)
+	}
+	return fmt.Errorf("render: unsupported media type %q", mediaType)
+}
+
+func write(w http.ResponseWriter, code int, contentType string, body []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	return err
+}
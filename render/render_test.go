@@ -0,0 +1,152 @@
+package render_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/render"
+)
+
+type user struct {
+	ID   int    `json:"id" xml:"id,attr"`
+	Name string `json:"name" xml:"name"`
+}
+
+func (u user) String() string {
+	return u.Name
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		offered     []string
+		accept      string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{"no_negotiation", nil, "application/xml", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n", false},
+		{"json", []string{"application/json", "application/xml"}, "", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n", false},
+		{"xml", []string{"application/json", "application/xml"}, "application/xml", "application/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user id="1"><name>amir</name></user>`, false},
+		{"problem_json", []string{"application/problem+json"}, "", "application/json; charset=utf-8", `{"id":1,"name":"amir"}` + "\n", false},
+		{"text", []string{"text/plain"}, "", "text/plain; charset=utf-8", "amir", false},
+		{"unsupported", []string{"image/png"}, "", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				err = render.Format(w, r, http.StatusCreated, user{ID: 1, Name: "amir"})
+			})
+			if tc.offered != nil {
+				h = middleware.Negotiate(tc.offered...)(h)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != tc.contentType || rec.Body.String() != tc.body {
+				t.Errorf("got %d %q %q, want %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body, tc.contentType, tc.body)
+			}
+		})
+	}
+}
+
+func TestPretty(t *testing.T) {
+	testCases := []struct {
+		name    string
+		target  string
+		offered []string
+		body    string
+	}{
+		{"flag", "/?pretty", nil, "{\n  \"id\": 1,\n  \"name\": \"amir\"\n}\n"},
+		{"true", "/?pretty=true", nil, "{\n  \"id\": 1,\n  \"name\": \"amir\"\n}\n"},
+		{"false", "/?pretty=false", nil, `{"id":1,"name":"amir"}` + "\n"},
+		{"absent", "/", nil, `{"id":1,"name":"amir"}` + "\n"},
+		{"xml", "/?pretty=1", []string{"application/xml"}, `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + "<user id=\"1\">\n  <name>amir</name>\n</user>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				render.Format(w, r, http.StatusOK, user{ID: 1, Name: "amir"})
+			})
+			if tc.offered != nil {
+				h = middleware.Negotiate(tc.offered...)(h)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, http.NoBody))
+			if rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body, tc.body)
+			}
+		})
+	}
+}
+
+func TestBlobAndStream(t *testing.T) {
+	testCases := []struct {
+		name  string
+		write func(w http.ResponseWriter) error
+	}{
+		{"blob", func(w http.ResponseWriter) error {
+			return render.Blob(w, http.StatusAccepted, "application/octet-stream", []byte("raw"))
+		}},
+		{"stream", func(w http.ResponseWriter) error {
+			return render.Stream(w, http.StatusAccepted, "application/octet-stream", strings.NewReader("raw"))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := tc.write(rec); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusAccepted || rec.Header().Get("Content-Type") != "application/octet-stream" || rec.Body.String() != "raw" {
+				t.Errorf("got %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+			}
+		})
+	}
+}
+
+func TestJSONEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := render.JSON(rec, http.StatusOK, func() {}); err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if rec.Body.Len() != 0 || len(rec.Header()) != 0 {
+		t.Errorf("got response written on encoding error: %v %q", rec.Header(), rec.Body)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	defer func(t render.TemplateExecutor) { render.Templates = t }(render.Templates)
+
+	render.Templates = nil
+	if err := render.HTML(httptest.NewRecorder(), http.StatusOK, "page", nil); err == nil {
+		t.Fatal("expected an error without templates")
+	}
+
+	render.Templates = template.Must(template.New("page").Parse(`<p>{{.}}</p>`))
+	rec := httptest.NewRecorder()
+	if err := render.HTML(rec, http.StatusOK, "page", "<b>"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" || rec.Body.String() != "<p>&lt;b&gt;</p>" {
+		t.Errorf("got %q %q", rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
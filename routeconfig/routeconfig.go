@@ -0,0 +1,222 @@
+// Package routeconfig builds rahjoo route tables from declarative JSON or YAML definitions, so
+// operators can adjust routing, such as paths, methods and middlewares, without recompiling:
+//
+//	{
+//	  "prefix": "/api",
+//	  "middlewares": ["recovery", "requestid"],
+//	  "routes": [
+//	    {"path": "/users", "methods": ["GET"], "handler": "users.list", "name": "users"},
+//	    {"path": "/users", "methods": ["POST"], "handler": "users.create",
+//	     "middlewares": ["enforcejson", "ratelimit:10rps"]},
+//	    {"path": "/health", "handler": "health", "metadata": {"public": true}}
+//	  ]
+//	}
+//
+// Handlers are looked up by name in a Handlers registry and middlewares are resolved with
+// middleware.Resolve, by reference ("ratelimit:10rps") or by name and configuration
+// ({"name": "cors", "config": {"origins": ["https://example.com"]}}). Routes without methods
+// handle all methods. Metadata is available to handlers and middlewares with Metadata.
+//
+// The package only depends on encoding/json. YAML definitions are parsed by passing the Unmarshal
+// function of a YAML package decoding mappings into map[string]any, such as gopkg.in/yaml.v3,
+// with WithUnmarshaler.
+package routeconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+// ErrUnknownHandler is returned for routes referring to a handler missing from the registry.
+var ErrUnknownHandler = errors.New("routeconfig: unknown handler")
+
+// Handlers maps the handler names used in route definitions to handlers.
+type Handlers map[string]http.HandlerFunc
+
+// Config is a declarative route table.
+type Config struct {
+	// Prefix is prepended to the path of every route.
+	Prefix string `json:"prefix,omitempty"`
+	// Middlewares run before the middlewares of every route.
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty"`
+	Routes      []RouteConfig      `json:"routes"`
+}
+
+// RouteConfig defines a route.
+type RouteConfig struct {
+	Path string `json:"path"`
+	// Methods handled by the route, all of them if empty.
+	Methods     []string           `json:"methods,omitempty"`
+	Handler     string             `json:"handler"`
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty"`
+	// Name identifies the route for URL generation, see rahjoo's WithName.
+	Name     string         `json:"name,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// MiddlewareConfig refers to a registered middleware, see middleware.Resolve. It is decoded from
+// either a reference string ("ratelimit:10rps") or an object with "name" and "config" fields.
+type MiddlewareConfig struct {
+	Name   string         `json:"name"`
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// UnmarshalJSON decodes a middleware reference string or object.
+func (m *MiddlewareConfig) UnmarshalJSON(data []byte) error {
+	var ref string
+	if err := json.Unmarshal(data, &ref); err == nil {
+		*m = MiddlewareConfig{Name: ref}
+		return nil
+	}
+	type plain MiddlewareConfig
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+type options struct {
+	unmarshal func(data []byte, v any) error
+}
+
+// Option configures how route definitions are parsed.
+type Option func(*options)
+
+// WithUnmarshaler parses route definitions with unmarshal instead of json.Unmarshal, e.g. with
+// yaml.Unmarshal of gopkg.in/yaml.v3. unmarshal must decode mappings into map[string]any.
+func WithUnmarshaler(unmarshal func(data []byte, v any) error) Option {
+	return func(o *options) {
+		o.unmarshal = unmarshal
+	}
+}
+
+// Parse decodes route definitions from data. It does not resolve handlers and middlewares.
+func Parse(data []byte, opts ...Option) (*Config, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.unmarshal != nil {
+		// Decode generically first, then through JSON, so the struct tags and the middleware
+		// decoding rules are shared by every format.
+		var raw any
+		if err := o.unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("routeconfig: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("routeconfig: %w", err)
+		}
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("routeconfig: %w", err)
+	}
+	return &c, nil
+}
+
+// Build materializes the route table defined by c, looking handlers up in handlers and
+// resolving middlewares with middleware.Resolve. All problems are reported, joined as
+// *rahjoo.RouteError values when they concern a route.
+func (c *Config) Build(handlers Handlers) (rahjoo.Route, error) {
+	var errs []error
+	global, err := resolveAll(c.Middlewares)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	routes := rahjoo.Route{}
+	for _, rc := range c.Routes {
+		path := rahjoo.Path(rc.Path)
+		fail := func(err error) {
+			errs = append(errs, &rahjoo.RouteError{Path: path, Err: err})
+		}
+
+		handler, ok := handlers[rc.Handler]
+		if !ok {
+			fail(fmt.Errorf("%w %q", ErrUnknownHandler, rc.Handler))
+			continue
+		}
+		mws, err := resolveAll(rc.Middlewares)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		action := rahjoo.NewHandler(handler, append(global[:len(global):len(global)], mws...)...)
+		if rc.Name != "" {
+			action = action.WithName(rc.Name)
+		}
+		if rc.Metadata != nil {
+			action = action.WithValue(metadataKey{}, rc.Metadata)
+		}
+
+		methods := rc.Methods
+		if len(methods) == 0 {
+			methods = []string{""}
+		}
+		for _, method := range methods {
+			if _, dup := routes[path][rahjoo.Method(method)]; dup {
+				errs = append(errs, &rahjoo.RouteError{Path: path, Method: rahjoo.Method(method), Err: errors.New("route defined twice")})
+				continue
+			}
+			routes.Add(path, rahjoo.Method(method), action)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if c.Prefix != "" {
+		routes = rahjoo.NewGroupRoute(c.Prefix, routes)
+	}
+	return routes, nil
+}
+
+func resolveAll(configs []MiddlewareConfig) ([]middleware.Middleware, error) {
+	var errs []error
+	mws := make([]middleware.Middleware, 0, len(configs))
+	for _, mc := range configs {
+		m, err := middleware.Resolve(mc.Name, mc.Config)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mws = append(mws, m)
+	}
+	return mws, errors.Join(errs...)
+}
+
+// Load parses the route definitions of the named file and builds their route table.
+func Load(name string, handlers Handlers, opts ...Option) (rahjoo.Route, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("routeconfig: %w", err)
+	}
+	c, err := Parse(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Build(handlers)
+}
+
+// Loader returns a rahjoo.RouteLoader loading the named file, so a rahjoo.DynamicRouter can
+// reload it, e.g. with WatchFile.
+func Loader(name string, handlers Handlers, opts ...Option) rahjoo.RouteLoader {
+	return func() (rahjoo.Route, error) {
+		return Load(name, handlers, opts...)
+	}
+}
+
+type metadataKey struct{}
+
+// Metadata returns the metadata of the route serving r, or nil if it has none.
+func Metadata(r *http.Request) map[string]any {
+	m, _ := r.Context().Value(metadataKey{}).(map[string]any)
+	return m
+}
@@ -0,0 +1,161 @@
+package routeconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/routeconfig"
+)
+
+const config = `{
+	"prefix": "/api/",
+	"middlewares": ["requestid"],
+	"routes": [
+		{"path": "/users", "methods": ["GET"], "handler": "users.list", "name": "users"},
+		{"path": "/users", "methods": ["POST"], "handler": "users.create", "middlewares": ["enforcejson"]},
+		{"path": "/health", "handler": "health", "metadata": {"public": true},
+		 "middlewares": [{"name": "cors", "config": {"origins": ["https://example.com"]}}]}
+	]
+}`
+
+var handlers = routeconfig.Handlers{
+	"users.list":   func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "list") },
+	"users.create": func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "create") },
+	"health": func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "public=%v", routeconfig.Metadata(r)["public"])
+	},
+}
+
+func TestBuild(t *testing.T) {
+	c, err := routeconfig.Parse([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := c.Build(handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := routes["/api/users"][http.MethodGet].Name(); name != "users" {
+		t.Errorf("got route name %q, want users", name)
+	}
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		status      int
+		body        string
+	}{
+		{"get", http.MethodGet, "/api/users", "", http.StatusOK, "list"},
+		{"post", http.MethodPost, "/api/users", "application/json", http.StatusOK, "create"},
+		{"route_middleware", http.MethodPost, "/api/users", "text/plain", http.StatusUnsupportedMediaType, ""},
+		{"all_methods", http.MethodDelete, "/api/health", "", http.StatusOK, "public=true"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, http.NoBody)
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("Origin", "https://example.com")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+			if rec.Header().Get(middleware.RequestIDHeader) == "" {
+				t.Error("global middleware did not run")
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); (tc.path == "/api/health") != (got != "") {
+				t.Errorf("got Access-Control-Allow-Origin %q", got)
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	c, err := routeconfig.Parse([]byte(`{"routes": [
+		{"path": "/a", "handler": "missing"},
+		{"path": "/b", "handler": "health", "middlewares": ["nope"]},
+		{"path": "/c", "methods": ["GET", "GET"], "handler": "health"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Build(handlers)
+	if !errors.Is(err, routeconfig.ErrUnknownHandler) || !errors.Is(err, middleware.ErrUnknownMiddleware) {
+		t.Errorf("got error %v, want unknown handler and middleware errors", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), `route GET "/c": route defined twice`) {
+		t.Errorf("got error %v, want a duplicate route error", err)
+	}
+
+	if _, err := routeconfig.Parse([]byte(`{"routes": [{"path": 1}]}`)); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestWithUnmarshaler(t *testing.T) {
+	// fakeYAML stands for the Unmarshal function of a YAML package.
+	fakeYAML := func(data []byte, v any) error {
+		if string(data) != "routes: ..." {
+			return errors.New("bad yaml")
+		}
+		*v.(*any) = map[string]any{
+			"routes": []any{map[string]any{"path": "/users", "handler": "users.list", "middlewares": []any{"nocache"}}},
+		}
+		return nil
+	}
+
+	c, err := routeconfig.Parse([]byte("routes: ..."), routeconfig.WithUnmarshaler(fakeYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Routes) != 1 || c.Routes[0].Middlewares[0].Name != "nocache" {
+		t.Errorf("got config %+v", c)
+	}
+	if _, err := routeconfig.Parse([]byte("{"), routeconfig.WithUnmarshaler(fakeYAML)); err == nil {
+		t.Error("expected the unmarshaler error")
+	}
+}
+
+func TestLoader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(name, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := rahjoo.NewDynamicRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Reload(routeconfig.Loader(name, handlers)); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", http.NoBody))
+	if rec.Body.String() != "list" {
+		t.Errorf("got body %q, want list", rec.Body.String())
+	}
+
+	if _, err := routeconfig.Load(filepath.Join(t.TempDir(), "missing.json"), handlers); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
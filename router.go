@@ -31,7 +31,9 @@
 //
 //	// Bind the routes to the ServeMux
 //	mux := http.NewServeMux()
-//	router.BindRoutesToMux(mux, routes)
+//	if err := router.BindRoutesToMux(mux, routes); err != nil {
+//	    log.Fatal(err)
+//	}
 //
 //	// Start the HTTP server
 //	http.ListenAndServe(":8080", mux)
@@ -40,9 +42,13 @@
 package rahjoo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/amirzayi/rahjoo/middleware"
 )
@@ -59,6 +65,16 @@ type (
 		// to the handler. These middlewares are executed in the order they are defined,
 		// with the last middleware in the slice being the first to execute (closest to the handler).
 		middlewares []middleware.Middleware
+		// values holds static key/value pairs injected into the request context
+		// before any middleware or the handler itself runs.
+		values []contextValue
+		// name identifies the route for URL generation, see WithName.
+		name string
+	}
+
+	// contextValue is a single static key/value pair attached to a route with WithValue.
+	contextValue struct {
+		key, val any
 	}
 
 	// Path represents the URL path for a route (e.g., "/shelves/{shelf_id}/books").
@@ -87,28 +103,129 @@ func (ah actionHandler) Middlewares() []middleware.Middleware {
 	return ah.middlewares
 }
 
+// WithValue returns a copy of the actionHandler that stores val under key in the request
+// context before the middlewares and the handler run. It is useful for passing per-route
+// configuration (e.g. required scopes, tenant hints) into shared handlers and middlewares,
+// which can read it back with r.Context().Value(key).
+// As with context.WithValue, key should be of a user-defined type to avoid collisions.
+func (ah actionHandler) WithValue(key, val any) actionHandler {
+	ah.values = append(ah.values[:len(ah.values):len(ah.values)], contextValue{key: key, val: val})
+	return ah
+}
+
+// WithName returns a copy of the actionHandler named name, so URLs of the route can be built
+// from the route table by name, e.g. with the links package, instead of string templates.
+// Names should be unique across the route table.
+func (ah actionHandler) WithName(name string) actionHandler {
+	ah.name = name
+	return ah
+}
+
+// Name returns the name set with WithName, or an empty string.
+func (ah actionHandler) Name() string {
+	return ah.name
+}
+
+// build composes the final http.Handler of the actionHandler by chaining its middlewares
+// and injecting its static context values, if any.
+func (ah actionHandler) build() http.Handler {
+	handler := middleware.Chain(ah.handler, ah.middlewares...)
+	if len(ah.values) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		for _, v := range ah.values {
+			ctx = context.WithValue(ctx, v.key, v.val)
+		}
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // NewGroupRoute creates a new Group Route with prefix(e.g., "/api/v1").
+// Prefix and paths are joined with a single slash, so "/api/" or "api" work as well, and
+// duplicate slashes are collapsed.
 func NewGroupRoute(prefix string, routes ...Route) Route {
 	r := Route{}
 	for _, route := range routes {
 		for path, method := range route {
-			r[Path(fmt.Sprintf("%s%s", prefix, path))] = method
+			r[joinPath(prefix, path)] = method
 		}
 	}
 	return r
 }
 
+// joinPath appends path to prefix, making sure the result begins with a slash, that prefix and
+// path are separated by one, and collapsing duplicate slashes. A trailing slash is kept, so
+// joining "/api" and "/" gives "/api/", matching the whole subtree.
+func joinPath(prefix string, path Path) Path {
+	sep := "/"
+	if path == "" {
+		sep = ""
+	}
+	var b strings.Builder
+	b.Grow(len(prefix) + len(path) + 2)
+	b.WriteByte('/')
+	last := byte('/')
+	for _, s := range [...]string{prefix, sep, string(path)} {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '/' && last == '/' {
+				continue
+			}
+			b.WriteByte(s[i])
+			last = s[i]
+		}
+	}
+	return Path(b.String())
+}
+
 // SetMiddleware set some middlewares on route.
+// The middlewares run after the ones already set on each handler, closest to the handler,
+// like with AppendMiddleware. Like AppendMiddleware and PrependMiddleware, it modifies the
+// route in place, so every holder of the route sees the change; use Clone or Freeze to share a
+// base route table.
 func (r Route) SetMiddleware(middlewares ...middleware.Middleware) Route {
+	return r.AppendMiddleware(middlewares...)
+}
+
+// AppendMiddleware adds middlewares after the ones already set on every handler of the route,
+// so they run last, closest to the handler. It suits group-level concerns depending on what
+// the handler middlewares did, such as validating a body they decoded.
+func (r Route) AppendMiddleware(middlewares ...middleware.Middleware) Route {
 	for _, path := range r {
-		for method := range path {
-			mids := append(path[method].middlewares, middlewares...)
-			path[method] = NewHandler(path[method].handler, mids...)
+		for method, action := range path {
+			action.middlewares = slices.Concat(action.middlewares, middlewares)
+			path[method] = action
 		}
 	}
 	return r
 }
 
+// PrependMiddleware adds middlewares before the ones already set on every handler of the route,
+// so they run first, outermost. It suits group-level concerns that must wrap everything else,
+// such as recovery and logging:
+//
+//	api := rahjoo.NewGroupRoute("/api", routes).PrependMiddleware(middleware.Recovery(logger))
+func (r Route) PrependMiddleware(middlewares ...middleware.Middleware) Route {
+	for _, path := range r {
+		for method, action := range path {
+			action.middlewares = slices.Concat(middlewares, action.middlewares)
+			path[method] = action
+		}
+	}
+	return r
+}
+
+// Add registers action for path and method, keeping the other methods of path. It is useful for
+// building route tables programmatically, e.g. from configuration.
+func (r Route) Add(path Path, method Method, action actionHandler) Route {
+	if r[path] == nil {
+		r[path] = map[Method]actionHandler{}
+	}
+	r[path][method] = action
+	return r
+}
+
 // NewHandler creates an actionHandler with the given HTTP handler and middlewares.
 // The middlewares are applied in reverse order, meaning the last middleware in the list
 // will be executed first (closest to the handler).
@@ -123,13 +240,134 @@ func NewHandler(handler http.HandlerFunc, middlewares ...middleware.Middleware)
 // It iterates over the routes, applies the middlewares to each handler using middleware.Chain,
 // and registers the handlers with the ServeMux. The route paths are combined with their HTTP methods
 // to create unique route identifiers (e.g., "GET /api/v1/books").
-func BindRoutesToMux(mux *http.ServeMux, routes ...Route) {
+//
+// Instead of letting the ServeMux panic, it returns all registration problems joined as
+// *RouteError values. Malformed routes (see Route.Validate) and patterns conflicting with each
+// other are detected before anything is registered, in which case the mux is left untouched.
+// Routes conflicting with patterns already registered on mux are skipped and reported.
+func BindRoutesToMux(mux *http.ServeMux, routes ...Route) error {
+	return BindRoutesToMuxWithOptions(mux, routes)
+}
+
+// BindRoutesToMuxWithPrefix binds the provided routes to a http.ServeMux like BindRoutesToMux,
+// re-mounting the whole route table under prefix (e.g., "/internal"). It is useful when the
+// service is deployed behind a path-based ingress.
+func BindRoutesToMuxWithPrefix(mux *http.ServeMux, prefix string, routes ...Route) error {
+	return BindRoutesToMuxWithOptions(mux, routes, WithPrefix(prefix))
+}
+
+// BindRoutesToMuxWithOptions binds the provided routes to a http.ServeMux like BindRoutesToMux,
+// applying the given options to the whole route table.
+func BindRoutesToMuxWithOptions(mux *http.ServeMux, routes []Route, opts ...BindOption) error {
+	mergedRoutes, err := prepareRoutes(routes, opts)
+	if err != nil {
+		return err
+	}
+	compiled := compileRoutes(mergedRoutes)
+	if err := registerRoutes(http.NewServeMux(), compiled); err != nil {
+		return err
+	}
+	return registerRoutes(mux, compiled)
+}
+
+// prepareRoutes merges routes into a single route table, applies opts to it and validates it.
+func prepareRoutes(routes []Route, opts []BindOption) (Route, error) {
+	o := bindOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	mergedRoutes := MergeRoutes(routes...)
-	for route, handler := range mergedRoutes {
-		for method, action := range handler {
-			mux.Handle(fmt.Sprintf("%s %s", method, route), middleware.Chain(action.handler, action.middlewares...))
+	if o.prefix != "" {
+		mergedRoutes = NewGroupRoute(o.prefix, mergedRoutes)
+	}
+	if len(o.middlewares) > 0 {
+		mergedRoutes = mergedRoutes.Clone()
+		for _, methods := range mergedRoutes {
+			for method, action := range methods {
+				action.middlewares = slices.Concat(o.middlewares, action.middlewares)
+				methods[method] = action
+			}
+		}
+	}
+	if err := mergedRoutes.Validate(); err != nil {
+		return nil, err
+	}
+	return mergedRoutes, nil
+}
+
+// BindOption configures how routes are bound to a http.ServeMux.
+type BindOption func(*bindOptions)
+
+type bindOptions struct {
+	prefix      string
+	middlewares []middleware.Middleware
+}
+
+// WithPrefix mounts every bound route under prefix (e.g., "/internal").
+func WithPrefix(prefix string) BindOption {
+	return func(o *bindOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithGlobalMiddleware applies the given middlewares to every bound route exactly once.
+// They run before the route's own middlewares, which makes the option suitable for
+// cross-cutting concerns such as request IDs, logging and recovery.
+// Multiple WithGlobalMiddleware options accumulate in the given order.
+func WithGlobalMiddleware(middlewares ...middleware.Middleware) BindOption {
+	return func(o *bindOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// compiledRoute is a route whose final http.Handler has been composed.
+type compiledRoute struct {
+	path    Path
+	method  Method
+	pattern string
+	handler http.Handler
+}
+
+// compileRoutes composes the final http.Handler of every route exactly once, in a deterministic
+// order, so the dry run and the actual registration share the same handlers and no middleware
+// chain is rebuilt after binding.
+func compileRoutes(routes Route) []compiledRoute {
+	compiled := make([]compiledRoute, 0, len(routes))
+	for _, path := range sortedPaths(routes) {
+		for _, method := range sortedMethods(routes[path]) {
+			compiled = append(compiled, compiledRoute{
+				path:    path,
+				method:  method,
+				pattern: string(method) + " " + string(path),
+				handler: withRoute(path, method, routes[path][method].build()),
+			})
 		}
 	}
+	return compiled
+}
+
+// registerRoutes registers every compiled route on mux, recovering the panics of mux.Handle
+// and returning them joined as *RouteError values.
+func registerRoutes(mux *http.ServeMux, routes []compiledRoute) error {
+	var errs []error
+	for _, route := range routes {
+		if err := handle(mux, route.pattern, route.handler); err != nil {
+			errs = append(errs, &RouteError{Path: route.path, Method: route.method, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handle registers handler for pattern on mux, turning a registration panic into an error.
+func handle(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
 }
 
 // MergeRoutes combines multiple Route maps into a single Route map.
@@ -142,3 +380,38 @@ func MergeRoutes(routes ...Route) Route {
 	}
 	return merged
 }
+
+// Clone returns a deep copy of the route, which can be modified, e.g. with SetMiddleware,
+// without affecting the route.
+func (r Route) Clone() Route {
+	c := make(Route, len(r))
+	for path, methods := range r {
+		c[path] = make(map[Method]actionHandler, len(methods))
+		for method, action := range methods {
+			action.middlewares = slices.Clone(action.middlewares)
+			action.values = slices.Clone(action.values)
+			c[path][method] = action
+		}
+	}
+	return c
+}
+
+// FrozenRoute is an immutable route table, which can be shared, e.g. across tenants or tests,
+// without any holder affecting the others.
+type FrozenRoute struct {
+	routes Route
+}
+
+// Freeze returns an immutable copy of the route. Later changes to the route do not affect it.
+func (r Route) Freeze() FrozenRoute {
+	return FrozenRoute{routes: r.Clone()}
+}
+
+// Route returns a copy of the frozen route table, which can be modified and bound:
+//
+//	base := rahjoo.Route{...}.Freeze()
+//	tenantA := base.Route().SetMiddleware(tenant("a"))
+//	tenantB := base.Route().SetMiddleware(tenant("b"))
+func (f FrozenRoute) Route() Route {
+	return f.routes.Clone()
+}
@@ -3,8 +3,10 @@ package rahjoo_test
 import (
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
 	"github.com/amirzayi/rahjoo"
@@ -51,7 +53,9 @@ func TestRouting(t *testing.T) {
 	})
 
 	mux := http.NewServeMux()
-	rahjoo.BindRoutesToMux(mux, r, r2)
+	if err := rahjoo.BindRoutesToMux(mux, r, r2); err != nil {
+		t.Fatal(err)
+	}
 
 	testCases := []struct {
 		method string
@@ -105,7 +109,9 @@ func TestMiddleware(t *testing.T) {
 	jsonRoute := rahjoo.Route{"/json": {http.MethodGet: rahjoo.NewHandler(jsonHandler, middleware.EnforceJSON)}}
 
 	mux := http.NewServeMux()
-	rahjoo.BindRoutesToMux(mux, panicRoute, jsonRoute)
+	if err := rahjoo.BindRoutesToMux(mux, panicRoute, jsonRoute); err != nil {
+		t.Fatal(err)
+	}
 
 	testCases := []struct {
 		name   string
@@ -159,7 +165,9 @@ func TestGroupRoute(t *testing.T) {
 
 	rec2 := httptest.NewRecorder()
 
-	rahjoo.BindRoutesToMux(mux, r)
+	if err := rahjoo.BindRoutesToMux(mux, r); err != nil {
+		t.Fatal(err)
+	}
 	mux.ServeHTTP(rec2, req)
 
 	res := rec2.Result()
@@ -167,3 +175,335 @@ func TestGroupRoute(t *testing.T) {
 		t.Errorf("got status code %d, want %d", res.StatusCode, http.StatusBadRequest)
 	}
 }
+
+func TestWithValue(t *testing.T) {
+	type ctxKey string
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		scope, _ := r.Context().Value(ctxKey("scope")).(string)
+		tenant, _ := r.Context().Value(ctxKey("tenant")).(string)
+		fmt.Fprintf(w, "%s:%s", scope, tenant)
+	}
+
+	r := rahjoo.NewGroupRoute("/api", rahjoo.Route{
+		"/admin": {
+			http.MethodGet: rahjoo.NewHandler(h).WithValue(ctxKey("scope"), "admin").WithValue(ctxKey("tenant"), "acme"),
+		},
+		"/public": {
+			http.MethodGet: rahjoo.NewHandler(h).WithValue(ctxKey("scope"), "public"),
+		},
+	}).SetMiddleware(middleware.EnforceJSON)
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, r); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path string
+		body string
+	}{
+		{"/api/admin", "admin:acme"},
+		{"/api/public", "public:"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.path, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if body := rec.Body.String(); body != tc.body {
+				t.Errorf("got body %q, want %q", body, tc.body)
+			}
+		})
+	}
+}
+
+func TestBindRoutesToMuxWithPrefix(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	r := rahjoo.NewGroupRoute("/api/v1", rahjoo.Route{
+		"/users": {http.MethodGet: rahjoo.NewHandler(h)},
+	})
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMuxWithPrefix(mux, "/internal", r); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{"/internal/api/v1/users", http.StatusOK},
+		{"/api/v1/users", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestWithGlobalMiddleware(t *testing.T) {
+	var order []string
+	trace := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(http.ResponseWriter, *http.Request) { order = append(order, "handler") }
+
+	routes := rahjoo.Route{
+		"/a": {http.MethodGet: rahjoo.NewHandler(h, trace("route"))},
+		"/b": {http.MethodGet: rahjoo.NewHandler(h)},
+	}
+
+	mux := http.NewServeMux()
+	err := rahjoo.BindRoutesToMuxWithOptions(mux, []rahjoo.Route{routes},
+		rahjoo.WithGlobalMiddleware(trace("global1")),
+		rahjoo.WithGlobalMiddleware(trace("global2")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path  string
+		order []string
+	}{
+		{"/a", []string{"global1", "global2", "route", "handler"}},
+		{"/b", []string{"global1", "global2", "handler"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			order = nil
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, http.NoBody))
+			if !slices.Equal(order, tc.order) {
+				t.Errorf("got order %v, want %v", order, tc.order)
+			}
+		})
+	}
+
+	if mids := routes["/a"][http.MethodGet].Middlewares(); len(mids) != 1 {
+		t.Errorf("binding modified the route table: got %d middlewares, want 1", len(mids))
+	}
+}
+
+func TestChainsComposedOnceAtBind(t *testing.T) {
+	wraps := 0
+	counting := func(next http.Handler) http.Handler {
+		wraps++
+		return next
+	}
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	routes := rahjoo.Route{
+		"/a": {http.MethodGet: rahjoo.NewHandler(h, counting)},
+		"/b": {http.MethodGet: rahjoo.NewHandler(h, counting), http.MethodPost: rahjoo.NewHandler(h, counting)},
+	}
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, routes); err != nil {
+		t.Fatal(err)
+	}
+	if wraps != 3 {
+		t.Errorf("got %d middleware compositions at bind, want 3", wraps)
+	}
+
+	for range 2 {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", http.NoBody))
+	}
+	if wraps != 3 {
+		t.Errorf("got %d middleware compositions after serving, want 3", wraps)
+	}
+}
+
+func TestPrependAppendMiddleware(t *testing.T) {
+	var order []string
+	trace := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := func(http.ResponseWriter, *http.Request) { order = append(order, "handler") }
+
+	// Handlers sharing a middleware slice must not see each other's additions.
+	shared := make([]middleware.Middleware, 1, 4)
+	shared[0] = trace("handler_mw")
+	routes := rahjoo.Route{
+		"/a": {http.MethodGet: rahjoo.NewHandler(h, shared...)},
+		"/b": {http.MethodGet: rahjoo.NewHandler(h, shared...)},
+	}
+	routes.AppendMiddleware(trace("append"))
+	routes.PrependMiddleware(trace("prepend1"), trace("prepend2"))
+	routes.SetMiddleware(trace("set"))
+
+	mux := http.NewServeMux()
+	if err := rahjoo.BindRoutesToMux(mux, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"prepend1", "prepend2", "handler_mw", "append", "set", "handler"}
+	for _, path := range []string{"/a", "/b"} {
+		order = nil
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		if !slices.Equal(order, want) {
+			t.Errorf("%s: got order %v, want %v", path, order, want)
+		}
+	}
+}
+
+// benchmarkSizes are the numbers of resources of the route tables of the benchmarks.
+var benchmarkSizes = []struct {
+	name      string
+	resources int
+}{{"small", 10}, {"medium", 100}, {"large", 1000}}
+
+func BenchmarkBindRoutesToMux(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		routes := benchmarkRoutes(size.resources)
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if err := rahjoo.BindRoutesToMux(http.NewServeMux(), routes...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkServe(b *testing.B) {
+	pass := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next.ServeHTTP(w, r) })
+	}
+
+	for _, size := range benchmarkSizes {
+		for _, depth := range []int{0, 10, 50} {
+			routes := benchmarkRoutes(size.resources)
+			mux := http.NewServeMux()
+			err := rahjoo.BindRoutesToMuxWithOptions(mux, routes,
+				rahjoo.WithGlobalMiddleware(slices.Repeat([]middleware.Middleware{pass}, depth)...))
+			if err != nil {
+				b.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/resource%d/42", size.resources/2), http.NoBody)
+
+			b.Run(fmt.Sprintf("%s/middlewares=%d", size.name, depth), func(b *testing.B) {
+				w := httptest.NewRecorder()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					mux.ServeHTTP(w, req)
+				}
+			})
+		}
+	}
+}
+
+func TestGroupRoutePaths(t *testing.T) {
+	h := rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})
+
+	testCases := []struct {
+		prefix string
+		path   rahjoo.Path
+		want   rahjoo.Path
+	}{
+		{"/api", "/users", "/api/users"},
+		{"/api/", "/users", "/api/users"},
+		{"/api", "/", "/api/"},
+		{"/api/", "/", "/api/"},
+		{"api", "/users", "/api/users"},
+		{"/api", "users", "/api/users"},
+		{"//api//v1/", "//users//{id}", "/api/v1/users/{id}"},
+		{"", "/users", "/users"},
+		{"", "/", "/"},
+		{"/", "/", "/"},
+		{"/", "/users/", "/users/"},
+		{"/api", "", "/api"},
+		{"/api/", "/{$}", "/api/{$}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.prefix+string(tc.path), func(t *testing.T) {
+			r := rahjoo.NewGroupRoute(tc.prefix, rahjoo.Route{tc.path: {http.MethodGet: h}})
+			if _, ok := r[tc.want]; !ok || len(r) != 1 {
+				t.Errorf("got paths %v, want %s", slices.Collect(maps.Keys(r)), tc.want)
+			}
+		})
+	}
+}
+
+func BenchmarkNewGroupRoute(b *testing.B) {
+	routes := rahjoo.MergeRoutes(benchmarkRoutes(1000)...)
+	b.ReportAllocs()
+	for range b.N {
+		rahjoo.NewGroupRoute("/tenant", routes)
+	}
+}
+
+func TestCloneAndFreeze(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	base := rahjoo.Route{
+		"/users": {http.MethodGet: rahjoo.NewHandler(h, middleware.EnforceJSON)},
+	}
+
+	clone := base.Clone()
+	clone.SetMiddleware(middleware.EnforceJSON)
+	clone["/posts"] = clone["/users"]
+	if mids := base["/users"][http.MethodGet].Middlewares(); len(mids) != 1 || len(base) != 1 {
+		t.Errorf("modifying the clone modified the route: got %d paths and %d middlewares", len(base), len(mids))
+	}
+
+	frozen := base.Freeze()
+	base.SetMiddleware(middleware.EnforceJSON)
+	a := frozen.Route().SetMiddleware(middleware.EnforceJSON, middleware.EnforceJSON)
+	b := frozen.Route()
+
+	testCases := []struct {
+		name  string
+		route rahjoo.Route
+		want  int
+	}{
+		{"base", base, 2},
+		{"tenant_a", a, 3},
+		{"tenant_b", b, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := len(tc.route["/users"][http.MethodGet].Middlewares()); got != tc.want {
+				t.Errorf("got %d middlewares, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRouteAdd(t *testing.T) {
+	h := rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})
+	r := rahjoo.Route{}.
+		Add("/users", http.MethodGet, h).
+		Add("/users", http.MethodPost, h).
+		Add("/posts", "", h)
+
+	if len(r) != 2 || len(r["/users"]) != 2 || len(r["/posts"]) != 1 {
+		t.Errorf("got route table %v", r)
+	}
+}
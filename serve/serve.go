@@ -0,0 +1,170 @@
+// Package serve serves files and other seekable content with correct Range, If-Range and
+// conditional request handling in one call:
+//
+//	func download(w http.ResponseWriter, r *http.Request) {
+//		serve.FileIn(w, r, "/var/reports", r.PathValue("name"), serve.WithAttachment(""))
+//	}
+package serve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type config struct {
+	etag        string
+	contentType string
+	disposition string
+	filename    string
+}
+
+// Option configures how content is served.
+type Option func(*config)
+
+// WithETag sets the entity tag of the content, quoted (e.g. `"v42"`), instead of the generated
+// one. It must be a strong tag for Range requests to be honored.
+func WithETag(etag string) Option {
+	return func(c *config) {
+		c.etag = etag
+	}
+}
+
+// WithContentType sets the Content-Type of the content instead of deriving it from the name
+// extension or sniffing it.
+func WithContentType(contentType string) Option {
+	return func(c *config) {
+		c.contentType = contentType
+	}
+}
+
+// WithAttachment makes clients download the content as filename rather than display it.
+// An empty filename uses the name of the content.
+func WithAttachment(filename string) Option {
+	return func(c *config) {
+		c.disposition = "attachment"
+		c.filename = filename
+	}
+}
+
+// WithInline makes clients display the content, suggesting filename if it is saved.
+// An empty filename uses the name of the content.
+func WithInline(filename string) Option {
+	return func(c *config) {
+		c.disposition = "inline"
+		c.filename = filename
+	}
+}
+
+// Content replies to the request with content using http.ServeContent, handling Range, If-Range,
+// If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since requests. name is used to
+// derive the Content-Type and the Content-Disposition filename, and modtime, if not zero, is
+// sent as Last-Modified.
+//
+// Unless set by WithETag, a strong ETag is generated from modtime and the size of the content,
+// or from a SHA-256 hash of the content if modtime is zero.
+func Content(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker, opts ...Option) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	h := w.Header()
+	if c.etag == "" {
+		etag, err := generateETag(modtime, content)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		c.etag = etag
+	}
+	h.Set("ETag", c.etag)
+	if c.contentType != "" {
+		h.Set("Content-Type", c.contentType)
+	}
+	if c.disposition != "" {
+		filename := c.filename
+		if filename == "" {
+			filename = filepath.Base(name)
+		}
+		h.Set("Content-Disposition", mime.FormatMediaType(c.disposition, map[string]string{"filename": filename}))
+	}
+	http.ServeContent(w, r, name, modtime, content)
+}
+
+// File replies to the request with the contents of the named file as Content does, with the
+// modification time of the file. Missing files and directories get 404 Not Found and
+// unreadable files 403 Forbidden.
+func File(w http.ResponseWriter, r *http.Request, name string, opts ...Option) {
+	f, err := os.Open(name)
+	if err != nil {
+		fileError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		fileError(w, err)
+		return
+	}
+	if info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	Content(w, r, info.Name(), info.ModTime(), f, opts...)
+}
+
+// FileIn replies to the request with the contents of the file named name within the directory
+// root, as File does. Names escaping root, such as "../../etc/passwd" decoded from a request
+// path, and absolute names get 404 Not Found, so name may come from the request. Symbolic links
+// within root are followed and may point outside of it.
+func FileIn(w http.ResponseWriter, r *http.Request, root, name string, opts ...Option) {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		http.NotFound(w, r)
+		return
+	}
+	File(w, r, filepath.Join(root, name), opts...)
+}
+
+func fileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func generateETag(modtime time.Time, content io.ReadSeeker) (string, error) {
+	if !modtime.IsZero() && !modtime.Equal(time.Unix(0, 0)) {
+		size, err := content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return "", err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		return `"` + strconv.FormatInt(modtime.UnixNano(), 36) + "-" + strconv.FormatInt(size, 36) + `"`, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
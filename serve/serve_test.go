@@ -0,0 +1,204 @@
+package serve_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo/serve"
+)
+
+func TestContent(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	const body = "0123456789"
+
+	// The ETag generated for body and modtime, read from a plain request.
+	rec := httptest.NewRecorder()
+	serve.Content(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody), "digits.txt", modtime, strings.NewReader(body))
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/`) {
+		t.Fatalf("got ETag %q, want a strong tag", etag)
+	}
+
+	testCases := []struct {
+		name    string
+		modtime time.Time
+		opts    []serve.Option
+		headers map[string]string
+		status  int
+		body    string
+		want    map[string]string
+	}{
+		{
+			name:    "full",
+			modtime: modtime,
+			status:  http.StatusOK,
+			body:    body,
+			want: map[string]string{
+				"Content-Type":  "text/plain; charset=utf-8",
+				"Last-Modified": modtime.Format(http.TimeFormat),
+				"Accept-Ranges": "bytes",
+			},
+		},
+		{
+			name:    "range",
+			modtime: modtime,
+			headers: map[string]string{"Range": "bytes=2-4"},
+			status:  http.StatusPartialContent,
+			body:    "234",
+			want:    map[string]string{"Content-Range": "bytes 2-4/10"},
+		},
+		{
+			name:    "if_range_match",
+			modtime: modtime,
+			headers: map[string]string{"Range": "bytes=5-", "If-Range": etag},
+			status:  http.StatusPartialContent,
+			body:    "56789",
+		},
+		{
+			name:    "if_range_changed",
+			modtime: modtime,
+			headers: map[string]string{"Range": "bytes=5-", "If-Range": `"stale"`},
+			status:  http.StatusOK,
+			body:    body,
+		},
+		{
+			name:    "if_none_match",
+			modtime: modtime,
+			headers: map[string]string{"If-None-Match": etag},
+			status:  http.StatusNotModified,
+		},
+		{
+			name:    "custom_etag",
+			modtime: modtime,
+			opts:    []serve.Option{serve.WithETag(`"v1"`)},
+			headers: map[string]string{"If-None-Match": `"v1"`},
+			status:  http.StatusNotModified,
+		},
+		{
+			name:   "hashed_etag",
+			opts:   []serve.Option{serve.WithContentType("application/octet-stream")},
+			status: http.StatusOK,
+			body:   body,
+			want:   map[string]string{"Content-Type": "application/octet-stream", "Last-Modified": ""},
+		},
+		{
+			name:    "attachment",
+			modtime: modtime,
+			opts:    []serve.Option{serve.WithAttachment("")},
+			status:  http.StatusOK,
+			body:    body,
+			want:    map[string]string{"Content-Disposition": `attachment; filename=digits.txt`},
+		},
+		{
+			name:    "inline_non_ascii",
+			modtime: modtime,
+			opts:    []serve.Option{serve.WithInline("گزارش.txt")},
+			status:  http.StatusOK,
+			body:    body,
+			want:    map[string]string{"Content-Disposition": `inline; filename*=utf-8''%DA%AF%D8%B2%D8%A7%D8%B1%D8%B4.txt`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			serve.Content(rec, req, "digits.txt", tc.modtime, strings.NewReader(body), tc.opts...)
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+			for k, v := range tc.want {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("got %s %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"file", path, http.StatusOK, "a,b\n1,2\n"},
+		{"missing", filepath.Join(dir, "missing.csv"), http.StatusNotFound, ""},
+		{"directory", dir, http.StatusNotFound, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			serve.File(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody), tc.path, serve.WithAttachment(""))
+			if rec.Code != tc.status {
+				t.Fatalf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.body == "" {
+				return
+			}
+			got, _ := io.ReadAll(rec.Body)
+			if string(got) != tc.body {
+				t.Errorf("got body %q, want %q", got, tc.body)
+			}
+			if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=report.csv" {
+				t.Errorf("got Content-Disposition %q", cd)
+			}
+		})
+	}
+}
+
+func TestFileIn(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "report.csv"), []byte("a,b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(root), "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dl/{name...}", func(w http.ResponseWriter, r *http.Request) {
+		serve.FileIn(w, r, root, r.PathValue("name"))
+	})
+
+	testCases := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"file", "/dl/report.csv", http.StatusOK},
+		{"missing", "/dl/missing.csv", http.StatusNotFound},
+		{"encoded_traversal", "/dl/..%2Fsecret.txt", http.StatusNotFound},
+		{"deep_encoded_traversal", "/dl/..%2F..%2F..%2F..%2Fetc%2Fpasswd", http.StatusNotFound},
+		{"absolute", "/dl/%2Fetc%2Fpasswd", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Package tenant serves multi-tenant applications, resolving the tenant of every request from
+// its subdomain, a header or its path prefix and storing it in the request context:
+//
+//	handler := tenant.Middleware(tenant.FromSubdomain("example.com"))(mux)
+//
+//	func listUsers(w http.ResponseWriter, r *http.Request) {
+//		users, err := store.Users(r.Context(), tenant.MustID(r.Context()))
+//		...
+//	}
+//
+// Tenants needing different endpoints, e.g. depending on their plan, get their own route table
+// with a Router:
+//
+//	router, err := tenant.NewRouter(tenant.FromHeader("X-Tenant"), map[string]rahjoo.Route{
+//		"acme":   rahjoo.MergeRoutes(api, reports),
+//		"globex": api,
+//	})
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+)
+
+var (
+	// ErrNoTenant is returned by resolvers for requests that do not name a tenant.
+	ErrNoTenant = errors.New("tenant: no tenant")
+	// ErrUnknownTenant is returned by resolvers for requests naming a tenant that does not exist.
+	ErrUnknownTenant = errors.New("tenant: unknown tenant")
+)
+
+// Resolver resolves the tenant of requests. It returns an error wrapping ErrNoTenant if the
+// request does not name one, and may check the tenant exists, returning an error wrapping
+// ErrUnknownTenant if not.
+type Resolver interface {
+	Resolve(r *http.Request) (string, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(r *http.Request) (string, error)
+
+// Resolve implements Resolver.
+func (f ResolverFunc) Resolve(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// FromSubdomain resolves the tenant from the subdomain of domain the request is sent to, e.g.
+// "acme" for "acme.example.com" with the domain "example.com". Requests to domain itself or to
+// nested subdomains name no tenant.
+func FromSubdomain(domain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return ResolverFunc(func(r *http.Request) (string, error) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		id, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || id == "" || strings.Contains(id, ".") {
+			return "", ErrNoTenant
+		}
+		return id, nil
+	})
+}
+
+// FromHeader resolves the tenant from the request header name, e.g. "X-Tenant-ID".
+func FromHeader(name string) Resolver {
+	return ResolverFunc(func(r *http.Request) (string, error) {
+		if id := r.Header.Get(name); id != "" {
+			return id, nil
+		}
+		return "", ErrNoTenant
+	})
+}
+
+// FromPathPrefix resolves the tenant from the first segment of the request path, e.g. "acme"
+// for "/acme/users". With WithStripPrefix, Middleware removes the segment from the path before
+// calling the next handler, so routes are declared without it, e.g. "/users".
+func FromPathPrefix() Resolver {
+	return ResolverFunc(func(r *http.Request) (string, error) {
+		id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if id == "" {
+			return "", ErrNoTenant
+		}
+		return id, nil
+	})
+}
+
+// stripTenant returns a shallow copy of r without the first segment of its path, the tenant, as
+// http.StripPrefix does.
+func stripTenant(r *http.Request) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = stripSegment(r.URL.Path)
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = stripSegment(r.URL.RawPath)
+	}
+	return r2
+}
+
+// stripSegment removes the first segment of path.
+func stripSegment(path string) string {
+	_, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return "/" + rest
+}
+
+type ctxKey struct{}
+
+// WithID returns a copy of ctx carrying the tenant id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// ID returns the tenant stored in ctx, if any.
+func ID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
+
+// MustID returns the tenant stored in ctx. It panics if there is none, which is a programming
+// error behind Middleware.
+func MustID(ctx context.Context) string {
+	id, ok := ID(ctx)
+	if !ok {
+		panic("tenant: no tenant in context")
+	}
+	return id
+}
+
+// KeyByTenant returns a KeyFunc grouping requests by tenant, e.g. for per-tenant rate limits or
+// quotas. Requests without a tenant are grouped by fallback (e.g. middleware.KeyByIP) instead.
+func KeyByTenant(fallback middleware.KeyFunc) middleware.KeyFunc {
+	return func(r *http.Request) string {
+		if id, ok := ID(r.Context()); ok {
+			return "tenant:" + id
+		}
+		return "anonymous:" + fallback(r)
+	}
+}
+
+type config struct {
+	optional bool
+	strip    bool
+}
+
+// Option configures the Middleware.
+type Option func(*config)
+
+// WithOptional lets requests naming no tenant through, without a tenant in their context.
+func WithOptional() Option {
+	return func(c *config) {
+		c.optional = true
+	}
+}
+
+// WithStripPrefix removes the first segment of the request path, the tenant resolved by
+// FromPathPrefix or a resolver built on it, before calling the next handler.
+func WithStripPrefix() Option {
+	return func(c *config) {
+		c.strip = true
+	}
+}
+
+// Middleware is a middleware resolving the tenant of requests with resolver and storing it in
+// their context, readable with ID. With WithStripPrefix, it should wrap the whole mux, for
+// routing to see the path without the tenant. Requests naming no tenant are answered with
+// 400 Bad Request, unknown tenants with 404 Not Found and requests the resolver fails on with
+// 500 Internal Server Error.
+func Middleware(resolver Resolver, opts ...Option) middleware.Middleware {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := resolver.Resolve(r)
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrNoTenant) && c.optional:
+				next.ServeHTTP(w, r)
+				return
+			case errors.Is(err, ErrNoTenant):
+				http.Error(w, "missing tenant", http.StatusBadRequest)
+				return
+			case errors.Is(err, ErrUnknownTenant):
+				http.Error(w, "unknown tenant", http.StatusNotFound)
+				return
+			default:
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if c.strip {
+				r = stripTenant(r)
+			}
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
+
+// Router is an http.Handler dispatching requests to the route table of their tenant.
+type Router struct {
+	handler  http.Handler
+	muxes    map[string]*http.ServeMux
+	fallback *http.ServeMux
+}
+
+type routerConfig struct {
+	fallback    rahjoo.Route
+	bindOptions []rahjoo.BindOption
+	mwOptions   []Option
+}
+
+// RouterOption configures a Router.
+type RouterOption func(*routerConfig)
+
+// WithDefaultRoutes serves routes to tenants without a table of their own and to requests
+// naming no tenant. Without it, such requests are answered as by Middleware.
+func WithDefaultRoutes(routes rahjoo.Route) RouterOption {
+	return func(c *routerConfig) {
+		c.fallback = routes
+	}
+}
+
+// WithBindOptions binds every route table with opts, e.g. rahjoo.WithGlobalMiddleware.
+func WithBindOptions(opts ...rahjoo.BindOption) RouterOption {
+	return func(c *routerConfig) {
+		c.bindOptions = opts
+	}
+}
+
+// WithMiddlewareOptions configures the Middleware resolving tenants with opts, e.g.
+// WithStripPrefix along with FromPathPrefix.
+func WithMiddlewareOptions(opts ...Option) RouterOption {
+	return func(c *routerConfig) {
+		c.mwOptions = opts
+	}
+}
+
+// NewRouter returns a Router resolving tenants with resolver, as Middleware does, and serving
+// each one the route table tables holds for it. It returns an error if a table can not be bound.
+func NewRouter(resolver Resolver, tables map[string]rahjoo.Route, opts ...RouterOption) (*Router, error) {
+	var c routerConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	rt := &Router{muxes: make(map[string]*http.ServeMux, len(tables))}
+	for id, routes := range tables {
+		mux := http.NewServeMux()
+		if err := rahjoo.BindRoutesToMuxWithOptions(mux, []rahjoo.Route{routes}, c.bindOptions...); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", id, err)
+		}
+		rt.muxes[id] = mux
+	}
+	mwOpts := slices.Clone(c.mwOptions)
+	if c.fallback != nil {
+		rt.fallback = http.NewServeMux()
+		if err := rahjoo.BindRoutesToMuxWithOptions(rt.fallback, []rahjoo.Route{c.fallback}, c.bindOptions...); err != nil {
+			return nil, fmt.Errorf("tenant default routes: %w", err)
+		}
+		mwOpts = append(mwOpts, WithOptional())
+	}
+	rt.handler = Middleware(resolver, mwOpts...)(http.HandlerFunc(rt.dispatch))
+	return rt, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.handler.ServeHTTP(w, r)
+}
+
+func (rt *Router) dispatch(w http.ResponseWriter, r *http.Request) {
+	mux := rt.fallback
+	if id, ok := ID(r.Context()); ok {
+		if m, ok := rt.muxes[id]; ok {
+			mux = m
+		}
+	}
+	if mux == nil {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
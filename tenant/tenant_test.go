@@ -0,0 +1,141 @@
+package tenant_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/middleware"
+	"github.com/amirzayi/rahjoo/tenant"
+)
+
+// echo writes the tenant and the path the request was routed with.
+func echo(w http.ResponseWriter, r *http.Request) {
+	id, _ := tenant.ID(r.Context())
+	io.WriteString(w, id+" "+r.URL.Path)
+}
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resolver tenant.Resolver
+		opts     []tenant.Option
+		host     string
+		header   string
+		path     string
+		status   int
+		body     string
+	}{
+		{"subdomain", tenant.FromSubdomain("example.com"), nil, "acme.example.com:8080", "", "/users", http.StatusOK, "acme /users"},
+		{"subdomain_case", tenant.FromSubdomain("Example.com."), nil, "ACME.example.com", "", "/users", http.StatusOK, "acme /users"},
+		{"apex_domain", tenant.FromSubdomain("example.com"), nil, "example.com", "", "/users", http.StatusBadRequest, ""},
+		{"nested_subdomain", tenant.FromSubdomain("example.com"), nil, "a.acme.example.com", "", "/users", http.StatusBadRequest, ""},
+		{"other_domain", tenant.FromSubdomain("example.com"), nil, "acme.example.org", "", "/users", http.StatusBadRequest, ""},
+		{"header", tenant.FromHeader("X-Tenant"), nil, "", "globex", "/users", http.StatusOK, "globex /users"},
+		{"missing_header", tenant.FromHeader("X-Tenant"), nil, "", "", "/users", http.StatusBadRequest, ""},
+		{"optional", tenant.FromHeader("X-Tenant"), []tenant.Option{tenant.WithOptional()}, "", "", "/users", http.StatusOK, " /users"},
+		{"path_prefix", tenant.FromPathPrefix(), []tenant.Option{tenant.WithStripPrefix()}, "", "", "/acme/users/42", http.StatusOK, "acme /users/42"},
+		{"path_prefix_root", tenant.FromPathPrefix(), []tenant.Option{tenant.WithStripPrefix()}, "", "", "/acme", http.StatusOK, "acme /"},
+		{"no_path_prefix", tenant.FromPathPrefix(), nil, "", "", "/", http.StatusBadRequest, ""},
+		{"path_prefix_kept", tenant.FromPathPrefix(), nil, "", "", "/acme/users", http.StatusOK, "acme /acme/users"},
+		{"path_prefix_checked", tenant.ResolverFunc(func(r *http.Request) (string, error) {
+			id, err := tenant.FromPathPrefix().Resolve(r)
+			if err == nil && id != "acme" {
+				return "", tenant.ErrUnknownTenant
+			}
+			return id, err
+		}), []tenant.Option{tenant.WithStripPrefix()}, "", "", "/acme/users", http.StatusOK, "acme /users"},
+		{"unknown", tenant.ResolverFunc(func(*http.Request) (string, error) { return "", tenant.ErrUnknownTenant }), nil, "", "", "/", http.StatusNotFound, ""},
+		{"failing", tenant.ResolverFunc(func(*http.Request) (string, error) { return "", errors.New("db down") }), nil, "", "", "/", http.StatusInternalServerError, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, http.NoBody)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			req.Header.Set("X-Tenant", tc.header)
+			rec := httptest.NewRecorder()
+			tenant.Middleware(tc.resolver, tc.opts...)(http.HandlerFunc(echo)).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == http.StatusOK && rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	api := rahjoo.Route{"/users": {http.MethodGet: rahjoo.NewHandler(echo)}}
+	reports := rahjoo.Route{"/reports": {http.MethodGet: rahjoo.NewHandler(echo)}}
+	public := rahjoo.Route{"/status": {http.MethodGet: rahjoo.NewHandler(echo)}}
+
+	testCases := []struct {
+		name   string
+		opts   []tenant.RouterOption
+		path   string
+		status int
+		body   string
+	}{
+		{"acme_users", nil, "/acme/users", http.StatusOK, "acme /users"},
+		{"acme_reports", nil, "/acme/reports", http.StatusOK, "acme /reports"},
+		{"globex_reports", nil, "/globex/reports", http.StatusNotFound, ""},
+		{"unknown_tenant", nil, "/initech/users", http.StatusNotFound, ""},
+		{"no_tenant", nil, "/", http.StatusBadRequest, ""},
+		{"default_routes", []tenant.RouterOption{tenant.WithDefaultRoutes(public)}, "/initech/status", http.StatusOK, "initech /status"},
+		{"default_routes_no_tenant", []tenant.RouterOption{tenant.WithDefaultRoutes(public)}, "/", http.StatusNotFound, ""},
+		{"bind_options", []tenant.RouterOption{tenant.WithBindOptions(rahjoo.WithPrefix("/v1"))}, "/acme/v1/users", http.StatusOK, "acme /v1/users"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]tenant.RouterOption{tenant.WithMiddlewareOptions(tenant.WithStripPrefix())}, tc.opts...)
+			router, err := tenant.NewRouter(tenant.FromPathPrefix(), map[string]rahjoo.Route{
+				"acme":   rahjoo.MergeRoutes(api, reports),
+				"globex": api,
+			}, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == http.StatusOK && rec.Body.String() != tc.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestNewRouterInvalidRoutes(t *testing.T) {
+	_, err := tenant.NewRouter(tenant.FromHeader("X-Tenant"), map[string]rahjoo.Route{
+		"acme": {"/users/{id": {http.MethodGet: rahjoo.NewHandler(echo)}},
+	})
+	if err == nil {
+		t.Error("got no error for an invalid route")
+	}
+}
+
+func TestKeyByTenant(t *testing.T) {
+	key := tenant.KeyByTenant(middleware.KeyByIP)
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if got, want := key(req), "anonymous:192.0.2.1"; got != want {
+		t.Errorf("got key %q, want %q", got, want)
+	}
+	req = req.WithContext(tenant.WithID(req.Context(), "acme"))
+	if got, want := key(req), "tenant:acme"; got != want {
+		t.Errorf("got key %q, want %q", got, want)
+	}
+	if got := tenant.MustID(req.Context()); got != "acme" {
+		t.Errorf("got tenant %q, want %q", got, "acme")
+	}
+}
@@ -0,0 +1,213 @@
+package rahjoo
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// TrieRouter is an http.Handler matching requests against a route table with a segment trie
+// instead of an http.ServeMux. Matching walks the path once, segment by segment, so its cost
+// does not grow with the number of routes, and its precedence rules stay simple to reason about
+// with thousands of routes. BenchmarkRouterMatch compares both matchers on large route tables.
+//
+// Patterns are those of BindRoutesToMux, but matching does not follow every ServeMux rule:
+//   - static segments take precedence over wildcards, which take precedence over "{name...}"
+//     and trailing slash wildcards, at every segment;
+//   - paths are neither cleaned nor redirected, so "/users" does not match "/users/" patterns;
+//   - host patterns are not supported.
+//
+// Wildcard values are set on the request, so handlers read them with r.PathValue as usual, and
+// RoutePattern reports the matched route. Requests matching a path but none of its methods get
+// 405 Method Not Allowed with an Allow header, other unmatched requests get 404 Not Found.
+type TrieRouter struct {
+	root *trieNode
+}
+
+// trieNode is a path segment of the trie.
+type trieNode struct {
+	static map[string]*trieNode
+	// param matches any non-empty segment.
+	param *trieNode
+	// rest matches the remaining segments, including none after a trailing slash.
+	rest *trieNode
+	// methods holds the routes ending at the node.
+	methods map[Method]trieRoute
+}
+
+// trieRoute is a route registered in the trie.
+type trieRoute struct {
+	handler http.Handler
+	// names holds the wildcard names of the route, in the order of the matched segments.
+	names []string
+}
+
+// NewTrieRouter builds a TrieRouter from routes, applying opts as BindRoutesToMuxWithOptions
+// does. Like BindRoutesToMux, it returns the problems of the route table joined as
+// *RouteError values, including routes registered twice.
+func NewTrieRouter(routes []Route, opts ...BindOption) (*TrieRouter, error) {
+	mergedRoutes, err := prepareRoutes(routes, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TrieRouter{root: &trieNode{}}
+	var errs []error
+	for _, route := range compileRoutes(mergedRoutes) {
+		if err := t.insert(route); err != nil {
+			errs = append(errs, &RouteError{Path: route.path, Method: route.method, Err: err})
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *TrieRouter) insert(route compiledRoute) error {
+	node := t.root
+	var names []string
+	segments := strings.Split(string(route.path)[1:], "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch {
+		case last && seg == "":
+			// A trailing slash matches the whole subtree.
+			node = node.child(&node.rest)
+			names = append(names, "")
+		case seg == "{$}":
+			node = node.staticChild("")
+		case strings.HasSuffix(seg, "...}"):
+			node = node.child(&node.rest)
+			names = append(names, seg[1:len(seg)-len("...}")])
+		case strings.HasPrefix(seg, "{"):
+			node = node.child(&node.param)
+			names = append(names, seg[1:len(seg)-1])
+		default:
+			unescaped, err := url.PathUnescape(seg)
+			if err != nil {
+				return err
+			}
+			node = node.staticChild(unescaped)
+		}
+	}
+
+	if _, ok := node.methods[route.method]; ok {
+		return errors.New("pattern conflicts with a route registered with the same path")
+	}
+	if node.methods == nil {
+		node.methods = map[Method]trieRoute{}
+	}
+	node.methods[route.method] = trieRoute{handler: route.handler, names: names}
+	return nil
+}
+
+func (n *trieNode) staticChild(seg string) *trieNode {
+	if n.static == nil {
+		n.static = map[string]*trieNode{}
+	}
+	child, ok := n.static[seg]
+	if !ok {
+		child = &trieNode{}
+		n.static[seg] = child
+	}
+	return child
+}
+
+func (n *trieNode) child(c **trieNode) *trieNode {
+	if *c == nil {
+		*c = &trieNode{}
+	}
+	return *c
+}
+
+// ServeHTTP dispatches the request to the handler of the matching route.
+func (t *TrieRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf [8]string
+	m := trieMatch{method: Method(r.Method)}
+	if !m.find(t.root, strings.TrimPrefix(r.URL.EscapedPath(), "/"), false, buf[:0]) {
+		if len(m.allowed) > 0 {
+			slices.Sort(m.allowed)
+			w.Header().Set("Allow", strings.Join(slices.Compact(m.allowed), ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	for i, name := range m.route.names {
+		if name != "" {
+			r.SetPathValue(name, m.values[i])
+		}
+	}
+	m.route.handler.ServeHTTP(w, r)
+}
+
+// trieMatch is the state of a lookup.
+type trieMatch struct {
+	method Method
+	route  trieRoute
+	values []string
+	// allowed collects the methods of the routes matching the path but not the method.
+	allowed []string
+}
+
+// find looks for a route matching the escaped path under n, in order of precedence, reporting
+// whether one was found. done reports that the path has no segment left, and values holds the
+// wildcard values matched so far.
+func (m *trieMatch) find(n *trieNode, path string, done bool, values []string) bool {
+	if done {
+		return m.matchMethod(n, values)
+	}
+	seg, rest, more := strings.Cut(path, "/")
+	seg = unescape(seg)
+	if child, ok := n.static[seg]; ok && m.find(child, rest, !more, values) {
+		return true
+	}
+	if n.param != nil && seg != "" && m.find(n.param, rest, !more, append(values, seg)) {
+		return true
+	}
+	if n.rest != nil {
+		return m.matchMethod(n.rest, append(values, unescape(path)))
+	}
+	return false
+}
+
+// unescape returns s with its percent-encoding decoded, or s if it is malformed.
+func unescape(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// matchMethod selects the route of n for the request method, falling back to GET for HEAD
+// requests and to the route handling all methods, like http.ServeMux.
+func (m *trieMatch) matchMethod(n *trieNode, values []string) bool {
+	if len(n.methods) == 0 {
+		return false
+	}
+	route, ok := n.methods[m.method]
+	if !ok && m.method == http.MethodHead {
+		route, ok = n.methods[http.MethodGet]
+	}
+	if !ok {
+		route, ok = n.methods[""]
+	}
+	if ok {
+		m.route, m.values = route, values
+		return true
+	}
+	for method := range n.methods {
+		m.allowed = append(m.allowed, string(method))
+		if method == http.MethodGet {
+			m.allowed = append(m.allowed, http.MethodHead)
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestTrieRouter(t *testing.T) {
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			path, method := rahjoo.RoutePattern(r)
+			fmt.Fprintf(w, "%s %s %s id=%s rest=%s", body, method, path, r.PathValue("id"), r.PathValue("rest"))
+		}
+	}
+
+	routes := rahjoo.Route{
+		"/users/me": {
+			http.MethodGet: rahjoo.NewHandler(reply("me")),
+		},
+		"/users/{id}": {
+			http.MethodGet:    rahjoo.NewHandler(reply("user")),
+			http.MethodDelete: rahjoo.NewHandler(reply("delete")),
+		},
+		"/users/{id}/posts": {
+			"": rahjoo.NewHandler(reply("posts")),
+		},
+		"/files/{rest...}": {
+			http.MethodGet: rahjoo.NewHandler(reply("file")),
+		},
+		"/static/": {
+			http.MethodGet: rahjoo.NewHandler(reply("static")),
+		},
+		"/{$}": {
+			http.MethodGet: rahjoo.NewHandler(reply("index")),
+		},
+	}
+
+	router, err := rahjoo.NewTrieRouter([]rahjoo.Route{routes}, rahjoo.WithPrefix("/api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		allow  string
+	}{
+		{"static_precedence", http.MethodGet, "/api/users/me", http.StatusOK, "me GET /api/users/me id= rest=", ""},
+		{"param", http.MethodGet, "/api/users/42", http.StatusOK, "user GET /api/users/{id} id=42 rest=", ""},
+		{"escaped_param", http.MethodGet, "/api/users/a%2Fb", http.StatusOK, "user GET /api/users/{id} id=a/b rest=", ""},
+		{"head_uses_get", http.MethodHead, "/api/users/42", http.StatusOK, "user GET /api/users/{id} id=42 rest=", ""},
+		{"other_method", http.MethodDelete, "/api/users/42", http.StatusOK, "delete DELETE /api/users/{id} id=42 rest=", ""},
+		{"any_method", http.MethodPatch, "/api/users/42/posts", http.StatusOK, "posts  /api/users/{id}/posts id=42 rest=", ""},
+		{"rest", http.MethodGet, "/api/files/a/b.txt", http.StatusOK, "file GET /api/files/{rest...} id= rest=a/b.txt", ""},
+		{"empty_rest", http.MethodGet, "/api/files/", http.StatusOK, "file GET /api/files/{rest...} id= rest=", ""},
+		{"trailing_slash", http.MethodGet, "/api/static/css/app.css", http.StatusOK, "static GET /api/static/ id= rest=", ""},
+		{"exact_slash", http.MethodGet, "/api/", http.StatusOK, "index GET /api/{$} id= rest=", ""},
+		{"exact_slash_only", http.MethodGet, "/api/other", http.StatusNotFound, "404 page not found\n", ""},
+		{"empty_param", http.MethodGet, "/api/users/", http.StatusNotFound, "404 page not found\n", ""},
+		{"no_redirect", http.MethodGet, "/api/static", http.StatusNotFound, "404 page not found\n", ""},
+		{"method_not_allowed", http.MethodPost, "/api/users/42", http.StatusMethodNotAllowed, "Method Not Allowed\n", "DELETE, GET, HEAD"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, http.NoBody))
+			if rec.Code != tc.status {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Body.String(); got != tc.body {
+				t.Errorf("got body %q, want %q", got, tc.body)
+			}
+			if got := rec.Header().Get("Allow"); got != tc.allow {
+				t.Errorf("got Allow %q, want %q", got, tc.allow)
+			}
+		})
+	}
+}
+
+func TestTrieRouterErrors(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	_, err := rahjoo.NewTrieRouter([]rahjoo.Route{{
+		"/users/{id}":   {http.MethodGet: rahjoo.NewHandler(h)},
+		"/users/{name}": {http.MethodGet: rahjoo.NewHandler(h)},
+	}})
+	var routeErr *rahjoo.RouteError
+	if !errors.As(err, &routeErr) || routeErr.Path != "/users/{name}" {
+		t.Errorf("got error %v, want a conflict on /users/{name}", err)
+	}
+
+	_, err = rahjoo.NewTrieRouter([]rahjoo.Route{{"users": {http.MethodGet: rahjoo.NewHandler(h)}}})
+	if !errors.As(err, &routeErr) {
+		t.Errorf("got error %v, want a validation error", err)
+	}
+}
+
+// benchmarkRoutes returns a route table of n resources with four routes each.
+func benchmarkRoutes(n int) []rahjoo.Route {
+	h := rahjoo.NewHandler(func(http.ResponseWriter, *http.Request) {})
+	routes := make([]rahjoo.Route, n)
+	for i := range routes {
+		base := rahjoo.Path(fmt.Sprintf("/api/v1/resource%d", i))
+		routes[i] = rahjoo.Route{
+			base:              {http.MethodGet: h, http.MethodPost: h},
+			base + "/{id}":    {http.MethodGet: h},
+			base + "/{id}/xs": {http.MethodGet: h},
+		}
+	}
+	return routes
+}
+
+func BenchmarkRouterMatch(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		routes := benchmarkRoutes(n)
+		mux := http.NewServeMux()
+		if err := rahjoo.BindRoutesToMuxWithOptions(mux, routes); err != nil {
+			b.Fatal(err)
+		}
+		trie, err := rahjoo.NewTrieRouter(routes)
+		if err != nil {
+			b.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/resource%d/42/xs", n-1), http.NoBody)
+
+		for _, router := range []struct {
+			name    string
+			handler http.Handler
+		}{{"ServeMux", mux}, {"TrieRouter", trie}} {
+			b.Run(fmt.Sprintf("%s/routes=%d", router.name, 4*n), func(b *testing.B) {
+				w := httptest.NewRecorder()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					router.handler.ServeHTTP(w, req)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package rahjoo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// RouteError describes a route that can not be registered, naming the offending Path and Method.
+type RouteError struct {
+	Path   Path
+	Method Method
+	Err    error
+}
+
+func (e *RouteError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("rahjoo: route %q: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("rahjoo: route %s %q: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that every path and method of the route table is well-formed before it is
+// handed to http.ServeMux, which would otherwise panic with a less helpful message.
+// A path must begin with a slash, must not contain whitespace and its wildcards must be whole,
+// uniquely named segments (e.g. "/shelves/{shelf_id}/books/{path...}"). A method must be empty
+// or a valid HTTP token. All problems are reported, joined, as *RouteError values.
+func (r Route) Validate() error {
+	var errs []error
+	for _, path := range sortedPaths(r) {
+		if err := validatePath(path); err != nil {
+			errs = append(errs, &RouteError{Path: path, Err: err})
+		}
+		for _, method := range sortedMethods(r[path]) {
+			if err := validateMethod(method); err != nil {
+				errs = append(errs, &RouteError{Path: path, Method: method, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validatePath(path Path) error {
+	s := string(path)
+	if s == "" {
+		return errors.New("path is empty")
+	}
+	if s[0] != '/' {
+		return errors.New("path must begin with a slash")
+	}
+	if strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		return errors.New("path must not contain whitespace")
+	}
+
+	names := map[string]bool{}
+	segments := strings.Split(s[1:], "/")
+	for i, seg := range segments {
+		if !strings.ContainsAny(seg, "{}") {
+			continue
+		}
+		if seg[0] != '{' || seg[len(seg)-1] != '}' || strings.Count(seg, "{") != 1 || strings.Count(seg, "}") != 1 {
+			return fmt.Errorf("wildcard in segment %q must be the whole segment", seg)
+		}
+		name := seg[1 : len(seg)-1]
+		last := i == len(segments)-1
+		if name == "$" {
+			if !last {
+				return errors.New("{$} must be at the end of the path")
+			}
+			continue
+		}
+		if rest, ok := strings.CutSuffix(name, "..."); ok {
+			if !last {
+				return fmt.Errorf("wildcard %q must be the last segment", seg)
+			}
+			name = rest
+		}
+		if !isIdentifier(name) {
+			return fmt.Errorf("bad wildcard name %q", name)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate wildcard name %q", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+func validateMethod(method Method) error {
+	for _, c := range method {
+		if !isTokenRune(c) {
+			return fmt.Errorf("invalid character %q in method", c)
+		}
+	}
+	return nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTokenRune reports whether c is allowed in an HTTP token (RFC 9110, section 5.6.2).
+func isTokenRune(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("!#$%&'*+-.^_`|~", c))
+}
+
+func sortedPaths(r Route) []Path {
+	paths := make([]Path, 0, len(r))
+	for path := range r {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func sortedMethods(methods map[Method]actionHandler) []Method {
+	ms := make([]Method, 0, len(methods))
+	for method := range methods {
+		ms = append(ms, method)
+	}
+	slices.Sort(ms)
+	return ms
+}
@@ -0,0 +1,103 @@
+package rahjoo_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amirzayi/rahjoo"
+)
+
+func TestRouteValidate(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	testCases := []struct {
+		name   string
+		path   rahjoo.Path
+		method rahjoo.Method
+		err    string
+	}{
+		{"valid", "/shelves/{shelf_id}/books/{path...}", http.MethodGet, ""},
+		{"valid_exact", "/books/{$}", "", ""},
+		{"empty", "", http.MethodGet, "path is empty"},
+		{"missing_slash", "books", http.MethodGet, "must begin with a slash"},
+		{"space", "/my books", http.MethodGet, "whitespace"},
+		{"duplicate_braces", "/books/{{id}}", http.MethodGet, "whole segment"},
+		{"partial_segment", "/books/id-{id}", http.MethodGet, "whole segment"},
+		{"unclosed_brace", "/books/{id", http.MethodGet, "whole segment"},
+		{"duplicate_name", "/{id}/books/{id}", http.MethodGet, "duplicate wildcard"},
+		{"bad_name", "/books/{1d}", http.MethodGet, "bad wildcard name"},
+		{"rest_not_last", "/books/{rest...}/x", http.MethodGet, "last segment"},
+		{"bad_method", "/books", "GET POST", "invalid character"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := rahjoo.Route{tc.path: {tc.method: rahjoo.NewHandler(h)}}.Validate()
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got error %v, want error containing %q", err, tc.err)
+			}
+			var routeErr *rahjoo.RouteError
+			if !errors.As(err, &routeErr) || routeErr.Path != tc.path {
+				t.Errorf("got error %v, want *RouteError for path %q", err, tc.path)
+			}
+		})
+	}
+}
+
+func TestBindRoutesToMuxErrors(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	testCases := []struct {
+		name   string
+		routes rahjoo.Route
+		paths  []rahjoo.Path
+	}{
+		{"malformed", rahjoo.Route{
+			"users":  {http.MethodGet: rahjoo.NewHandler(h)},
+			"/books": {http.MethodGet: rahjoo.NewHandler(h)},
+		}, []rahjoo.Path{"users"}},
+		{"conflict", rahjoo.Route{
+			"/users/{id}":   {http.MethodGet: rahjoo.NewHandler(h)},
+			"/users/{name}": {http.MethodGet: rahjoo.NewHandler(h)},
+			"/books":        {http.MethodGet: rahjoo.NewHandler(h)},
+		}, []rahjoo.Path{"/users/{name}"}},
+		{"existing_pattern", rahjoo.Route{
+			"/images/": {"": rahjoo.NewHandler(h)},
+		}, []rahjoo.Path{"/images/"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/images/", http.NotFoundHandler())
+
+			err := rahjoo.BindRoutesToMux(mux, tc.routes)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, path := range tc.paths {
+				if !strings.Contains(err.Error(), string(path)) {
+					t.Errorf("error %q does not name path %q", err, path)
+				}
+			}
+			var routeErr *rahjoo.RouteError
+			if !errors.As(err, &routeErr) {
+				t.Errorf("got error %v, want *RouteError", err)
+			}
+
+			// nothing must be registered when the routes are invalid by themselves.
+			if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/books", http.NoBody)); pattern != "" {
+				t.Errorf("got registered pattern %q, want none", pattern)
+			}
+		})
+	}
+}
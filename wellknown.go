@@ -0,0 +1,131 @@
+package rahjoo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amirzayi/rahjoo/serve"
+)
+
+// SecurityTxt is the content of a security.txt file (RFC 9116), telling security researchers
+// how to report vulnerabilities.
+type SecurityTxt struct {
+	// Contact lists the URIs to report vulnerabilities to, e.g. "mailto:security@example.com".
+	// At least one is required.
+	Contact []string
+	// Expires is when the file should be considered stale. It is required and should be less
+	// than a year away.
+	Expires time.Time
+	// Encryption lists the URIs of the keys to encrypt reports with.
+	Encryption []string
+	// Acknowledgments lists the URIs of pages recognizing reporters.
+	Acknowledgments []string
+	// PreferredLanguages lists the languages reports are preferred in, e.g. "en, fa".
+	PreferredLanguages string
+	// Canonical lists the URIs the file is served at.
+	Canonical []string
+	// Policy lists the URIs of the vulnerability disclosure policy.
+	Policy []string
+	// Hiring lists the URIs of security related job offers.
+	Hiring []string
+}
+
+// String returns the security.txt file.
+func (s SecurityTxt) String() string {
+	var b strings.Builder
+	field := func(name string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				fmt.Fprintf(&b, "%s: %s\n", name, v)
+			}
+		}
+	}
+	field("Contact", s.Contact...)
+	field("Expires", s.Expires.UTC().Format(time.RFC3339))
+	field("Encryption", s.Encryption...)
+	field("Acknowledgments", s.Acknowledgments...)
+	field("Preferred-Languages", s.PreferredLanguages)
+	field("Canonical", s.Canonical...)
+	field("Policy", s.Policy...)
+	field("Hiring", s.Hiring...)
+	return b.String()
+}
+
+// WellKnownOption adds a file to the Route returned by WellKnown.
+type WellKnownOption func(Route)
+
+// WithRobotsTxt serves content at /robots.txt, e.g. "User-agent: *\nDisallow: /\n" to keep
+// crawlers away from an API.
+func WithRobotsTxt(content string) WellKnownOption {
+	return func(r Route) {
+		r.Add("/robots.txt", http.MethodGet, wellKnownContent("robots.txt", []byte(content), "text/plain; charset=utf-8"))
+	}
+}
+
+// WithSecurityTxt serves s at /.well-known/security.txt and, for older clients, /security.txt.
+// It panics if s has no Contact or no Expires, both required by RFC 9116.
+func WithSecurityTxt(s SecurityTxt) WellKnownOption {
+	if len(s.Contact) == 0 || s.Expires.IsZero() {
+		panic("rahjoo: security.txt requires Contact and Expires")
+	}
+	handler := wellKnownContent("security.txt", []byte(s.String()), "text/plain; charset=utf-8")
+	return func(r Route) {
+		r.Add("/.well-known/security.txt", http.MethodGet, handler)
+		r.Add("/security.txt", http.MethodGet, handler)
+	}
+}
+
+// WithChangePasswordURL redirects /.well-known/change-password to url, the page letting users
+// change their password, so password managers can send them there.
+func WithChangePasswordURL(url string) WellKnownOption {
+	return func(r Route) {
+		r.Add("/.well-known/change-password", http.MethodGet, NewHandler(func(w http.ResponseWriter, req *http.Request) {
+			http.Redirect(w, req, url, http.StatusFound)
+		}))
+	}
+}
+
+// WithWellKnownFile serves content, e.g. embedded with go:embed, at /.well-known/name, such as
+// "apple-app-site-association" or "openid-configuration". An empty contentType is derived from
+// the name extension or sniffed from content.
+func WithWellKnownFile(name string, content []byte, contentType string) WellKnownOption {
+	return func(r Route) {
+		r.Add(Path("/.well-known/"+strings.TrimPrefix(name, "/")), http.MethodGet, wellKnownContent(name, content, contentType))
+	}
+}
+
+// WellKnown returns a Route serving the files configured by opts at the well-known locations
+// clients look for them, such as /robots.txt and /.well-known/security.txt:
+//
+//	wellKnown := rahjoo.WellKnown(
+//		rahjoo.WithRobotsTxt("User-agent: *\nDisallow: /\n"),
+//		rahjoo.WithSecurityTxt(rahjoo.SecurityTxt{
+//			Contact: []string{"mailto:security@example.com"},
+//			Expires: time.Now().AddDate(0, 6, 0),
+//		}),
+//		rahjoo.WithChangePasswordURL("/account/password"),
+//	)
+//
+// Files are served with an ETag so clients can revalidate them. As the locations are absolute,
+// the Route should not be bound under a prefix.
+func WellKnown(opts ...WellKnownOption) Route {
+	routes := Route{}
+	for _, opt := range opts {
+		opt(routes)
+	}
+	return routes
+}
+
+// wellKnownContent returns a handler serving content named name.
+func wellKnownContent(name string, content []byte, contentType string) actionHandler {
+	var opts []serve.Option
+	if contentType != "" {
+		opts = append(opts, serve.WithContentType(contentType))
+	}
+	return NewHandler(func(w http.ResponseWriter, r *http.Request) {
+		serve.Content(w, r, name, time.Time{}, bytes.NewReader(content), opts...)
+	})
+}
@@ -0,0 +1,66 @@
+package rahjoo_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirzayi/rahjoo"
+	"github.com/amirzayi/rahjoo/rahjootest"
+)
+
+func TestWellKnown(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	security := "Contact: mailto:security@example.com\nContact: https://example.com/report\nExpires: 2030-01-02T03:04:05Z\nPreferred-Languages: en, fa\n"
+	c := rahjootest.New(t, rahjoo.WellKnown(
+		rahjoo.WithRobotsTxt("User-agent: *\nDisallow: /\n"),
+		rahjoo.WithSecurityTxt(rahjoo.SecurityTxt{
+			Contact:            []string{"mailto:security@example.com", "https://example.com/report"},
+			Expires:            expires,
+			PreferredLanguages: "en, fa",
+		}),
+		rahjoo.WithChangePasswordURL("/account/password"),
+		rahjoo.WithWellKnownFile("assetlinks.json", []byte(`[{"relation":["delegate_permission/common.handle_all_urls"]}]`), ""),
+		rahjoo.WithWellKnownFile("/apple-app-site-association", []byte(`{"applinks":{}}`), "application/json"),
+	))
+
+	testCases := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"robots", "/robots.txt", http.StatusOK, "text/plain; charset=utf-8", "User-agent: *\nDisallow: /\n"},
+		{"security", "/.well-known/security.txt", http.StatusOK, "text/plain; charset=utf-8", security},
+		{"legacy_security", "/security.txt", http.StatusOK, "text/plain; charset=utf-8", security},
+		{"extension_type", "/.well-known/assetlinks.json", http.StatusOK, "application/json", `[{"relation":["delegate_permission/common.handle_all_urls"]}]`},
+		{"explicit_type", "/.well-known/apple-app-site-association", http.StatusOK, "application/json", `{"applinks":{}}`},
+		{"unknown", "/.well-known/unknown", http.StatusNotFound, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := c.Get(tc.path).ExpectStatus(tc.status)
+			if tc.status != http.StatusOK {
+				return
+			}
+			req.ExpectHeader("Content-Type", tc.contentType).ExpectBody(tc.body)
+			if req.Response().Header.Get("ETag") == "" {
+				t.Error("missing ETag")
+			}
+		})
+	}
+
+	c.Get("/.well-known/change-password").ExpectStatus(http.StatusFound).ExpectHeader("Location", "/account/password")
+}
+
+func TestWellKnownInvalidSecurityTxt(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec == nil || !strings.Contains(rec.(string), "Contact and Expires") {
+			t.Errorf("got panic %v, want one about required fields", rec)
+		}
+	}()
+	rahjoo.WithSecurityTxt(rahjoo.SecurityTxt{Contact: []string{"mailto:security@example.com"}})
+}